@@ -0,0 +1 @@
+../performance-comparison/compiler-report.go
\ No newline at end of file
@@ -1,8 +1,12 @@
+// 运行: go run cpuTest.go compiler-report.go [-json]
+// compiler-report.go 是指向 performance-comparison/compiler-report.go 的符号链接，-json 的结果格式只有这一份定义
 package main
 
 import (
+	"flag"
 	"fmt"
 	"math"
+	"os"
 	"sync"
 	"time"
 )
@@ -38,6 +42,15 @@ func findPrimesInRange(start, end int, wg *sync.WaitGroup, results chan<- int) {
 }
 
 func main() {
+	jsonOutput := flag.Bool("json", false, "在标准输出输出 JSON 格式的结果，文字报告改写到标准错误")
+	flag.Parse()
+
+	// JSON 模式下标准输出只保留 JSON，其余输出全部转到标准错误
+	stdout := os.Stdout
+	if *jsonOutput {
+		os.Stdout = os.Stderr
+	}
+
 	startTime := time.Now()
 	
 	const (
@@ -45,6 +58,11 @@ func main() {
 		rangeEnd   = 50000000 // 一百万
 		numWorkers = 10      // 使用10个goroutine并行计算
 	)
+	run := startBenchmark("cpu-primes", map[string]interface{}{
+		"rangeStart": rangeStart,
+		"rangeEnd":   rangeEnd,
+		"workers":    numWorkers,
+	})
 	
 	// 创建通道和等待组
 	results := make(chan int, 1000)
@@ -79,6 +97,8 @@ func main() {
 	// 对结果进行排序（可选）
 	// sort.Ints(primes)
 	
+	result := run.stop(map[string]float64{"primes": float64(len(primes))})
+
 	// 打印结果
 	fmt.Printf("Found %d prime numbers\n", len(primes))
 	if len(primes) >= 5 {
@@ -88,4 +108,11 @@ func main() {
 	
 	duration := time.Since(startTime)
 	fmt.Printf("Go CPU Test took: %v\n", duration)
-} 
\ No newline at end of file
+
+	if *jsonOutput {
+		if err := writeResultsJSON(stdout, []BenchmarkResult{result}); err != nil {
+			fmt.Fprintln(os.Stderr, "输出 JSON 失败:", err)
+			os.Exit(1)
+		}
+	}
+}
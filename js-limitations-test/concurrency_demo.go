@@ -1,7 +1,11 @@
+// 运行: go run concurrency_demo.go compiler-report.go [-json]
+// compiler-report.go 是指向 performance-comparison/compiler-report.go 的符号链接，-json 的结果格式只有这一份定义
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -41,23 +45,44 @@ func (cd *ComplexData) AddMetadata(key string, value interface{}) {
 }
 
 func main() {
-	fmt.Println("=== Go 并发能力演示 ===\n")
+	jsonOutput := flag.Bool("json", false, "在标准输出输出 JSON 格式的结果，文字报告改写到标准错误")
+	flag.Parse()
+
+	// JSON 模式下标准输出只保留 JSON，其余输出全部转到标准错误
+	stdout := os.Stdout
+	if *jsonOutput {
+		os.Stdout = os.Stderr
+	}
+
+	printSummary()
+
+	fmt.Println("=== Go 并发能力演示 ===")
+	fmt.Println()
+
+	var results []BenchmarkResult
 
 	// 测试1：共享复杂数据结构
-	testComplexDataSharing()
+	results = append(results, testComplexDataSharing())
 
 	// 测试2：大量 goroutine 并发
-	testMassiveConcurrency()
+	results = append(results, testMassiveConcurrency())
 
 	// 测试3：原子操作性能
-	testAtomicOperations()
+	results = append(results, testAtomicOperations()...)
 
 	// 测试4：通道通信
-	testChannelCommunication()
+	results = append(results, testChannelCommunication())
+
+	if *jsonOutput {
+		if err := writeResultsJSON(stdout, results); err != nil {
+			fmt.Fprintln(os.Stderr, "输出 JSON 失败:", err)
+			os.Exit(1)
+		}
+	}
 }
 
 // 测试1：共享复杂数据结构
-func testComplexDataSharing() {
+func testComplexDataSharing() BenchmarkResult {
 	fmt.Println("=== 测试1：共享复杂数据结构 ===")
 
 	// 创建一个复杂的共享数据结构
@@ -77,6 +102,7 @@ func testComplexDataSharing() {
 
 	var wg sync.WaitGroup
 	workerCount := 5
+	run := startBenchmark("shared-complex-data", map[string]interface{}{"workers": workerCount})
 
 	// 启动多个 goroutine 同时修改共享数据
 	for i := 0; i < workerCount; i++ {
@@ -96,14 +122,17 @@ func testComplexDataSharing() {
 	}
 
 	wg.Wait()
+	result := run.stop(map[string]float64{"metadataEntries": float64(len(sharedData.Metadata))})
 
 	fmt.Printf("最终数据：%+v\n", sharedData.Values)
 	fmt.Printf("元数据数量：%d\n", len(sharedData.Metadata))
-	fmt.Println("✓ Go 可以安全地在多个 goroutine 间共享复杂数据结构！\n")
+	fmt.Println("✓ Go 可以安全地在多个 goroutine 间共享复杂数据结构！")
+	fmt.Println()
+	return result
 }
 
 // 测试2：大量 goroutine 并发
-func testMassiveConcurrency() {
+func testMassiveConcurrency() BenchmarkResult {
 	fmt.Println("=== 测试2：大量 goroutine 并发 ===")
 
 	start := time.Now()
@@ -113,6 +142,10 @@ func testMassiveConcurrency() {
 	// 创建大量 goroutine
 	goroutineCount := 10000
 	operationsPerGoroutine := 1000
+	run := startBenchmark("massive-goroutines", map[string]interface{}{
+		"goroutines":             goroutineCount,
+		"operationsPerGoroutine": operationsPerGoroutine,
+	})
 
 	fmt.Printf("创建 %d 个 goroutine，每个执行 %d 次操作\n", 
 		goroutineCount, operationsPerGoroutine)
@@ -137,6 +170,7 @@ func testMassiveConcurrency() {
 
 	wg.Wait()
 	duration := time.Since(start)
+	result := run.stop(map[string]float64{"counter": float64(counter)})
 
 	expectedValue := int64(goroutineCount * operationsPerGoroutine)
 	fmt.Printf("最终计数值：%d\n", counter)
@@ -144,10 +178,11 @@ func testMassiveConcurrency() {
 	fmt.Printf("是否一致：%t\n", counter == expectedValue)
 	fmt.Printf("执行时间：%v\n", duration)
 	fmt.Printf("✓ Go 轻松处理 %d 个并发 goroutine！\n\n", goroutineCount)
+	return result
 }
 
 // 测试3：原子操作性能
-func testAtomicOperations() {
+func testAtomicOperations() []BenchmarkResult {
 	fmt.Println("=== 测试3：原子操作性能 ===")
 
 	var atomicCounter int64
@@ -156,9 +191,11 @@ func testAtomicOperations() {
 
 	operationCount := 1000000
 	workerCount := 10
+	params := map[string]interface{}{"operations": operationCount, "workers": workerCount}
 
 	// 测试原子操作
 	start := time.Now()
+	run := startBenchmark("atomic-counter", params)
 	var wg1 sync.WaitGroup
 
 	for i := 0; i < workerCount; i++ {
@@ -173,9 +210,11 @@ func testAtomicOperations() {
 
 	wg1.Wait()
 	atomicDuration := time.Since(start)
+	atomicResult := run.stop(map[string]float64{"counter": float64(atomicCounter)})
 
 	// 测试互斥锁操作
 	start = time.Now()
+	run = startBenchmark("mutex-counter", params)
 	var wg2 sync.WaitGroup
 
 	for i := 0; i < workerCount; i++ {
@@ -192,17 +231,21 @@ func testAtomicOperations() {
 
 	wg2.Wait()
 	mutexDuration := time.Since(start)
+	mutexResult := run.stop(map[string]float64{"counter": float64(mutexCounter)})
 
 	fmt.Printf("原子操作结果：%d，耗时：%v\n", atomicCounter, atomicDuration)
 	fmt.Printf("互斥锁操作结果：%d，耗时：%v\n", mutexCounter, mutexDuration)
 	fmt.Printf("原子操作比互斥锁快：%.2fx\n", 
 		float64(mutexDuration)/float64(atomicDuration))
-	fmt.Println("✓ Go 提供了多种高效的并发同步原语！\n")
+	fmt.Println("✓ Go 提供了多种高效的并发同步原语！")
+	fmt.Println()
+	return []BenchmarkResult{atomicResult, mutexResult}
 }
 
 // 测试4：通道通信
-func testChannelCommunication() {
+func testChannelCommunication() BenchmarkResult {
 	fmt.Println("=== 测试4：通道通信 ===")
+	run := startBenchmark("channel-pipeline", map[string]interface{}{"items": 50, "consumers": 3})
 
 	// 创建不同类型的通道
 	dataChannel := make(chan int, 100)
@@ -257,11 +300,15 @@ func testChannelCommunication() {
 	}
 
 	<-doneChannel
-	fmt.Println("✓ Go 的通道提供了优雅的并发通信机制！\n")
+	result := run.stop(nil)
+	fmt.Println("✓ Go 的通道提供了优雅的并发通信机制！")
+	fmt.Println()
+	return result
 }
 
 // 额外演示：展示 Go 相比 JavaScript 的优势
-func init() {
+// 在 main 中调用而不是放在 init 中，这样 -json 模式可以先把输出转到标准错误
+func printSummary() {
 	fmt.Println("=== Go 并发优势总结 ===")
 	fmt.Println("1. 真正的共享内存：可以安全地在 goroutine 间共享任何数据结构")
 	fmt.Println("2. 轻量级 goroutine：可以轻松创建数万个并发单元")
@@ -270,4 +317,4 @@ func init() {
 	fmt.Println("5. 高效的调度器：M:N 调度模型，充分利用多核")
 	fmt.Println("6. 内置并发支持：语言级别的并发原语")
 	fmt.Println()
-} 
\ No newline at end of file
+}
@@ -1,7 +1,11 @@
+// 运行: go run memoryTest.go compiler-report.go [-json]
+// compiler-report.go 是指向 performance-comparison/compiler-report.go 的符号链接，-json 的结果格式只有这一份定义
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
 	"runtime"
 	"sort"
 	"time"
@@ -58,8 +62,8 @@ func (mt *MemoryTester) createPerson(id int) Person {
 	}
 }
 
-// runTest 运行内存测试
-func (mt *MemoryTester) runTest() {
+// runTest 运行内存测试，返回创建阶段和操作阶段的机器可读结果
+func (mt *MemoryTester) runTest() []BenchmarkResult {
 	fmt.Println("=== Go 内存测试 ===")
 	fmt.Printf("测试规模: %s 条记录\n", formatNumber(mt.count))
 	fmt.Println()
@@ -68,6 +72,8 @@ func (mt *MemoryTester) runTest() {
 	fmt.Printf("初始内存使用: %.2f MB\n", initialMemory)
 
 	startTime := time.Now()
+	params := map[string]interface{}{"records": mt.count}
+	createRun := startBenchmark("memory-create", params)
 
 	// 创建大量对象
 	fmt.Println("开始创建对象...")
@@ -82,6 +88,10 @@ func (mt *MemoryTester) runTest() {
 
 	afterCreationMemory := mt.getMemoryUsage()
 	creationTime := time.Since(startTime)
+	createResult := createRun.stop(map[string]float64{
+		"heapGrowthMB":   afterCreationMemory - initialMemory,
+		"bytesPerRecord": (afterCreationMemory - initialMemory) * 1024 * 1024 / float64(mt.count),
+	})
 
 	fmt.Println()
 	fmt.Println("=== 创建阶段结果 ===")
@@ -94,6 +104,7 @@ func (mt *MemoryTester) runTest() {
 	fmt.Println()
 	fmt.Println("执行数据操作...")
 	operationStartTime := time.Now()
+	operationRun := startBenchmark("memory-operations", params)
 
 	// 查找操作
 	highSalaryCount := 0
@@ -123,6 +134,10 @@ func (mt *MemoryTester) runTest() {
 
 	operationTime := time.Since(operationStartTime)
 	finalMemory := mt.getMemoryUsage()
+	operationResult := operationRun.stop(map[string]float64{
+		"highSalaryCount": float64(highSalaryCount),
+		"heapGrowthMB":    finalMemory - initialMemory,
+	})
 
 	fmt.Println()
 	fmt.Println("=== 最终结果 ===")
@@ -138,6 +153,9 @@ func (mt *MemoryTester) runTest() {
 	afterGCMemory := mt.getMemoryUsage()
 	fmt.Printf("垃圾回收后内存: %.2f MB\n", afterGCMemory)
 	fmt.Printf("回收的内存: %.2f MB\n", finalMemory-afterGCMemory)
+
+	operationResult.Metrics["reclaimedMB"] = finalMemory - afterGCMemory
+	return []BenchmarkResult{createResult, operationResult}
 }
 
 // formatNumber 格式化数字，添加千位分隔符
@@ -158,7 +176,23 @@ func formatNumber(n int) string {
 }
 
 func main() {
+	jsonOutput := flag.Bool("json", false, "在标准输出输出 JSON 格式的结果，文字报告改写到标准错误")
+	flag.Parse()
+
+	// JSON 模式下标准输出只保留 JSON，其余输出全部转到标准错误
+	stdout := os.Stdout
+	if *jsonOutput {
+		os.Stdout = os.Stderr
+	}
+
 	recordCount := 1000000 // 100万条记录
 	tester := NewMemoryTester(recordCount)
-	tester.runTest()
-} 
\ No newline at end of file
+	results := tester.runTest()
+
+	if *jsonOutput {
+		if err := writeResultsJSON(stdout, results); err != nil {
+			fmt.Fprintln(os.Stderr, "输出 JSON 失败:", err)
+			os.Exit(1)
+		}
+	}
+}
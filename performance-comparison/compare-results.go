@@ -0,0 +1,407 @@
+package main
+
+// 汇总 Go、JavaScript、TypeScript 测试程序的 JSON 结果，生成带加速比的对比表格。
+// 需要与 compiler-report.go 一起编译：
+//
+//	go run compare-results.go compiler-report.go -run small
+//	go run compare-results.go compiler-report.go -format csv go.json js.json ts.json
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// suiteCommand 一个测试程序的运行命令，参数中的通配符会被展开
+type suiteCommand struct {
+	Language string
+	Args     []string
+}
+
+// 各测试套件的运行命令。TypeScript 小规模测试使用 npm run build 编译后的 dist
+var suites = map[string][]suiteCommand{
+	"small": {
+		{"javascript", []string{"node", "src/javascript-test.js", "--json"}},
+		{"typescript", []string{"node", "dist/typescript-test.js", "--json"}},
+		{"go", []string{"go", "run", "go-test.go", "compiler-*.go", "-json"}},
+	},
+	"large": {
+		{"javascript", []string{"node", "src/large-scale-test.js", "--json"}},
+		{"typescript", []string{"npx", "ts-node", "src/large-scale-test.ts", "--json"}},
+		{"go", []string{"go", "run", "large-scale-*.go", "compiler-*.go", "-json"}},
+	},
+}
+
+// languageOrder 表格中语言列的顺序，未列出的语言按名称排在最后
+var languageOrder = []string{"javascript", "typescript", "go"}
+
+var languageNames = map[string]string{
+	"javascript": "JavaScript",
+	"typescript": "TypeScript",
+	"go":         "Go",
+}
+
+func languageName(language string) string {
+	if name, ok := languageNames[language]; ok {
+		return name
+	}
+	return language
+}
+
+// comparisonInput 单条测试结果。JavaScript/TypeScript 的结果没有 Go 的内存统计字段，
+// 但带有 Node.js 版本
+type comparisonInput struct {
+	BenchmarkResult
+	NodeVersion string `json:"nodeVersion"`
+}
+
+// runSuiteCommand 运行一个测试程序并解析它输出的 JSON 结果
+func runSuiteCommand(command suiteCommand, verbose bool) ([]comparisonInput, error) {
+	var args []string
+	for _, arg := range command.Args {
+		matches, err := filepath.Glob(arg)
+		if err != nil || len(matches) == 0 {
+			args = append(args, arg)
+			continue
+		}
+		args = append(args, matches...)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if verbose {
+		cmd.Stderr = os.Stderr
+	}
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %v", strings.Join(args, " "), err)
+	}
+	return decodeResults(&stdout, command.Language)
+}
+
+// decodeResults 解析 -json 输出的结果数组；language 非空时覆盖结果中的语言
+func decodeResults(r io.Reader, language string) ([]comparisonInput, error) {
+	var results []comparisonInput
+	if err := json.NewDecoder(r).Decode(&results); err != nil {
+		return nil, fmt.Errorf("解析 JSON 结果失败: %v", err)
+	}
+	for i := range results {
+		if language != "" {
+			results[i].Language = language
+		}
+		results[i].Language = strings.ToLower(results[i].Language)
+	}
+	return results, nil
+}
+
+func loadResultsFile(path string) ([]comparisonInput, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	results, err := decodeResults(f, "")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return results, nil
+}
+
+// comparisonRow 表格中的一行：同一测试、同一分组参数下各语言的耗时
+type comparisonRow struct {
+	Benchmark string
+	Params    string
+	Times     map[string][]float64
+}
+
+// comparisonTable 按测试汇总后的结果
+type comparisonTable struct {
+	Languages    []string
+	Baseline     string
+	Rows         []*comparisonRow
+	Environments []string
+}
+
+// buildComparisonTable 按 benchmark 和 groupBy 中的参数把结果分组。
+// 不同语言的测试参数不完全相同，因此只有 groupBy 中的参数参与分组；工作负载指纹 workload 总是参与分组，
+// 处理的数据不同（指纹不同，或只有一方记录了指纹）的结果分在不同的行，不会计算它们之间的加速比
+func buildComparisonTable(results []comparisonInput, groupBy []string, baseline string) *comparisonTable {
+	if !slices.Contains(groupBy, "workload") {
+		groupBy = append(slices.Clip(groupBy), "workload")
+	}
+	table := &comparisonTable{Baseline: baseline}
+	rows := make(map[string]*comparisonRow)
+	languages := make(map[string]bool)
+	environments := make(map[string]bool)
+
+	for _, result := range results {
+		var params []string
+		for _, name := range groupBy {
+			if value, ok := result.Parameters[name]; ok {
+				params = append(params, fmt.Sprintf("%s=%v", name, value))
+			}
+		}
+		key := result.Benchmark + " " + strings.Join(params, ",")
+		row, ok := rows[key]
+		if !ok {
+			row = &comparisonRow{
+				Benchmark: result.Benchmark,
+				Params:    strings.Join(params, ", "),
+				Times:     make(map[string][]float64),
+			}
+			rows[key] = row
+			table.Rows = append(table.Rows, row)
+		}
+		row.Times[result.Language] = append(row.Times[result.Language], result.WallTimeMs)
+		languages[result.Language] = true
+
+		switch {
+		case result.GoVersion != "":
+			environments[fmt.Sprintf("%s %s/%s，%d CPU", result.GoVersion, result.GOOS, result.GOARCH, result.CPUCount)] = true
+		case result.NodeVersion != "":
+			environments[fmt.Sprintf("Node.js %s，%d CPU", result.NodeVersion, result.CPUCount)] = true
+		}
+	}
+
+	for _, language := range languageOrder {
+		if languages[language] {
+			table.Languages = append(table.Languages, language)
+			delete(languages, language)
+		}
+	}
+	var others []string
+	for language := range languages {
+		others = append(others, language)
+	}
+	sort.Strings(others)
+	table.Languages = append(table.Languages, others...)
+
+	for environment := range environments {
+		table.Environments = append(table.Environments, environment)
+	}
+	sort.Strings(table.Environments)
+	return table
+}
+
+// median 多次运行的结果取中位数
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// cells 把表格展开为字符串单元格，第一行为表头，ratioSuffix 追加在加速比之后。
+// 加速比 = 其他语言耗时 / 基准语言耗时，大于 1 表示基准语言更快
+func (t *comparisonTable) cells(precision int, ratioSuffix string) [][]string {
+	header := []string{"测试", "参数"}
+	for _, language := range t.Languages {
+		header = append(header, languageName(language)+" (ms)")
+	}
+	var compared []string
+	for _, language := range t.Languages {
+		if language != t.Baseline {
+			compared = append(compared, language)
+			header = append(header, fmt.Sprintf("%s/%s", languageName(language), languageName(t.Baseline)))
+		}
+	}
+
+	formatFloat := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', precision, 64)
+	}
+
+	result := [][]string{header}
+	for _, row := range t.Rows {
+		line := []string{row.Benchmark, row.Params}
+		for _, language := range t.Languages {
+			if times := row.Times[language]; len(times) > 0 {
+				line = append(line, formatFloat(median(times)))
+			} else {
+				line = append(line, "-")
+			}
+		}
+		base := row.Times[t.Baseline]
+		for _, language := range compared {
+			times := row.Times[language]
+			if len(times) == 0 || len(base) == 0 || median(base) == 0 {
+				line = append(line, "-")
+				continue
+			}
+			line = append(line, formatFloat(median(times)/median(base))+ratioSuffix)
+		}
+		result = append(result, line)
+	}
+	return result
+}
+
+func (t *comparisonTable) writeMarkdown(w io.Writer) error {
+	cells := t.cells(2, "x")
+	for i, line := range cells {
+		fmt.Fprintf(w, "| %s |\n", strings.Join(line, " | "))
+		if i == 0 {
+			separators := make([]string, len(line))
+			for j := range separators {
+				separators[j] = "---"
+			}
+			fmt.Fprintf(w, "| %s |\n", strings.Join(separators, " | "))
+		}
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "加速比 = 该语言耗时 / %s 耗时，大于 1 表示 %s 更快；多次运行的结果取中位数。\n",
+		languageName(t.Baseline), languageName(t.Baseline))
+	if len(t.Environments) > 0 {
+		fmt.Fprintf(w, "运行环境: %s\n", strings.Join(t.Environments, "；"))
+	}
+	return nil
+}
+
+func (t *comparisonTable) writeCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.WriteAll(t.cells(3, ""))
+	return writer.Error()
+}
+
+var htmlTemplate = template.Must(template.New("comparison").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>性能对比</title>
+<style>
+table { border-collapse: collapse; font-family: sans-serif; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: right; }
+th:nth-child(-n+2), td:nth-child(-n+2) { text-align: left; }
+</style>
+</head>
+<body>
+<table>
+<thead><tr>{{range index .Cells 0}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{range slice .Cells 1}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</tbody>
+</table>
+<p>加速比 = 该语言耗时 / {{.Baseline}} 耗时，大于 1 表示 {{.Baseline}} 更快；多次运行的结果取中位数。</p>
+{{if .Environments}}<p>运行环境: {{range $i, $e := .Environments}}{{if $i}}；{{end}}{{$e}}{{end}}</p>
+{{end}}</body>
+</html>
+`))
+
+func (t *comparisonTable) writeHTML(w io.Writer) error {
+	return htmlTemplate.Execute(w, map[string]interface{}{
+		"Cells":        t.cells(2, "x"),
+		"Baseline":     languageName(t.Baseline),
+		"Environments": t.Environments,
+	})
+}
+
+func main() {
+	suite := flag.String("run", "", "运行测试套件并收集结果: small（基础测试）、large（大规模测试）或 all")
+	format := flag.String("format", "markdown", "输出格式: markdown、csv、html")
+	output := flag.String("o", "", "输出文件（默认输出到标准输出）")
+	baseline := flag.String("baseline", "go", "计算加速比的基准语言")
+	groupBy := flag.String("group", "files", "除 benchmark 外参与分组的参数，逗号分隔")
+	verbose := flag.Bool("v", false, "显示各测试程序的文字报告")
+	flag.Parse()
+
+	// 先检查输出格式，避免运行测试之后才报错，或者截断 -o 指定的已有文件
+	var write func(t *comparisonTable, w io.Writer) error
+	switch *format {
+	case "markdown", "md":
+		write = (*comparisonTable).writeMarkdown
+	case "csv":
+		write = (*comparisonTable).writeCSV
+	case "html":
+		write = (*comparisonTable).writeHTML
+	default:
+		fmt.Fprintf(os.Stderr, "未知的输出格式 %q（可选: markdown、csv、html）\n", *format)
+		os.Exit(2)
+	}
+
+	var names []string
+	switch *suite {
+	case "":
+	case "all":
+		names = []string{"small", "large"}
+	default:
+		if _, ok := suites[*suite]; !ok {
+			fmt.Fprintf(os.Stderr, "未知的测试套件 %q（可选: small、large、all）\n", *suite)
+			os.Exit(2)
+		}
+		names = []string{*suite}
+	}
+	if len(names) == 0 && flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "用法: go run compare-results.go compiler-report.go [-run small|large|all] [结果文件...]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	var results []comparisonInput
+	for _, name := range names {
+		for _, command := range suites[name] {
+			fmt.Fprintf(os.Stderr, "运行 %s %s 测试...\n", languageName(command.Language), name)
+			commandResults, err := runSuiteCommand(command, *verbose)
+			if err != nil {
+				// 缺少某种语言的环境时跳过它，其余语言照常汇总
+				fmt.Fprintf(os.Stderr, "  跳过: %v\n", err)
+				continue
+			}
+			results = append(results, commandResults...)
+		}
+	}
+	for _, path := range flag.Args() {
+		fileResults, err := loadResultsFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		results = append(results, fileResults...)
+	}
+	if len(results) == 0 {
+		fmt.Fprintln(os.Stderr, "没有可汇总的结果")
+		os.Exit(1)
+	}
+
+	var group []string
+	for _, name := range strings.Split(*groupBy, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			group = append(group, name)
+		}
+	}
+	table := buildComparisonTable(results, group, *baseline)
+
+	if *output == "" {
+		if err := write(table, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "输出失败:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := write(table, f); err != nil {
+		f.Close()
+		fmt.Fprintln(os.Stderr, "输出失败:", err)
+		os.Exit(1)
+	}
+	if err := f.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, "输出失败:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import "fmt"
+
+// NodeKind 枚举
+type NodeKind int
+
+const (
+	FunctionDeclaration NodeKind = iota + 1
+	VariableDeclaration
+	CallExpression
+	BinaryExpression
+	Identifier
+
+	// 以下节点类型由解析器产生
+	SourceFileNode
+	Block
+	Parameter
+	ReturnStatement
+	ExpressionStatement
+	NumericLiteral
+	StringLiteral
+	BooleanLiteral
+	IfStatement
+	ForStatement
+	WhileStatement
+	SwitchStatement
+	CaseClause
+	TryStatement
+	CatchClause
+	BreakStatement
+	ContinueStatement
+	ThrowStatement
+	VariableStatement
+	ClassDeclaration
+	InterfaceDeclaration
+	EnumDeclaration
+	TypeAliasDeclaration
+	PropertyAccessExpression
+	ElementAccessExpression
+	ConditionalExpression
+	UnaryExpression
+	NewExpression
+	FunctionExpression
+	ObjectLiteralExpression
+	ArrayLiteralExpression
+	RegularExpressionLiteral
+)
+
+var nodeKindNames = map[NodeKind]string{
+	FunctionDeclaration: "FunctionDeclaration",
+	VariableDeclaration: "VariableDeclaration",
+	CallExpression:      "CallExpression",
+	BinaryExpression:    "BinaryExpression",
+	Identifier:          "Identifier",
+	SourceFileNode:      "SourceFile",
+	Block:               "Block",
+	Parameter:           "Parameter",
+	ReturnStatement:     "ReturnStatement",
+	ExpressionStatement: "ExpressionStatement",
+	NumericLiteral:      "NumericLiteral",
+	StringLiteral:       "StringLiteral",
+	BooleanLiteral:      "BooleanLiteral",
+
+	IfStatement:              "IfStatement",
+	ForStatement:             "ForStatement",
+	WhileStatement:           "WhileStatement",
+	SwitchStatement:          "SwitchStatement",
+	CaseClause:               "CaseClause",
+	TryStatement:             "TryStatement",
+	CatchClause:              "CatchClause",
+	BreakStatement:           "BreakStatement",
+	ContinueStatement:        "ContinueStatement",
+	ThrowStatement:           "ThrowStatement",
+	VariableStatement:        "VariableStatement",
+	ClassDeclaration:         "ClassDeclaration",
+	InterfaceDeclaration:     "InterfaceDeclaration",
+	EnumDeclaration:          "EnumDeclaration",
+	TypeAliasDeclaration:     "TypeAliasDeclaration",
+	PropertyAccessExpression: "PropertyAccessExpression",
+	ElementAccessExpression:  "ElementAccessExpression",
+	ConditionalExpression:    "ConditionalExpression",
+	UnaryExpression:          "UnaryExpression",
+	NewExpression:            "NewExpression",
+	FunctionExpression:       "FunctionExpression",
+	ObjectLiteralExpression:  "ObjectLiteralExpression",
+	ArrayLiteralExpression:   "ArrayLiteralExpression",
+	RegularExpressionLiteral: "RegularExpressionLiteral",
+}
+
+func (k NodeKind) String() string {
+	if name, ok := nodeKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("NodeKind(%d)", int(k))
+}
+
+// NodeFlags 节点标志，对应 TypeScript 的 NodeFlags
+type NodeFlags int
+
+const (
+	NodeFlagsLet NodeFlags = 1 << iota
+	NodeFlagsConst
+	NodeFlagsExport
+)
+
+// Position 源码位置（Line、Column 从 1 开始）
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// ASTNode 结构体
+//
+// 各节点的子节点约定：
+//   - SourceFileNode / Block: 语句列表
+//   - FunctionDeclaration: Parameter...，最后一个是函数体 Block
+//   - VariableDeclaration: 可选的初始化表达式
+//   - CallExpression: 第一个是被调用表达式，其余是实参
+//   - BinaryExpression: 左右操作数，Name 为运算符
+//   - ReturnStatement / ExpressionStatement / ThrowStatement: 可选的表达式
+//   - VariableStatement: 一条语句中的多个 VariableDeclaration
+//   - IfStatement: 条件、then 语句、可选的 else 语句
+//   - ForStatement: 初始化、条件、更新（各自可省略）和循环体；for-of/for-in 的 Name 为 of/in，
+//     子节点为声明、被遍历的表达式和循环体
+//   - WhileStatement: 条件和循环体；do-while 的 Name 为 do，子节点为循环体和条件
+//   - SwitchStatement: 判别表达式和 CaseClause...；CaseClause 的 Name 为 case 或 default，
+//     子节点为 case 表达式（default 没有）和语句列表
+//   - TryStatement: try 块、可选的 CatchClause（Name 为 catch 参数）和 finally 块
+//   - BreakStatement / ContinueStatement: 没有子节点，Name 为可选的标签
+//   - ClassDeclaration / InterfaceDeclaration / EnumDeclaration: 没有子节点，成员被跳过
+//   - TypeAliasDeclaration: 没有子节点，类型保存在 TypeAnnotation 中
+//   - PropertyAccessExpression: 对象表达式，Name 为属性名
+//   - ElementAccessExpression: 对象表达式和索引表达式
+//   - ConditionalExpression: 条件和两个分支
+//   - UnaryExpression: 操作数，Name 为运算符（后缀 ++ -- 的 Name 为 x++ x--）
+//   - NewExpression: 第一个是构造函数表达式，其余是实参
+//   - FunctionExpression: 没有子节点，函数表达式和箭头函数的参数和函数体被跳过
+//   - ObjectLiteralExpression: 属性值；ArrayLiteralExpression: 元素
+//
+// TypeAnnotation 保存参数、变量的类型注解、函数的返回类型注解以及类型别名的类型。
+type ASTNode struct {
+	Kind           NodeKind
+	Name           string
+	Flags          NodeFlags
+	TypeAnnotation string
+	Pos            Position
+	End            Position
+	Children       []*ASTNode
+	Parent         *ASTNode
+}
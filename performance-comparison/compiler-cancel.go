@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 可取消的检查：调用方通过 context 取消（例如编辑器中新的按键使上一次检查过时）或设置时限。
+// 取消后不再开始新的文件，正在遍历的 AST 在下一个节点处退出；
+// 被中断的文件的诊断全部丢弃，因此诊断收集器中只有完整检查过的文件
+
+// CheckResult 一次可取消检查的结果
+type CheckResult struct {
+	Total     int
+	Completed []string // 完整检查过的文件，已排序
+	Duration  time.Duration
+	Err       error // 检查因取消或超时没有完成时为 ctx.Err()
+}
+
+func (r CheckResult) String() string {
+	s := fmt.Sprintf("完成 %d/%d 个文件，用时 %s", len(r.Completed), r.Total, r.Duration.Round(time.Microsecond))
+	if r.Err != nil {
+		s += fmt.Sprintf("（%v）", r.Err)
+	}
+	return s
+}
+
+// completionTracker 记录完整检查过的文件，可在多个 goroutine 中调用 done
+type completionTracker struct {
+	start time.Time
+	total int
+	mu    sync.Mutex
+	paths []string
+}
+
+func newCompletionTracker(total int) *completionTracker {
+	return &completionTracker{start: time.Now(), total: total}
+}
+
+func (t *completionTracker) done(path string) {
+	t.mu.Lock()
+	t.paths = append(t.paths, path)
+	t.mu.Unlock()
+}
+
+// result 在所有处理结束后调用。全部文件都已完成时，即使 ctx 随后被取消也不算部分结果
+func (t *completionTracker) result(ctx context.Context) CheckResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	completed := append([]string(nil), t.paths...)
+	sort.Strings(completed)
+	result := CheckResult{Total: t.total, Completed: completed, Duration: time.Since(t.start)}
+	if len(completed) < t.total {
+		result.Err = ctx.Err()
+	}
+	return result
+}
+
+// filterDiagnostics 只保留 files 中文件的诊断，用于和部分结果对比
+func filterDiagnostics(diagnostics *DiagnosticCollector, files []string) *DiagnosticCollector {
+	keep := make(map[string]bool, len(files))
+	for _, file := range files {
+		keep[file] = true
+	}
+	filtered := NewDiagnosticCollector()
+	for _, d := range diagnostics.sorted() {
+		if keep[d.File] {
+			filtered.add(d)
+		}
+	}
+	return filtered
+}
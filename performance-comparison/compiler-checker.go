@@ -0,0 +1,578 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// 内置类型名
+const (
+	TypeAny     = "any"
+	TypeNumber  = "number"
+	TypeString  = "string"
+	TypeBoolean = "boolean"
+	TypeVoid    = "void"
+)
+
+var builtinTypes = map[string]bool{
+	TypeAny:     true,
+	TypeNumber:  true,
+	TypeString:  true,
+	TypeBoolean: true,
+	TypeVoid:    true,
+}
+
+// SymbolKind 符号类型
+type SymbolKind int
+
+const (
+	SymbolVariable SymbolKind = iota
+	SymbolFunction
+	SymbolParameter
+)
+
+// Signature 函数签名
+type Signature struct {
+	ParamNames []string
+	ParamTypes []string
+	ReturnType string
+}
+
+func (s *Signature) String() string {
+	params := make([]string, len(s.ParamNames))
+	for i, name := range s.ParamNames {
+		params[i] = name + ": " + s.ParamTypes[i]
+	}
+	return "(" + strings.Join(params, ", ") + ") => " + s.ReturnType
+}
+
+// Symbol 结构体
+type Symbol struct {
+	Name      string
+	Kind      SymbolKind
+	Type      string     // 变量和参数的类型；函数为签名的字符串形式
+	Signature *Signature // 仅函数符号
+	Scope     int        // 声明所在作用域的嵌套深度，全局作用域为 0
+	TypeID    TypeID     // 驻留的类型（见 TypeInterner），未驻留时为 0
+
+	// 声明的位置，由 declare 记录；预先加入全局作用域的符号没有声明节点
+	File        string
+	Declaration *ASTNode
+}
+
+// ScopeKind 作用域类型
+type ScopeKind int
+
+const (
+	GlobalScope ScopeKind = iota
+	FileScope
+	FunctionScope
+	BlockScope
+)
+
+// SymbolTable 结构体，每个词法作用域对应一个 SymbolTable
+type SymbolTable struct {
+	symbols map[string]*Symbol
+	parent  *SymbolTable
+	kind    ScopeKind
+	name    string // 作用域名称（文件或函数名），用于报告
+	depth   int
+}
+
+func newSymbolTable(kind ScopeKind, name string, parent *SymbolTable) *SymbolTable {
+	depth := 0
+	if parent != nil {
+		depth = parent.depth + 1
+	}
+	return &SymbolTable{
+		symbols: make(map[string]*Symbol),
+		parent:  parent,
+		kind:    kind,
+		name:    name,
+		depth:   depth,
+	}
+}
+
+// RedeclarationError 同一作用域内重复声明
+type RedeclarationError struct {
+	Name     string
+	Previous *Symbol
+}
+
+func (e *RedeclarationError) Error() string {
+	return fmt.Sprintf("无法重新声明 '%s'", e.Name)
+}
+
+// TypeChecker 结构体
+type TypeChecker struct {
+	globals     *SymbolTable  // 全局作用域，所有文件共享
+	symbolTable *SymbolTable  // 当前作用域
+	mu          *sync.RWMutex // 保护 globals，在 fork 出的检查器之间共享
+
+	// 以下状态只属于当前检查器，不在 fork 之间共享
+	fileName    string              // 正在检查的文件，用于诊断
+	nodeTypes   map[*ASTNode]string // 已推断的表达式类型和声明类型
+	functions   []*Signature        // 正在检查的函数签名栈，用于检查 return
+	diagnostics []*Diagnostic
+
+	// ctx 非 nil 时遍历在每个节点开始前检查取消；取消后 visitNode 不再深入，err 记录 ctx.Err()
+	ctx context.Context
+	err error
+
+	references *ReferenceIndex // 非 nil 时记录声明和标识符引用，在 fork 之间共享
+}
+
+// NewTypeChecker 创建新的类型检查器
+func NewTypeChecker() *TypeChecker {
+	globals := newSymbolTable(GlobalScope, "global", nil)
+	return &TypeChecker{
+		globals:     globals,
+		symbolTable: globals,
+		mu:          &sync.RWMutex{},
+		nodeTypes:   make(map[*ASTNode]string),
+	}
+}
+
+// fork 创建共享全局作用域、但拥有独立作用域栈的检查器，供并发处理单个文件使用
+func (tc *TypeChecker) fork() *TypeChecker {
+	return &TypeChecker{
+		globals:     tc.globals,
+		symbolTable: tc.globals,
+		mu:          tc.mu,
+		nodeTypes:   make(map[*ASTNode]string),
+		references:  tc.references,
+	}
+}
+
+// enterScope 进入新的子作用域
+func (tc *TypeChecker) enterScope(kind ScopeKind, name string) *SymbolTable {
+	tc.symbolTable = newSymbolTable(kind, name, tc.symbolTable)
+	return tc.symbolTable
+}
+
+// exitScope 退出当前作用域，回到父作用域
+func (tc *TypeChecker) exitScope() {
+	if tc.symbolTable.parent == nil {
+		panic("exitScope: 不能退出全局作用域")
+	}
+	tc.symbolTable = tc.symbolTable.parent
+}
+
+func (tc *TypeChecker) report(node *ASTNode, code int, format string, args ...interface{}) {
+	tc.diagnostics = append(tc.diagnostics, newDiagnostic(tc.fileName, node, code, format, args...))
+}
+
+// cancelled 判断遍历是否已被取消，取消后的检查结果不完整
+func (tc *TypeChecker) cancelled() bool {
+	if tc.err == nil && tc.ctx != nil {
+		tc.err = tc.ctx.Err()
+	}
+	return tc.err != nil
+}
+
+// 1. AST 节点遍历测试
+func (tc *TypeChecker) visitNode(node *ASTNode) int {
+	if tc.cancelled() {
+		return 0
+	}
+	count := 1
+
+	switch node.Kind {
+	case SourceFileNode:
+		tc.fileName = node.Name
+		tc.enterScope(FileScope, node.Name)
+		defer tc.exitScope()
+		tc.hoistFunctions(node)
+	case Block:
+		// 函数体与参数共用函数作用域，其他块创建新的块级作用域
+		if node.Parent == nil || node.Parent.Kind != FunctionDeclaration {
+			tc.enterScope(BlockScope, "")
+			defer tc.exitScope()
+		}
+		tc.hoistFunctions(node)
+	case FunctionDeclaration:
+		signature := tc.checkFunctionDeclaration(node)
+		tc.enterScope(FunctionScope, node.Name)
+		tc.functions = append(tc.functions, signature)
+		defer func() {
+			tc.functions = tc.functions[:len(tc.functions)-1]
+			tc.exitScope()
+		}()
+	case ForStatement, SwitchStatement:
+		// for 的循环变量和 case 子句中的声明属于语句自己的块级作用域
+		tc.enterScope(BlockScope, "")
+		defer tc.exitScope()
+	case CatchClause:
+		tc.enterScope(BlockScope, "")
+		defer tc.exitScope()
+		if node.Name != "" {
+			tc.declare(node, &Symbol{Name: node.Name, Kind: SymbolVariable, Type: TypeAny})
+		}
+	case Parameter:
+		tc.declare(node, &Symbol{Name: node.Name, Kind: SymbolParameter, Type: tc.resolveTypeAnnotation(node)})
+	case VariableDeclaration:
+		tc.checkVariableDeclaration(node)
+	case ReturnStatement:
+		tc.checkReturnStatement(node)
+	case CallExpression, BinaryExpression, Identifier, NumericLiteral, StringLiteral, BooleanLiteral:
+		tc.checkExpression(node)
+	default:
+		// 其他节点类型
+	}
+
+	// 递归处理子节点
+	for _, child := range node.Children {
+		count += tc.visitNode(child)
+	}
+
+	// 变量在初始化表达式之后才进入作用域
+	if node.Kind == VariableDeclaration {
+		tc.declare(node, &Symbol{Name: node.Name, Kind: SymbolVariable, Type: tc.nodeTypes[node]})
+	}
+
+	return count
+}
+
+// hoistFunctions 函数声明提升：在进入作用域时先声明其中的所有函数。
+// class 和 enum 也在这里声明，它们常在声明之前的函数体中被引用（成员被跳过，类型为 any）
+func (tc *TypeChecker) hoistFunctions(container *ASTNode) {
+	for _, stmt := range container.Children {
+		switch {
+		case stmt.Kind == FunctionDeclaration:
+			signature := tc.getSignature(stmt)
+			tc.declare(stmt, &Symbol{
+				Name:      stmt.Name,
+				Kind:      SymbolFunction,
+				Type:      signature.String(),
+				Signature: signature,
+			})
+		case (stmt.Kind == ClassDeclaration || stmt.Kind == EnumDeclaration) && stmt.Name != "":
+			tc.declare(stmt, &Symbol{Name: stmt.Name, Kind: SymbolVariable, Type: TypeAny})
+		}
+	}
+}
+
+// declare 在当前作用域声明符号，重复声明时报告与 tsc 相同的诊断
+func (tc *TypeChecker) declare(node *ASTNode, symbol *Symbol) {
+	symbol.File = tc.fileName
+	symbol.Declaration = node
+	_, err := tc.declareSymbol(symbol)
+	if err == nil && tc.references != nil {
+		tc.references.addDeclaration(tc.fileName, node, symbol, tc.symbolTable.kind == FileScope)
+	}
+	redeclared, ok := err.(*RedeclarationError)
+	if !ok {
+		return
+	}
+	switch {
+	case symbol.Kind == SymbolFunction && redeclared.Previous.Kind == SymbolFunction:
+		// 重载签名没有函数体，不算重复实现
+		if hasFunctionBody(node) && hasFunctionBody(redeclared.Previous.Declaration) {
+			tc.report(node, DiagDuplicateFunction, "Duplicate function implementation.")
+		}
+	case symbol.Kind == SymbolVariable || redeclared.Previous.Kind == SymbolVariable:
+		tc.report(node, DiagCannotRedeclareBlockScoped, "Cannot redeclare block-scoped variable '%s'.", node.Name)
+	default:
+		tc.report(node, DiagDuplicateIdentifier, "Duplicate identifier '%s'.", node.Name)
+	}
+}
+
+// hasFunctionBody 判断函数声明是否有函数体（最后一个子节点是 Block）
+func hasFunctionBody(node *ASTNode) bool {
+	return node != nil && len(node.Children) > 0 && node.Children[len(node.Children)-1].Kind == Block
+}
+
+// resolveTypeAnnotation 返回节点的类型注解，没有注解时为 any。
+// 只检查类型名和数组后缀形式的注解，联合类型、泛型等其他形式不建模，按 any 处理
+func (tc *TypeChecker) resolveTypeAnnotation(node *ASTNode) string {
+	annotation := node.TypeAnnotation
+	if annotation == "" || !isSimpleTypeName(annotation) {
+		return TypeAny
+	}
+	if !builtinTypes[strings.TrimSuffix(annotation, "[]")] {
+		tc.report(node, DiagCannotFindName, "Cannot find name '%s'.", strings.TrimSuffix(annotation, "[]"))
+		return TypeAny
+	}
+	return annotation
+}
+
+// isSimpleTypeName 判断类型注解是否只由标识符和数组后缀组成
+func isSimpleTypeName(annotation string) bool {
+	for strings.HasSuffix(annotation, "[]") {
+		annotation = strings.TrimSuffix(annotation, "[]")
+	}
+	for i := 0; i < len(annotation); i++ {
+		if ch := annotation[i]; !isIdentifierStart(ch) && !isDigit(ch) {
+			return false
+		}
+	}
+	return annotation != ""
+}
+
+// getSignature 由参数和返回类型注解构造函数签名。
+// 没有返回类型注解的函数返回 any（不做基于函数体的返回类型推断）
+func (tc *TypeChecker) getSignature(node *ASTNode) *Signature {
+	signature := &Signature{ReturnType: TypeAny}
+	for _, child := range node.Children {
+		if child.Kind == Parameter {
+			signature.ParamNames = append(signature.ParamNames, child.Name)
+			paramType := child.TypeAnnotation
+			if paramType == "" || !builtinTypes[strings.TrimSuffix(paramType, "[]")] {
+				paramType = TypeAny
+			}
+			signature.ParamTypes = append(signature.ParamTypes, paramType)
+		}
+	}
+	if node.TypeAnnotation != "" {
+		signature.ReturnType = tc.resolveTypeAnnotation(node)
+	}
+	return signature
+}
+
+func (tc *TypeChecker) checkFunctionDeclaration(node *ASTNode) *Signature {
+	var signature *Signature
+	if symbol := tc.symbolTable.symbols[node.Name]; symbol != nil && symbol.Kind == SymbolFunction {
+		signature = symbol.Signature
+	} else {
+		signature = tc.getSignature(node)
+	}
+
+	// 声明了非 void/any 返回类型的函数，函数体中必须有 return 语句（没有函数体时不检查）
+	if n := len(node.Children); n > 0 && node.Children[n-1].Kind == Block &&
+		signature.ReturnType != TypeVoid && signature.ReturnType != TypeAny {
+		if !containsReturn(node.Children[n-1]) {
+			tc.report(node, DiagMustReturnValue,
+				"A function whose declared type is neither 'undefined', 'void', nor 'any' must return a value.")
+		}
+	}
+	return signature
+}
+
+func containsReturn(block *ASTNode) bool {
+	for _, stmt := range block.Children {
+		if stmt.Kind == ReturnStatement {
+			return true
+		}
+	}
+	return false
+}
+
+func (tc *TypeChecker) checkVariableDeclaration(node *ASTNode) {
+	declaredType := TypeAny
+	if node.TypeAnnotation != "" {
+		declaredType = tc.resolveTypeAnnotation(node)
+	}
+
+	if len(node.Children) > 0 {
+		initializer := node.Children[0]
+		initType := tc.checkExpression(initializer)
+		if node.TypeAnnotation == "" {
+			// 没有类型注解时，变量类型由初始化表达式推断
+			declaredType = initType
+		} else if !isAssignable(initType, declaredType) {
+			tc.report(initializer, DiagTypeNotAssignable, "Type '%s' is not assignable to type '%s'.", initType, declaredType)
+		}
+	}
+	tc.nodeTypes[node] = declaredType
+}
+
+func (tc *TypeChecker) checkReturnStatement(node *ASTNode) {
+	if len(tc.functions) == 0 || len(node.Children) == 0 {
+		return
+	}
+	returnType := tc.functions[len(tc.functions)-1].ReturnType
+	exprType := tc.checkExpression(node.Children[0])
+	if !isAssignable(exprType, returnType) {
+		tc.report(node.Children[0], DiagTypeNotAssignable, "Type '%s' is not assignable to type '%s'.", exprType, returnType)
+	}
+}
+
+// checkExpression 推断表达式类型。结果按节点缓存，
+// visitNode 再次访问子表达式时不会重复检查和重复报告
+func (tc *TypeChecker) checkExpression(node *ASTNode) string {
+	if t, ok := tc.nodeTypes[node]; ok {
+		return t
+	}
+
+	var t string
+	switch node.Kind {
+	case NumericLiteral:
+		t = TypeNumber
+	case StringLiteral:
+		t = TypeString
+	case BooleanLiteral:
+		t = TypeBoolean
+	case Identifier:
+		t = tc.checkIdentifier(node)
+	case BinaryExpression:
+		t = tc.checkBinaryExpression(node)
+	case CallExpression:
+		t = tc.checkCallExpression(node)
+	default:
+		t = TypeAny
+	}
+	tc.nodeTypes[node] = t
+	return t
+}
+
+func (tc *TypeChecker) checkIdentifier(node *ASTNode) string {
+	symbol := tc.resolveSymbol(node.Name)
+	if tc.references != nil {
+		tc.references.addReference(tc.fileName, node, symbol)
+	}
+	if symbol == nil {
+		tc.report(node, DiagCannotFindName, "Cannot find name '%s'.", node.Name)
+		return TypeAny
+	}
+	return symbol.Type
+}
+
+// checkBinaryExpression 检查前两个子节点作为左右操作数。赋值和逗号表达式的结果是右操作数的类型，
+// 位运算、移位和 ** 按算术运算检查。生成的 AST（见 generateWorkloadAST）中
+// 节点的子节点数和名称是随机的：不足两个操作数时结果为 any，名称不是运算符时按 && || 处理
+func (tc *TypeChecker) checkBinaryExpression(node *ASTNode) string {
+	if len(node.Children) < 2 {
+		return TypeAny
+	}
+	left := tc.checkExpression(node.Children[0])
+	right := tc.checkExpression(node.Children[1])
+	op := node.Name
+
+	switch op {
+	case "=", ",":
+		return right
+	case "+=", "-=", "*=", "/=", "%=", "**=", "<<=", ">>=", ">>>=", "&=", "|=", "^=", "&&=", "||=", "??=":
+		return left
+	case "-", "*", "/", "%", "**", "&", "|", "^", "<<", ">>", ">>>":
+		if left != TypeNumber && left != TypeAny {
+			tc.report(node.Children[0], DiagArithmeticLeftOperand,
+				"The left-hand side of an arithmetic operation must be of type 'any', 'number', 'bigint' or an enum type.")
+		}
+		if right != TypeNumber && right != TypeAny {
+			tc.report(node.Children[1], DiagArithmeticRightOperand,
+				"The right-hand side of an arithmetic operation must be of type 'any', 'number', 'bigint' or an enum type.")
+		}
+		return TypeNumber
+	case "+":
+		switch {
+		case left == TypeAny || right == TypeAny:
+			return TypeAny
+		case left == TypeNumber && right == TypeNumber:
+			return TypeNumber
+		case left == TypeString || right == TypeString:
+			return TypeString
+		}
+		tc.report(node, DiagOperatorCannotBeApplied, "Operator '+' cannot be applied to types '%s' and '%s'.", left, right)
+		return TypeAny
+	case "<", ">", "<=", ">=":
+		if left != right && left != TypeAny && right != TypeAny {
+			tc.report(node, DiagOperatorCannotBeApplied, "Operator '%s' cannot be applied to types '%s' and '%s'.", op, left, right)
+		}
+		return TypeBoolean
+	case "==", "!=", "===", "!==":
+		if left != right && left != TypeAny && right != TypeAny {
+			tc.report(node, DiagComparisonNoOverlap,
+				"This comparison appears to be unintentional because the types '%s' and '%s' have no overlap.", left, right)
+		}
+		return TypeBoolean
+	case "instanceof", "in":
+		return TypeBoolean
+	default: // && || ??
+		// 简化：两侧类型相同则为该类型，否则为 any（不建模联合类型）
+		if left == right {
+			return left
+		}
+		return TypeAny
+	}
+}
+
+// checkCallExpression 检查第一个子节点作为被调用表达式，其余为实参；没有子节点时结果为 any
+func (tc *TypeChecker) checkCallExpression(node *ASTNode) string {
+	if len(node.Children) == 0 {
+		return TypeAny
+	}
+	callee := node.Children[0]
+	args := node.Children[1:]
+	calleeType := tc.checkExpression(callee)
+
+	var signature *Signature
+	if callee.Kind == Identifier {
+		if symbol := tc.resolveSymbol(callee.Name); symbol != nil {
+			signature = symbol.Signature
+		}
+	}
+	if signature == nil {
+		for _, arg := range args {
+			tc.checkExpression(arg)
+		}
+		if calleeType != TypeAny {
+			tc.report(callee, DiagNotCallable, "This expression is not callable. Type '%s' has no call signatures.", calleeType)
+		}
+		return TypeAny
+	}
+
+	if len(args) != len(signature.ParamTypes) {
+		tc.report(node, DiagExpectedArguments, "Expected %d arguments, but got %d.", len(signature.ParamTypes), len(args))
+	}
+	for i, arg := range args {
+		argType := tc.checkExpression(arg)
+		if i < len(signature.ParamTypes) && !isAssignable(argType, signature.ParamTypes[i]) {
+			tc.report(arg, DiagArgumentNotAssignable,
+				"Argument of type '%s' is not assignable to parameter of type '%s'.", argType, signature.ParamTypes[i])
+		}
+	}
+	return signature.ReturnType
+}
+
+// isAssignable 判断 source 类型能否赋值给 target 类型
+func isAssignable(source, target string) bool {
+	return source == target || source == TypeAny || target == TypeAny
+}
+
+// 2. 符号表查找测试
+func (tc *TypeChecker) resolveSymbol(name string) *Symbol {
+	symbol, _ := tc.lookupSymbol(name)
+	return symbol
+}
+
+// lookupSymbol 沿作用域链由内向外查找符号，同时返回符号所在的作用域
+func (tc *TypeChecker) lookupSymbol(name string) (*Symbol, *SymbolTable) {
+	for current := tc.symbolTable; current != nil; current = current.parent {
+		if current == tc.globals {
+			tc.mu.RLock()
+			symbol, exists := current.symbols[name]
+			tc.mu.RUnlock()
+			if exists {
+				return symbol, current
+			}
+			continue
+		}
+		if symbol, exists := current.symbols[name]; exists {
+			return symbol, current
+		}
+	}
+	return nil, nil
+}
+
+// declareSymbol 在当前作用域声明符号。允许遮蔽外层作用域的同名符号，
+// 但同一作用域内重复声明会返回 *RedeclarationError
+func (tc *TypeChecker) declareSymbol(symbol *Symbol) (*Symbol, error) {
+	scope := tc.symbolTable
+	if scope == tc.globals {
+		tc.mu.Lock()
+		defer tc.mu.Unlock()
+	}
+
+	if previous, exists := scope.symbols[symbol.Name]; exists {
+		return previous, &RedeclarationError{Name: symbol.Name, Previous: previous}
+	}
+	symbol.Scope = scope.depth
+	scope.symbols[symbol.Name] = symbol
+	return symbol, nil
+}
+
+func (tc *TypeChecker) addSymbol(name, symbolType string) {
+	tc.declareSymbol(&Symbol{Name: name, Kind: SymbolVariable, Type: symbolType})
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 诊断代码与 TypeScript 编译器保持一致，便于对照 tsc 的输出
+const (
+	DiagExpressionExpected         = 1109
+	DiagDuplicateIdentifier        = 2300
+	DiagCannotFindName             = 2304
+	DiagTypeNotAssignable          = 2322
+	DiagArgumentNotAssignable      = 2345
+	DiagNotCallable                = 2349
+	DiagMustReturnValue            = 2355
+	DiagArithmeticLeftOperand      = 2362
+	DiagArithmeticRightOperand     = 2363
+	DiagOperatorCannotBeApplied    = 2365
+	DiagComparisonNoOverlap        = 2367
+	DiagDuplicateFunction          = 2393
+	DiagCannotRedeclareBlockScoped = 2451
+	DiagExpectedArguments          = 2554
+)
+
+// DiagnosticSeverity 诊断级别，对应 TypeScript 的 DiagnosticCategory
+type DiagnosticSeverity int
+
+const (
+	SeverityError DiagnosticSeverity = iota
+	SeverityWarning
+	SeveritySuggestion
+)
+
+func (s DiagnosticSeverity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "suggestion"
+	}
+}
+
+// Diagnostic 检查器产生的诊断信息，[Pos, End) 为对应的源码范围
+type Diagnostic struct {
+	Severity DiagnosticSeverity
+	File     string
+	Pos      Position
+	End      Position
+	Code     int
+	Message  string
+}
+
+func (d *Diagnostic) String() string {
+	return fmt.Sprintf("%s(%d,%d): %s TS%d: %s", d.File, d.Pos.Line, d.Pos.Column, d.Severity, d.Code, d.Message)
+}
+
+func newDiagnostic(file string, node *ASTNode, code int, format string, args ...interface{}) *Diagnostic {
+	return &Diagnostic{
+		Severity: SeverityError,
+		File:     file,
+		Pos:      node.Pos,
+		End:      node.End,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// compareDiagnostics 定义诊断的全序：文件、起止位置、代码、级别、消息
+func compareDiagnostics(a, b *Diagnostic) int {
+	switch {
+	case a.File != b.File:
+		return strings.Compare(a.File, b.File)
+	case a.Pos.Offset != b.Pos.Offset:
+		return a.Pos.Offset - b.Pos.Offset
+	case a.End.Offset != b.End.Offset:
+		return a.End.Offset - b.End.Offset
+	case a.Code != b.Code:
+		return a.Code - b.Code
+	case a.Severity != b.Severity:
+		return int(a.Severity) - int(b.Severity)
+	}
+	return strings.Compare(a.Message, b.Message)
+}
+
+// sortDiagnostics 按 compareDiagnostics 原地排序
+func sortDiagnostics(diagnostics []*Diagnostic) {
+	sort.Slice(diagnostics, func(i, j int) bool {
+		return compareDiagnostics(diagnostics[i], diagnostics[j]) < 0
+	})
+}
+
+// DiagnosticCollector 并发安全的诊断收集器。
+// 多个 goroutine 可以同时 add，sorted 返回的顺序与添加顺序无关
+type DiagnosticCollector struct {
+	mu          sync.Mutex
+	diagnostics []*Diagnostic
+}
+
+// NewDiagnosticCollector 创建新的诊断收集器
+func NewDiagnosticCollector() *DiagnosticCollector {
+	return &DiagnosticCollector{}
+}
+
+func (c *DiagnosticCollector) add(diagnostics ...*Diagnostic) {
+	if len(diagnostics) == 0 {
+		return
+	}
+	c.mu.Lock()
+	c.diagnostics = append(c.diagnostics, diagnostics...)
+	c.mu.Unlock()
+}
+
+func (c *DiagnosticCollector) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.diagnostics)
+}
+
+// sorted 返回排序后的诊断副本
+func (c *DiagnosticCollector) sorted() []*Diagnostic {
+	c.mu.Lock()
+	result := make([]*Diagnostic, len(c.diagnostics))
+	copy(result, c.diagnostics)
+	c.mu.Unlock()
+
+	sortDiagnostics(result)
+	return result
+}
+
+// fingerprint 返回排序后全部诊断的摘要，用于比较不同处理模式的结果是否一致
+func (c *DiagnosticCollector) fingerprint() string {
+	h := sha256.New()
+	for _, d := range c.sorted() {
+		io.WriteString(h, d.String())
+		io.WriteString(h, "\n")
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))[:16]
+}
+
+// summary 按级别统计诊断数量，例如 "3 error, 1 warning"
+func (c *DiagnosticCollector) summary() string {
+	counts := make(map[DiagnosticSeverity]int)
+	c.mu.Lock()
+	for _, d := range c.diagnostics {
+		counts[d.Severity]++
+	}
+	c.mu.Unlock()
+
+	parts := make([]string, 0, 3)
+	for _, severity := range []DiagnosticSeverity{SeverityError, SeverityWarning, SeveritySuggestion} {
+		if counts[severity] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[severity], severity))
+		}
+	}
+	if len(parts) == 0 {
+		return "无诊断"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// writeDiagnostics 按排序后的顺序输出前 limit 条诊断（limit <= 0 表示全部）
+func writeDiagnostics(w io.Writer, diagnostics []*Diagnostic, indent string, limit int) {
+	for i, d := range diagnostics {
+		if limit > 0 && i >= limit {
+			fmt.Fprintf(w, "%s... 还有 %d 条\n", indent, len(diagnostics)-limit)
+			return
+		}
+		fmt.Fprintf(w, "%s%s\n", indent, d)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import "strings"
+
+// 扁平 AST：与 ASTNode 表示同样的树，但所有节点的字段分别存放在连续的切片中（struct-of-arrays），
+// 节点之间用 int32 下标而不是指针相连。整棵树只有十几次切片分配，切片中除名称文本外不含指针，
+// 垃圾回收器不需要逐个扫描节点；代价是节点不能单独引用，增删节点也不方便
+
+// NodeIndex 扁平 AST 中节点的下标
+type NodeIndex int32
+
+// NoNode 表示没有节点（根节点的父节点、没有子节点或下一个兄弟节点）
+const NoNode NodeIndex = -1
+
+// textSpan 名称等文本在 FlatAST.text 中的范围
+type textSpan struct {
+	start, end int32
+}
+
+// FlatAST 扁平 AST。节点按加入的顺序编号，根节点为 0；每个节点记录父节点、第一个子节点和下一个兄弟节点，
+// 子节点的顺序与加入的顺序相同。按前序遍历顺序加入节点时，编号就是前序遍历的顺序
+type FlatAST struct {
+	kinds           []NodeKind
+	flags           []NodeFlags
+	names           []textSpan
+	typeAnnotations []textSpan
+	pos             []Position
+	end             []Position
+	parent          []NodeIndex
+	firstChild      []NodeIndex
+	nextSibling     []NodeIndex
+	lastChild       []NodeIndex // 只在加入节点时使用，使追加子节点不需要遍历兄弟链，Finish 之后为 nil
+
+	text strings.Builder // 所有名称和类型注解依次拼接
+}
+
+// NewFlatAST 创建空的扁平 AST，capacity 为预计的节点数
+func NewFlatAST(capacity int) *FlatAST {
+	return &FlatAST{
+		kinds:           make([]NodeKind, 0, capacity),
+		flags:           make([]NodeFlags, 0, capacity),
+		names:           make([]textSpan, 0, capacity),
+		typeAnnotations: make([]textSpan, 0, capacity),
+		pos:             make([]Position, 0, capacity),
+		end:             make([]Position, 0, capacity),
+		parent:          make([]NodeIndex, 0, capacity),
+		firstChild:      make([]NodeIndex, 0, capacity),
+		nextSibling:     make([]NodeIndex, 0, capacity),
+		lastChild:       make([]NodeIndex, 0, capacity),
+	}
+}
+
+// AddNode 加入一个节点作为 parent 的最后一个子节点（parent 为 NoNode 时是根节点），返回它的下标。
+// 不能在 Finish 之后调用
+func (a *FlatAST) AddNode(parent NodeIndex, kind NodeKind, name string) NodeIndex {
+	node := NodeIndex(len(a.kinds))
+	a.kinds = append(a.kinds, kind)
+	a.flags = append(a.flags, 0)
+	a.names = append(a.names, a.addText(name))
+	a.typeAnnotations = append(a.typeAnnotations, textSpan{})
+	a.pos = append(a.pos, Position{})
+	a.end = append(a.end, Position{})
+	a.parent = append(a.parent, parent)
+	a.firstChild = append(a.firstChild, NoNode)
+	a.nextSibling = append(a.nextSibling, NoNode)
+	a.lastChild = append(a.lastChild, NoNode)
+	if parent != NoNode {
+		if last := a.lastChild[parent]; last != NoNode {
+			a.nextSibling[last] = node
+		} else {
+			a.firstChild[parent] = node
+		}
+		a.lastChild[parent] = node
+	}
+	return node
+}
+
+// Finish 结束构建，释放只在加入节点时使用的 lastChild，使存活的 AST 只包含上面描述的布局
+func (a *FlatAST) Finish() {
+	a.lastChild = nil
+}
+
+func (a *FlatAST) addText(s string) textSpan {
+	start := int32(a.text.Len())
+	a.text.WriteString(s)
+	return textSpan{start, int32(a.text.Len())}
+}
+
+// spanText 返回 span 对应的文本。strings.Builder.String 不复制内容，截取子串也不分配内存
+func (a *FlatAST) spanText(span textSpan) string {
+	return a.text.String()[span.start:span.end]
+}
+
+// SetTypeAnnotation 设置节点的类型注解
+func (a *FlatAST) SetTypeAnnotation(node NodeIndex, annotation string) {
+	a.typeAnnotations[node] = a.addText(annotation)
+}
+
+// SetFlags 设置节点的标志
+func (a *FlatAST) SetFlags(node NodeIndex, flags NodeFlags) { a.flags[node] = flags }
+
+// SetRange 设置节点的起止位置
+func (a *FlatAST) SetRange(node NodeIndex, pos, end Position) {
+	a.pos[node], a.end[node] = pos, end
+}
+
+// Len 返回节点数
+func (a *FlatAST) Len() int { return len(a.kinds) }
+
+// Root 返回根节点，空树时为 NoNode
+func (a *FlatAST) Root() NodeIndex {
+	if len(a.kinds) == 0 {
+		return NoNode
+	}
+	return 0
+}
+
+func (a *FlatAST) Kind(node NodeIndex) NodeKind         { return a.kinds[node] }
+func (a *FlatAST) Name(node NodeIndex) string           { return a.spanText(a.names[node]) }
+func (a *FlatAST) Flags(node NodeIndex) NodeFlags       { return a.flags[node] }
+func (a *FlatAST) TypeAnnotation(node NodeIndex) string { return a.spanText(a.typeAnnotations[node]) }
+func (a *FlatAST) Pos(node NodeIndex) Position          { return a.pos[node] }
+func (a *FlatAST) End(node NodeIndex) Position          { return a.end[node] }
+func (a *FlatAST) Parent(node NodeIndex) NodeIndex      { return a.parent[node] }
+func (a *FlatAST) FirstChild(node NodeIndex) NodeIndex  { return a.firstChild[node] }
+func (a *FlatAST) NextSibling(node NodeIndex) NodeIndex { return a.nextSibling[node] }
+
+// ChildCount 返回子节点数（需要遍历兄弟链）
+func (a *FlatAST) ChildCount(node NodeIndex) int {
+	count := 0
+	for child := a.firstChild[node]; child != NoNode; child = a.nextSibling[child] {
+		count++
+	}
+	return count
+}
+
+// Walk 从 node 开始深度优先遍历，与 visitNode 的结构对应：enter 在访问子节点之前调用，返回 false 时跳过子节点；
+// leave（可以为 nil）在子节点之后调用，对应 visitNode 中退出作用域、声明变量等后处理。返回访问的节点数
+func (a *FlatAST) Walk(node NodeIndex, enter func(node NodeIndex) bool, leave func(node NodeIndex)) int {
+	count := 1
+	if enter(node) {
+		for child := a.firstChild[node]; child != NoNode; child = a.nextSibling[child] {
+			count += a.Walk(child, enter, leave)
+		}
+	}
+	if leave != nil {
+		leave(node)
+	}
+	return count
+}
+
+// flattenAST 把指针形式的 AST 按前序遍历顺序转换为扁平 AST
+func flattenAST(root *ASTNode) *FlatAST {
+	a := NewFlatAST(0)
+	var add func(node *ASTNode, parent NodeIndex)
+	add = func(node *ASTNode, parent NodeIndex) {
+		index := a.AddNode(parent, node.Kind, node.Name)
+		a.SetFlags(index, node.Flags)
+		if node.TypeAnnotation != "" {
+			a.SetTypeAnnotation(index, node.TypeAnnotation)
+		}
+		a.SetRange(index, node.Pos, node.End)
+		for _, child := range node.Children {
+			add(child, index)
+		}
+	}
+	add(root, NoNode)
+	a.Finish()
+	return a
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 类型驻留：结构相同的类型只保留一个实例，用 TypeID 表示。对象类型的名称不参与比较，
+// 成员相同的两个接口驻留为同一个类型；属性（名称、可选性和类型都相同）同样只保留一个实例。
+// 驻留后结构相同的类型按指针就能判断相同，关系缓存和实例化缓存的命中率也随之提高
+
+// TypeID 驻留类型的编号，0 表示没有类型
+type TypeID uint32
+
+// TypeInterner 类型驻留表，可以被多个 goroutine 同时使用
+type TypeInterner struct {
+	mu          sync.Mutex
+	ids         map[string]TypeID
+	types       []*Type // 下标为 TypeID，types[0] 不使用
+	propertyIDs map[string]int
+	properties  []*Property // 下标为 propertyIDs 中的编号
+
+	lookups int64
+	hits    int64
+}
+
+// InternerStats 驻留表的统计
+type InternerStats struct {
+	Lookups    int64 // 驻留类型的次数（包括嵌套的成员类型）
+	Hits       int64 // 其中已有结构相同的类型的次数
+	Types      int   // 驻留的类型数
+	Properties int   // 驻留的属性数
+}
+
+// HitRate 已有结构相同的类型的比例
+func (s InternerStats) HitRate() float64 {
+	if s.Lookups == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Lookups)
+}
+
+func (s InternerStats) String() string {
+	return fmt.Sprintf("%d 个类型、%d 个属性，驻留 %d 次（%.1f%% 已存在）", s.Types, s.Properties, s.Lookups, s.HitRate()*100)
+}
+
+// NewTypeInterner 创建空的驻留表
+func NewTypeInterner() *TypeInterner {
+	return &TypeInterner{
+		ids:         make(map[string]TypeID),
+		types:       []*Type{nil},
+		propertyIDs: make(map[string]int),
+	}
+}
+
+// Intern 返回与 t 结构相同的驻留类型的编号。第一次遇到某个结构时，以驻留后的成员类型创建新的实例
+func (in *TypeInterner) Intern(t *Type) TypeID {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.intern(t)
+}
+
+// Type 返回编号为 id 的驻留类型
+func (in *TypeInterner) Type(id TypeID) *Type {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.types[id]
+}
+
+// Stats 返回到目前为止的统计
+func (in *TypeInterner) Stats() InternerStats {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return InternerStats{Lookups: in.lookups, Hits: in.hits, Types: len(in.types) - 1, Properties: len(in.properties)}
+}
+
+// intern 先驻留成员类型，再按成员的编号组成结构键查找；类型参数只与自身相同，按创建编号区分
+func (in *TypeInterner) intern(t *Type) TypeID {
+	var key strings.Builder
+	var build func() *Type
+	switch t.Kind {
+	case PrimitiveType:
+		key.WriteString("p:" + t.Name)
+		build = func() *Type { return t } // 原始类型本来就只有一个实例
+	case TypeParameter:
+		key.WriteString("t:" + strconv.FormatUint(t.id, 10))
+		build = func() *Type { return t }
+	case UnionType:
+		// 成员按编号排序并去重，成员相同、顺序不同的联合也相同；驻留后只剩一个成员时就是该成员
+		ids := in.internAll(t.Members)
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		ids = slices.Compact(ids)
+		if len(ids) == 1 {
+			return ids[0]
+		}
+		key.WriteString("u:")
+		writeTypeIDs(&key, ids)
+		build = func() *Type { return newType(&Type{Kind: UnionType, Members: in.typesOf(ids)}) }
+	case FunctionType:
+		params := in.internAll(t.Params)
+		ret := in.intern(t.Return)
+		key.WriteString("f:")
+		writeTypeIDs(&key, params)
+		key.WriteString("=>" + strconv.Itoa(int(ret)))
+		build = func() *Type { return NewFunctionType(in.typesOf(params), in.types[ret]) }
+	case ReferenceType:
+		args := in.internAll(t.TypeArgs)
+		key.WriteString("r:" + t.Name + "<")
+		writeTypeIDs(&key, args)
+		build = func() *Type { return NewReferenceType(t.Name, in.typesOf(args)...) }
+	case ObjectType:
+		typeParams := in.internAll(t.TypeParams)
+		typeArgs := in.internAll(t.TypeArgs)
+		properties := make([]*Property, len(t.Properties))
+		key.WriteString("o:")
+		writeTypeIDs(&key, typeParams)
+		key.WriteString("<")
+		writeTypeIDs(&key, typeArgs)
+		key.WriteString("{")
+		for i, property := range t.Properties {
+			id := in.internProperty(property)
+			properties[i] = in.properties[id]
+			key.WriteString(strconv.Itoa(id) + ";")
+		}
+		build = func() *Type {
+			return newType(&Type{Kind: ObjectType, Name: t.Name, Properties: properties,
+				TypeParams: in.typesOf(typeParams), TypeArgs: in.typesOf(typeArgs)})
+		}
+	}
+
+	in.lookups++
+	if id, ok := in.ids[key.String()]; ok {
+		in.hits++
+		return id
+	}
+	canonical := build()
+	id := TypeID(len(in.types))
+	in.types = append(in.types, canonical)
+	in.ids[key.String()] = id
+	return id
+}
+
+// internProperty 返回名称、可选性和驻留后的类型都相同的属性在 properties 中的编号
+func (in *TypeInterner) internProperty(property *Property) int {
+	typeID := in.intern(property.Type)
+	key := property.Name + ":" + strconv.Itoa(int(typeID))
+	if property.Optional {
+		key = property.Name + "?:" + strconv.Itoa(int(typeID))
+	}
+	if id, ok := in.propertyIDs[key]; ok {
+		return id
+	}
+	id := len(in.properties)
+	in.properties = append(in.properties, &Property{Name: property.Name, Type: in.types[typeID], Optional: property.Optional})
+	in.propertyIDs[key] = id
+	return id
+}
+
+func (in *TypeInterner) internAll(types []*Type) []TypeID {
+	ids := make([]TypeID, len(types))
+	for i, t := range types {
+		ids[i] = in.intern(t)
+	}
+	return ids
+}
+
+func (in *TypeInterner) typesOf(ids []TypeID) []*Type {
+	if len(ids) == 0 {
+		return nil
+	}
+	types := make([]*Type, len(ids))
+	for i, id := range ids {
+		types[i] = in.types[id]
+	}
+	return types
+}
+
+// writeTypeIDs 以逗号分隔写出编号
+func writeTypeIDs(b *strings.Builder, ids []TypeID) {
+	for i, id := range ids {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(int(id)))
+	}
+}
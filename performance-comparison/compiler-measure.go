@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"runtime/trace"
+	"sort"
+	"time"
+)
+
+// MeasureConfig 重复测量的配置
+type MeasureConfig struct {
+	Warmup  int       // 预热次数，结果不计入统计
+	Samples int       // 计入统计的采样次数
+	Profile *Profiler // 非 nil 时剖析匹配的阶段（只剖析采样，不含预热）
+}
+
+// String 用于文字报告，例如 "中位数 1.20 ms（p95 1.35，标准差 0.08，95% CI [1.15, 1.27]，10 次）"
+func (s *TimingStats) String() string {
+	if s.Samples < 2 {
+		return fmt.Sprintf("%.2f ms（1 次）", s.MedianMs)
+	}
+	return fmt.Sprintf("中位数 %.2f ms（p95 %.2f，标准差 %.2f，95%% CI [%.2f, %.2f]，%d 次）",
+		s.MedianMs, s.P95Ms, s.StdDevMs, s.CILowMs, s.CIHighMs, s.Samples)
+}
+
+// measureBenchmark 预热 config.Warmup 次后采样 config.Samples 次，返回的结果中
+// WallTimeMs 为中位数，AllocBytes 和 GCCount 为每次采样的平均值。
+// prepare 在每次运行前调用且不计时，可以为 nil。
+// 每次采样在执行跟踪中是一个以测试名称命名的区域
+func measureBenchmark(name string, params map[string]interface{}, config MeasureConfig, prepare, fn func()) BenchmarkResult {
+	for i := 0; i < config.Warmup; i++ {
+		if prepare != nil {
+			prepare()
+		}
+		fn()
+	}
+
+	samples := max(config.Samples, 1)
+	durations := make([]time.Duration, samples)
+	var allocBytes uint64
+	var gcCount uint32
+	profile := config.Profile.begin(name, params)
+	for i := range durations {
+		if prepare != nil {
+			prepare()
+		}
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+		trace.WithRegion(context.Background(), name, fn)
+		durations[i] = time.Since(start)
+		runtime.ReadMemStats(&after)
+		allocBytes += after.TotalAlloc - before.TotalAlloc
+		gcCount += after.NumGC - before.NumGC
+	}
+	profile.end()
+
+	result := sampledResult(name, params, durations, config.Warmup)
+	result.AllocBytes = allocBytes / uint64(samples)
+	result.GCCount = gcCount / uint32(samples)
+	return result
+}
+
+// sampledResult 由已测得的多次耗时生成结果，WallTimeMs 为中位数。
+// 用于在别处计时的采样，例如检查服务中每个请求的延迟
+func sampledResult(name string, params map[string]interface{}, durations []time.Duration, warmup int) BenchmarkResult {
+	stats := newTimingStats(durations, warmup)
+	return BenchmarkResult{
+		Benchmark:  name,
+		Language:   "go",
+		Parameters: params,
+		WallTimeMs: stats.MedianMs,
+		CPUCount:   runtime.NumCPU(),
+		GoVersion:  runtime.Version(),
+		GOOS:       runtime.GOOS,
+		GOARCH:     runtime.GOARCH,
+		Stats:      stats,
+	}
+}
+
+func newTimingStats(durations []time.Duration, warmup int) *TimingStats {
+	n := len(durations)
+	samples := make([]float64, n)
+	sum := 0.0
+	for i, d := range durations {
+		samples[i] = float64(d.Nanoseconds()) / 1000000
+		sum += samples[i]
+	}
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+
+	stats := &TimingStats{
+		Warmup:    warmup,
+		Samples:   n,
+		MeanMs:    sum / float64(n),
+		MedianMs:  percentile(sorted, 0.5),
+		P95Ms:     percentile(sorted, 0.95),
+		MinMs:     sorted[0],
+		MaxMs:     sorted[n-1],
+		SamplesMs: samples,
+	}
+	stats.CILowMs, stats.CIHighMs = stats.MeanMs, stats.MeanMs
+	if n > 1 {
+		variance := 0.0
+		for _, v := range samples {
+			variance += (v - stats.MeanMs) * (v - stats.MeanMs)
+		}
+		stats.StdDevMs = math.Sqrt(variance / float64(n-1))
+		margin := tCritical95(float64(n-1)) * stats.StdDevMs / math.Sqrt(float64(n))
+		stats.CILowMs -= margin
+		stats.CIHighMs += margin
+	}
+	return stats
+}
+
+// percentile 对已排序的数据按线性插值求分位数
+func percentile(sorted []float64, p float64) float64 {
+	pos := p * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(pos-float64(lower))
+}
+
+// t 分布双侧 95% 临界值，下标为自由度 1..30
+var tTable95 = []float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// tCritical95 返回自由度 df 对应的临界值，非整数自由度向下取整（偏保守）
+func tCritical95(df float64) float64 {
+	switch d := int(df); {
+	case d < 1:
+		return math.Inf(1)
+	case d <= len(tTable95):
+		return tTable95[d-1]
+	case d < 60:
+		return 2.021
+	case d < 120:
+		return 2.000
+	default:
+		return 1.960
+	}
+}
+
+// significantlyDifferent 用 Welch t 检验判断两组采样的均值在 95% 置信水平下是否不同。
+// 任意一组少于 2 次采样时无法判断，ok 返回 false
+func significantlyDifferent(a, b *TimingStats) (different, ok bool) {
+	if a == nil || b == nil || a.Samples < 2 || b.Samples < 2 {
+		return false, false
+	}
+	va := a.StdDevMs * a.StdDevMs / float64(a.Samples)
+	vb := b.StdDevMs * b.StdDevMs / float64(b.Samples)
+	if va+vb == 0 {
+		return a.MeanMs != b.MeanMs, true
+	}
+	t := math.Abs(a.MeanMs-b.MeanMs) / math.Sqrt(va+vb)
+	df := (va + vb) * (va + vb) / (va*va/float64(a.Samples-1) + vb*vb/float64(b.Samples-1))
+	return t > tCritical95(df), true
+}
+
+// speedupString 格式化 before 相对 after 的加速比，差异不显著时注明
+func speedupString(before, after *TimingStats) string {
+	s := fmt.Sprintf("%.2fx", before.MedianMs/after.MedianMs)
+	if different, ok := significantlyDifferent(before, after); ok && !different {
+		s += "（差异不显著）"
+	}
+	return s
+}
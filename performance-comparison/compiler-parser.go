@@ -0,0 +1,2036 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 支持的 TypeScript 子集：
+//
+//	[export] [async] function name[<T>](a: T, b?: T, c = 1, ...d: T[]): R { ... }
+//	[export] let|const|var name[: T] [= expr][, ...];
+//	if (...) ... [else ...]    for (...; ...; ...) ...    for (const x of|in y) ...
+//	while (...) ...    do ... while (...);    switch (...) { case ...: ... }
+//	try { ... } catch (e) { ... } finally { ... }
+//	return [expr];    break;    continue;    throw expr;    { ... }    expr;
+//
+// 表达式支持标识符、字面量（数字、字符串、模板字符串、正则表达式、布尔、数组、对象）、属性访问
+// （a.b、a?.b、a[i]）、函数调用、new、一元和二元运算、赋值、条件表达式以及 as/satisfies（类型被丢弃）。
+// 类型注解保留类型的文本（见 parseTypeAnnotation）。
+//
+// class、interface、enum 只保留名称，成员被跳过；函数表达式和箭头函数只产生一个节点，参数和函数体被跳过；
+// import、namespace、declare module 等语句整体跳过。跳过的源码字节数记录在 Parser.skipped 中，
+// 用于报告 AST 覆盖了多少源码。
+
+// TokenKind 词法单元类型
+type TokenKind int
+
+const (
+	TokenEOF TokenKind = iota
+	TokenIdentifier
+	TokenNumber
+	TokenString // 也包括模板字符串，Text 为反引号之间的原文
+	TokenKeyword
+	TokenPunctuation
+	TokenOperator
+	TokenRegExp
+)
+
+// Token 词法单元
+type Token struct {
+	Kind TokenKind
+	Text string
+	Pos  Position
+	End  Position
+}
+
+// class、interface、type、async、of 等上下文关键字在词法上是标识符，由解析器按位置判断
+var keywords = map[string]bool{
+	"function":   true,
+	"let":        true,
+	"const":      true,
+	"return":     true,
+	"export":     true,
+	"true":       true,
+	"false":      true,
+	"if":         true,
+	"else":       true,
+	"for":        true,
+	"while":      true,
+	"do":         true,
+	"break":      true,
+	"continue":   true,
+	"throw":      true,
+	"try":        true,
+	"catch":      true,
+	"finally":    true,
+	"switch":     true,
+	"case":       true,
+	"new":        true,
+	"typeof":     true,
+	"instanceof": true,
+	"in":         true,
+	"delete":     true,
+}
+
+// 出错后 synchronize 停在这些关键字之前，它们开始一条新的语句或 case 子句
+var statementKeywords = map[string]bool{
+	"function": true,
+	"let":      true,
+	"const":    true,
+	"return":   true,
+	"export":   true,
+	"if":       true,
+	"for":      true,
+	"while":    true,
+	"do":       true,
+	"break":    true,
+	"continue": true,
+	"throw":    true,
+	"try":      true,
+	"switch":   true,
+	"case":     true,
+}
+
+// 按长度从长到短排列，保证最长匹配。不识别 >> 等右移运算符：Array<Array<T>> 中的 >> 是两个类型实参的结束，
+// 表达式中相邻的 > 由解析器合并（见 peekOperator）
+var operators = []string{
+	"...", "===", "!==", "**=", "&&=", "||=", "??=", "<<=",
+	"=>", "==", "!=", "<=", ">=", "&&", "||", "??", "?.", "++", "--",
+	"+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "**", "<<",
+	"+", "-", "*", "/", "%", "<", ">", "=", "!", "~", "&", "|", "^", "?",
+}
+
+// 二元运算符优先级，数值越大越先结合
+var binaryPrecedence = map[string]int{
+	"??":         1,
+	"||":         1,
+	"&&":         2,
+	"|":          3,
+	"^":          4,
+	"&":          5,
+	"==":         6,
+	"!=":         6,
+	"===":        6,
+	"!==":        6,
+	"<":          7,
+	">":          7,
+	"<=":         7,
+	">=":         7,
+	"instanceof": 7,
+	"in":         7,
+	"<<":         8,
+	">>":         8,
+	">>>":        8,
+	"+":          9,
+	"-":          9,
+	"*":          10,
+	"/":          10,
+	"%":          10,
+	"**":         11,
+}
+
+var assignmentOperators = map[string]bool{
+	"=": true, "+=": true, "-=": true, "*=": true, "/=": true, "%=": true, "**=": true,
+	"<<=": true, ">>=": true, ">>>=": true, "&=": true, "|=": true, "^=": true,
+	"&&=": true, "||=": true, "??=": true,
+}
+
+// 前缀运算符；... 是展开运算符，也按前缀运算处理
+var prefixOperators = map[string]bool{
+	"!": true, "-": true, "+": true, "~": true, "++": true, "--": true, "...": true,
+}
+
+// ParseError 解析错误
+type ParseError struct {
+	Pos     Position
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
+// Lexer 词法分析器
+type Lexer struct {
+	src    string
+	offset int
+	line   int
+	column int
+	errors []*ParseError
+
+	// 上一个词法单元是操作数（标识符、字面量、右括号等），此时 / 是除号而不是正则表达式的开始
+	afterOperand bool
+}
+
+// NewLexer 创建新的词法分析器
+func NewLexer(src string) *Lexer {
+	return &Lexer{src: src, line: 1, column: 1}
+}
+
+func (l *Lexer) position() Position {
+	return Position{Offset: l.offset, Line: l.line, Column: l.column}
+}
+
+func (l *Lexer) peekByte(ahead int) byte {
+	if l.offset+ahead >= len(l.src) {
+		return 0
+	}
+	return l.src[l.offset+ahead]
+}
+
+func (l *Lexer) advance(n int) {
+	for i := 0; i < n && l.offset < len(l.src); i++ {
+		if l.src[l.offset] == '\n' {
+			l.line++
+			l.column = 1
+		} else {
+			l.column++
+		}
+		l.offset++
+	}
+}
+
+// 跳过空白和注释
+func (l *Lexer) skipTrivia() {
+	for l.offset < len(l.src) {
+		ch := l.src[l.offset]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n':
+			l.advance(1)
+		case ch == '/' && l.peekByte(1) == '/':
+			for l.offset < len(l.src) && l.src[l.offset] != '\n' {
+				l.advance(1)
+			}
+		case ch == '/' && l.peekByte(1) == '*':
+			l.advance(2)
+			for l.offset < len(l.src) && !(l.src[l.offset] == '*' && l.peekByte(1) == '/') {
+				l.advance(1)
+			}
+			l.advance(2)
+		default:
+			return
+		}
+	}
+}
+
+// 标识符可以包含 # （私有字段）和非 ASCII 字符
+func isIdentifierStart(ch byte) bool {
+	return ch == '_' || ch == '$' || ch == '#' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch >= 0x80
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+// nextToken 读取下一个词法单元
+func (l *Lexer) nextToken() Token {
+	tok := l.scanToken()
+	l.afterOperand = endsOperand(tok)
+	return tok
+}
+
+func (l *Lexer) scanToken() Token {
+	l.skipTrivia()
+	start := l.position()
+	if l.offset >= len(l.src) {
+		return Token{Kind: TokenEOF, Pos: start, End: start}
+	}
+
+	ch := l.src[l.offset]
+	switch {
+	case isIdentifierStart(ch):
+		for l.offset < len(l.src) && (isIdentifierStart(l.src[l.offset]) || isDigit(l.src[l.offset])) {
+			l.advance(1)
+		}
+		text := l.src[start.Offset:l.offset]
+		kind := TokenIdentifier
+		if keywords[text] {
+			kind = TokenKeyword
+		}
+		return Token{Kind: kind, Text: text, Pos: start, End: l.position()}
+
+	case isDigit(ch) || ch == '.' && isDigit(l.peekByte(1)):
+		l.scanNumber()
+		return Token{Kind: TokenNumber, Text: l.src[start.Offset:l.offset], Pos: start, End: l.position()}
+
+	case ch == '"' || ch == '\'':
+		l.advance(1)
+		for l.offset < len(l.src) && l.src[l.offset] != ch && l.src[l.offset] != '\n' {
+			if l.src[l.offset] == '\\' {
+				l.advance(1)
+			}
+			l.advance(1)
+		}
+		if l.offset >= len(l.src) || l.src[l.offset] != ch {
+			l.errors = append(l.errors, &ParseError{Pos: start, Message: "未结束的字符串字面量"})
+			return Token{Kind: TokenString, Text: l.src[start.Offset+1 : l.offset], Pos: start, End: l.position()}
+		}
+		text := l.src[start.Offset+1 : l.offset]
+		l.advance(1)
+		return Token{Kind: TokenString, Text: text, Pos: start, End: l.position()}
+
+	case ch == '`':
+		if !l.scanTemplate() {
+			l.errors = append(l.errors, &ParseError{Pos: start, Message: "未结束的模板字符串"})
+			return Token{Kind: TokenString, Text: l.src[start.Offset+1 : l.offset], Pos: start, End: l.position()}
+		}
+		return Token{Kind: TokenString, Text: l.src[start.Offset+1 : l.offset-1], Pos: start, End: l.position()}
+
+	case ch == '/' && !l.afterOperand:
+		if !l.scanRegExp() {
+			l.errors = append(l.errors, &ParseError{Pos: start, Message: "未结束的正则表达式字面量"})
+		}
+		return Token{Kind: TokenRegExp, Text: l.src[start.Offset:l.offset], Pos: start, End: l.position()}
+
+	case ch == '.' && l.peekByte(1) != '.' || strings.ContainsRune("(){}[],;:@", rune(ch)):
+		l.advance(1)
+		return Token{Kind: TokenPunctuation, Text: string(ch), Pos: start, End: l.position()}
+	}
+
+	for _, op := range operators {
+		if op[0] == ch && strings.HasPrefix(l.src[l.offset:], op) {
+			if op == "?." && isDigit(l.peekByte(2)) {
+				continue // a?.5:b 是条件表达式
+			}
+			l.advance(len(op))
+			return Token{Kind: TokenOperator, Text: op, Pos: start, End: l.position()}
+		}
+	}
+
+	l.errors = append(l.errors, &ParseError{Pos: start, Message: fmt.Sprintf("无法识别的字符 %q", ch)})
+	l.advance(1)
+	return l.scanToken()
+}
+
+// scanNumber 扫描数字字面量：小数、指数、0x/0o/0b 前缀、数字分隔符 _ 以及 BigInt 后缀 n
+func (l *Lexer) scanNumber() {
+	start := l.offset
+	for l.offset < len(l.src) {
+		ch := l.src[l.offset]
+		if !isDigit(ch) && ch != '.' && !isIdentifierStart(ch) {
+			return
+		}
+		l.advance(1)
+		// 指数的符号，十六进制数中的 e 是数字
+		if (ch == 'e' || ch == 'E') && (l.peekByte(0) == '+' || l.peekByte(0) == '-') &&
+			!strings.HasPrefix(l.src[start:], "0x") && !strings.HasPrefix(l.src[start:], "0X") {
+			l.advance(1)
+		}
+	}
+}
+
+// scanTemplate 扫描从 ` 开始的模板字符串，${...} 中的表达式按花括号配对跳过（其中可以嵌套字符串和模板字符串）。
+// 返回是否找到结束的 `
+func (l *Lexer) scanTemplate() bool {
+	l.advance(1)
+	for l.offset < len(l.src) {
+		switch ch := l.src[l.offset]; {
+		case ch == '\\':
+			l.advance(2)
+		case ch == '`':
+			l.advance(1)
+			return true
+		case ch == '$' && l.peekByte(1) == '{':
+			l.advance(2)
+			if !l.skipTemplateExpression() {
+				return false
+			}
+		default:
+			l.advance(1)
+		}
+	}
+	return false
+}
+
+// skipTemplateExpression 跳过 ${ 之后的表达式直到配对的 }
+func (l *Lexer) skipTemplateExpression() bool {
+	depth := 0
+	for l.offset < len(l.src) {
+		switch ch := l.src[l.offset]; ch {
+		case '{':
+			depth++
+			l.advance(1)
+		case '}':
+			l.advance(1)
+			if depth == 0 {
+				return true
+			}
+			depth--
+		case '`':
+			if !l.scanTemplate() {
+				return false
+			}
+		case '"', '\'':
+			l.advance(1)
+			for l.offset < len(l.src) && l.src[l.offset] != ch && l.src[l.offset] != '\n' {
+				if l.src[l.offset] == '\\' {
+					l.advance(1)
+				}
+				l.advance(1)
+			}
+			l.advance(1)
+		default:
+			l.advance(1)
+		}
+	}
+	return false
+}
+
+// endsOperand 判断词法单元是否可以结束一个操作数：之后的 / 是除号。
+// 在运算符、关键字、左括号等之后是表达式的开始，/ 开始正则表达式字面量
+func endsOperand(tok Token) bool {
+	switch tok.Kind {
+	case TokenOperator:
+		return tok.Text == "++" || tok.Text == "--"
+	case TokenKeyword:
+		return tok.Text == "true" || tok.Text == "false"
+	case TokenPunctuation:
+		return tok.Text == ")" || tok.Text == "]" || tok.Text == "}"
+	}
+	return tok.Kind != TokenEOF
+}
+
+// scanRegExp 扫描从 / 开始的正则表达式字面量和标志，字符类 [...] 中的 / 不结束字面量；遇到换行时失败
+func (l *Lexer) scanRegExp() bool {
+	l.advance(1)
+	inClass := false
+	for l.offset < len(l.src) {
+		switch ch := l.src[l.offset]; {
+		case ch == '\n':
+			return false
+		case ch == '\\':
+			l.advance(2)
+			continue
+		case ch == '[':
+			inClass = true
+		case ch == ']':
+			inClass = false
+		case ch == '/' && !inClass:
+			l.advance(1)
+			for l.offset < len(l.src) && isIdentifierStart(l.src[l.offset]) {
+				l.advance(1)
+			}
+			return true
+		}
+		l.advance(1)
+	}
+	return false
+}
+
+// tokenize 将源码切分为词法单元（以 TokenEOF 结尾）
+func (l *Lexer) tokenize() []Token {
+	tokens := make([]Token, 0, len(l.src)/4)
+	for {
+		tok := l.nextToken()
+		tokens = append(tokens, tok)
+		if tok.Kind == TokenEOF {
+			return tokens
+		}
+	}
+}
+
+// Parser 递归下降解析器
+type Parser struct {
+	tokens  []Token
+	current int
+	errors  []*ParseError
+	skipped int  // 被跳过、没有进入 AST 的源码字节数
+	noIn    bool // 解析 for 语句的初始化部分时 in 不是二元运算符
+	ambient bool // 解析 declare 声明和 .d.ts 文件时 const 可以没有初始化
+}
+
+// NewParser 创建新的解析器
+func NewParser(tokens []Token) *Parser {
+	return &Parser{tokens: tokens}
+}
+
+// parseSource 解析一段源码，返回 SourceFile 根节点（Name 为文件名）和所有词法、语法错误
+func parseSource(fileName, src string) (*ASTNode, []*ParseError) {
+	lexer := NewLexer(src)
+	parser := NewParser(lexer.tokenize())
+	root := parser.parseSourceFile()
+	root.Name = fileName
+	return root, append(lexer.errors, parser.errors...)
+}
+
+func (p *Parser) peek() Token {
+	return p.tokens[p.current]
+}
+
+// peekAt 向前看第 ahead 个词法单元，越过结尾时返回 TokenEOF
+func (p *Parser) peekAt(ahead int) Token {
+	if i := p.current + ahead; i < len(p.tokens) {
+		return p.tokens[i]
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+func (p *Parser) next() Token {
+	tok := p.tokens[p.current]
+	if tok.Kind != TokenEOF {
+		p.current++
+	}
+	return tok
+}
+
+// 上一个已消费的词法单元的结束位置
+func (p *Parser) lastEnd() Position {
+	if p.current == 0 {
+		return p.tokens[0].Pos
+	}
+	return p.tokens[p.current-1].End
+}
+
+// newLine 判断当前词法单元是否与上一个词法单元不在同一行，用于自动插入分号
+func (p *Parser) newLine() bool {
+	return p.current > 0 && p.peek().Pos.Line > p.tokens[p.current-1].End.Line
+}
+
+func (p *Parser) is(kind TokenKind, text string) bool {
+	tok := p.peek()
+	return tok.Kind == kind && tok.Text == text
+}
+
+// isWord 判断当前词法单元是否是给定的上下文关键字
+func (p *Parser) isWord(text string) bool {
+	return p.is(TokenIdentifier, text)
+}
+
+func (p *Parser) atEOF() bool {
+	return p.peek().Kind == TokenEOF
+}
+
+func (p *Parser) accept(kind TokenKind, text string) bool {
+	if p.is(kind, text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *Parser) expect(kind TokenKind, text string) bool {
+	if p.accept(kind, text) {
+		return true
+	}
+	p.errorAt(p.peek(), fmt.Sprintf("应为 '%s'", text))
+	return false
+}
+
+func (p *Parser) expectIdentifier() (Token, bool) {
+	tok := p.peek()
+	if tok.Kind != TokenIdentifier {
+		p.errorAt(tok, "应为标识符")
+		return tok, false
+	}
+	return p.next(), true
+}
+
+func (p *Parser) errorAt(tok Token, message string) {
+	found := tok.Text
+	if tok.Kind == TokenEOF {
+		found = "文件结尾"
+	}
+	p.errors = append(p.errors, &ParseError{
+		Pos:     tok.Pos,
+		Message: fmt.Sprintf("%s，实际为 '%s'", message, found),
+	})
+}
+
+// skipFrom 把从 start 到上一个已消费的词法单元结束的源码计为跳过
+func (p *Parser) skipFrom(start Position) {
+	if end := p.lastEnd(); end.Offset > start.Offset {
+		p.skipped += end.Offset - start.Offset
+	}
+}
+
+// 出错后跳到下一个语句边界，避免一个错误引发连锁错误。括号内的内容整体跳过
+func (p *Parser) synchronize() {
+	start := p.peek().Pos
+	defer p.skipFrom(start)
+	for {
+		tok := p.peek()
+		switch {
+		case tok.Kind == TokenEOF:
+			return
+		case tok.Kind == TokenPunctuation && tok.Text == ";":
+			p.next()
+			return
+		case tok.Kind == TokenPunctuation && tok.Text == "}":
+			return
+		case tok.Kind == TokenKeyword && statementKeywords[tok.Text]:
+			return
+		case isOpenBracket(tok):
+			p.skipBalanced()
+			continue
+		}
+		p.next()
+	}
+}
+
+func isOpenBracket(tok Token) bool {
+	return tok.Kind == TokenPunctuation && (tok.Text == "(" || tok.Text == "[" || tok.Text == "{")
+}
+
+func isCloseBracket(tok Token) bool {
+	return tok.Kind == TokenPunctuation && (tok.Text == ")" || tok.Text == "]" || tok.Text == "}")
+}
+
+// skipBalanced 跳过从当前的 ( [ { 开始、到与之配对的括号为止的词法单元
+func (p *Parser) skipBalanced() {
+	depth := 0
+	for {
+		tok := p.next()
+		switch {
+		case tok.Kind == TokenEOF:
+			return
+		case isOpenBracket(tok):
+			depth++
+		case isCloseBracket(tok):
+			depth--
+		}
+		if depth <= 0 {
+			return
+		}
+	}
+}
+
+// skipTypeArguments 跳过从当前的 < 开始的类型参数或类型实参
+func (p *Parser) skipTypeArguments() {
+	depth := 0
+	for {
+		tok := p.peek()
+		switch {
+		case tok.Kind == TokenEOF || isCloseBracket(tok) || tok.Kind == TokenPunctuation && tok.Text == ";":
+			return
+		case isOpenBracket(tok):
+			p.skipBalanced()
+			continue
+		case tok.Kind == TokenOperator && tok.Text == "<":
+			depth++
+		case tok.Kind == TokenOperator && tok.Text == ">":
+			depth--
+		}
+		p.next()
+		if depth <= 0 {
+			return
+		}
+	}
+}
+
+// matchingIndex 向前看，返回与第 i 个词法单元（( [ { 或 <）配对的词法单元下标，没有配对时返回 -1
+func (p *Parser) matchingIndex(i int) int {
+	angle := p.tokens[i].Text == "<"
+	depth := 0
+	for ; i < len(p.tokens); i++ {
+		tok := p.tokens[i]
+		switch {
+		case tok.Kind == TokenEOF:
+			return -1
+		case isOpenBracket(tok), angle && tok.Kind == TokenOperator && tok.Text == "<":
+			depth++
+		case isCloseBracket(tok), angle && tok.Kind == TokenOperator && tok.Text == ">":
+			depth--
+		}
+		if depth == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+func newNode(kind NodeKind, name string, pos Position) *ASTNode {
+	return &ASTNode{
+		Kind:     kind,
+		Name:     name,
+		Pos:      pos,
+		Children: make([]*ASTNode, 0),
+	}
+}
+
+func appendChild(parent, child *ASTNode) {
+	if child == nil {
+		return
+	}
+	child.Parent = parent
+	parent.Children = append(parent.Children, child)
+}
+
+func (p *Parser) parseSourceFile() *ASTNode {
+	root := newNode(SourceFileNode, "", p.peek().Pos)
+	p.parseStatements(root, p.atEOF)
+	root.End = p.peek().End
+	return root
+}
+
+// parseStatements 解析语句列表，直到 end 返回 true 或文件结尾
+func (p *Parser) parseStatements(parent *ASTNode, end func() bool) {
+	for !p.atEOF() && !end() {
+		start := p.current
+		appendChild(parent, p.parseStatement())
+		if p.current == start {
+			// 保证在任何错误输入下都能前进
+			tok := p.next()
+			p.skipped += tok.End.Offset - tok.Pos.Offset
+		}
+	}
+}
+
+func (p *Parser) parseStatement() *ASTNode {
+	var flags NodeFlags
+	start := p.peek().Pos
+	p.skipDecorators()
+	if p.accept(TokenKeyword, "export") {
+		flags |= NodeFlagsExport
+		switch {
+		case p.is(TokenPunctuation, "{") || p.is(TokenOperator, "*") || p.is(TokenOperator, "=") || p.isWord("as") ||
+			p.isWord("type") && (p.peekAt(1).Kind == TokenPunctuation || p.peekAt(1).Kind == TokenOperator):
+			// export { a } from "./m"、export * from "./m"、export type * from "./m"、export = x 只有模块语法，
+			// 由 scanModuleSyntax 扫描
+			p.skipStatement(start, false)
+			return nil
+		case p.isWord("default"):
+			p.next()
+			if !p.isDeclarationStart() {
+				// export default 表达式
+				stmt := p.parseExpressionStatement()
+				if stmt != nil {
+					stmt.Flags |= flags
+					stmt.Pos = start
+				}
+				return stmt
+			}
+		}
+		p.skipDecorators()
+	}
+	if p.isWord("declare") && p.peekAt(1).Pos.Line == p.peek().Pos.Line {
+		stmt := p.parseDeclareStatement(start)
+		if stmt != nil && flags != 0 {
+			stmt.Flags |= flags
+			stmt.Pos = start
+		}
+		return stmt
+	}
+
+	tok := p.peek()
+	var stmt *ASTNode
+	switch {
+	case p.isDeclarationStart():
+		stmt = p.parseDeclaration(start)
+	case flags&NodeFlagsExport != 0:
+		p.errorAt(tok, "export 后应为声明")
+		p.synchronize()
+		return nil
+	case tok.Kind == TokenKeyword:
+		stmt = p.parseKeywordStatement()
+	case tok.Kind == TokenPunctuation && tok.Text == "{":
+		stmt = p.parseBlock()
+	case tok.Kind == TokenPunctuation && tok.Text == ";":
+		p.next() // 空语句
+		return nil
+	case tok.Kind == TokenIdentifier && p.peekAt(1).Kind == TokenPunctuation && p.peekAt(1).Text == ":":
+		// 带标签的语句 label: for (...)
+		p.next()
+		p.next()
+		return p.parseStatement()
+	default:
+		stmt = p.parseExpressionStatement()
+	}
+
+	if stmt != nil && flags != 0 {
+		stmt.Flags |= flags
+		stmt.Pos = start
+	}
+	return stmt
+}
+
+// isDeclarationStart 判断当前位置是否开始一个声明（function、变量、class、interface、type、enum、namespace、import）
+func (p *Parser) isDeclarationStart() bool {
+	tok, next := p.peek(), p.peekAt(1)
+	sameLine := next.Pos.Line == tok.End.Line
+	switch {
+	case tok.Kind == TokenKeyword:
+		return tok.Text == "function" || tok.Text == "let" || tok.Text == "const"
+	case tok.Kind != TokenIdentifier:
+		return false
+	}
+	switch tok.Text {
+	case "var":
+		return next.Kind == TokenIdentifier || next.Kind == TokenPunctuation && (next.Text == "{" || next.Text == "[")
+	case "async":
+		return sameLine && next.Kind == TokenKeyword && next.Text == "function"
+	case "abstract":
+		return sameLine && next.Kind == TokenIdentifier && next.Text == "class"
+	case "class":
+		return next.Kind == TokenIdentifier || next.Kind == TokenPunctuation && next.Text == "{"
+	case "interface", "enum":
+		return next.Kind == TokenIdentifier
+	case "type":
+		return sameLine && next.Kind == TokenIdentifier
+	case "namespace", "module":
+		return sameLine && (next.Kind == TokenIdentifier || next.Kind == TokenString)
+	case "global":
+		return sameLine && next.Kind == TokenPunctuation && next.Text == "{"
+	case "import":
+		return !(next.Kind == TokenPunctuation && (next.Text == "(" || next.Text == "."))
+	}
+	return false
+}
+
+// parseDeclaration 解析 isDeclarationStart 判断出的声明
+func (p *Parser) parseDeclaration(start Position) *ASTNode {
+	tok := p.peek()
+	switch tok.Text {
+	case "function":
+		return p.parseFunctionDeclaration()
+	case "async":
+		p.next()
+		return p.parseFunctionDeclaration()
+	case "let", "var":
+		return p.parseVariableStatement()
+	case "const":
+		if p.peekAt(1).Kind == TokenIdentifier && p.peekAt(1).Text == "enum" {
+			p.next() // const enum
+			return p.parseClassLikeDeclaration(EnumDeclaration)
+		}
+		return p.parseVariableStatement()
+	case "abstract":
+		p.next()
+		return p.parseClassLikeDeclaration(ClassDeclaration)
+	case "class":
+		return p.parseClassLikeDeclaration(ClassDeclaration)
+	case "interface":
+		return p.parseClassLikeDeclaration(InterfaceDeclaration)
+	case "enum":
+		return p.parseClassLikeDeclaration(EnumDeclaration)
+	case "type":
+		return p.parseTypeAliasDeclaration()
+	case "namespace", "module", "global":
+		// namespace 的成员不进入 AST
+		p.skipStatement(start, true)
+		return nil
+	default: // import，只有模块语法
+		p.skipStatement(start, false)
+		return nil
+	}
+}
+
+// parseDeclareStatement 解析 declare 声明：函数、变量、class 等按普通声明解析（函数没有函数体，const 可以没有初始化），
+// declare module 和 declare global 整体跳过
+func (p *Parser) parseDeclareStatement(start Position) *ASTNode {
+	p.next() // declare
+	if !p.isDeclarationStart() || p.isWord("namespace") || p.isWord("module") || p.isWord("global") {
+		p.skipStatement(start, true)
+		return nil
+	}
+	ambient := p.ambient
+	p.ambient = true
+	defer func() { p.ambient = ambient }()
+	return p.parseDeclaration(start)
+}
+
+// skipStatement 跳过从 start 开始的整条语句：到分号、换行（自动插入分号）或 } 为止，括号内的内容整体跳过；
+// body 为 true 时语句在第一个 {...} 之后结束（例如 namespace）
+func (p *Parser) skipStatement(start Position, body bool) {
+	defer p.skipFrom(start)
+	for {
+		tok := p.peek()
+		switch {
+		case tok.Kind == TokenEOF || tok.Kind == TokenPunctuation && tok.Text == "}":
+			return
+		case tok.Kind == TokenPunctuation && tok.Text == ";":
+			p.next()
+			return
+		case isOpenBracket(tok):
+			p.skipBalanced()
+			if body && tok.Text == "{" {
+				return
+			}
+		default:
+			p.next()
+		}
+		if p.newLine() {
+			return
+		}
+	}
+}
+
+// skipDecorators 跳过装饰器 @expr
+func (p *Parser) skipDecorators() {
+	for p.is(TokenPunctuation, "@") {
+		start := p.next().Pos
+		skipped := p.skipped
+		p.parseCallExpression()
+		p.skipped = skipped
+		p.skipFrom(start)
+	}
+}
+
+func (p *Parser) parseKeywordStatement() *ASTNode {
+	switch p.peek().Text {
+	case "return":
+		return p.parseReturnStatement()
+	case "if":
+		return p.parseIfStatement()
+	case "for":
+		return p.parseForStatement()
+	case "while":
+		return p.parseWhileStatement()
+	case "do":
+		return p.parseDoStatement()
+	case "switch":
+		return p.parseSwitchStatement()
+	case "try":
+		return p.parseTryStatement()
+	case "break":
+		return p.parseJumpStatement(BreakStatement)
+	case "continue":
+		return p.parseJumpStatement(ContinueStatement)
+	case "throw":
+		return p.parseThrowStatement()
+	}
+	return p.parseExpressionStatement()
+}
+
+func (p *Parser) parseFunctionDeclaration() *ASTNode {
+	start := p.next().Pos // function
+	p.accept(TokenOperator, "*")
+	nameTok, ok := p.expectIdentifier()
+	if !ok {
+		p.synchronize()
+		return nil
+	}
+	fn := newNode(FunctionDeclaration, nameTok.Text, start)
+	if p.is(TokenOperator, "<") {
+		p.skipTypeArguments()
+	}
+
+	if p.expect(TokenPunctuation, "(") {
+		for !p.is(TokenPunctuation, ")") && !p.atEOF() {
+			param, ok := p.parseParameter()
+			if !ok {
+				break
+			}
+			appendChild(fn, param)
+			if !p.accept(TokenPunctuation, ",") {
+				break
+			}
+		}
+		p.expect(TokenPunctuation, ")")
+	}
+
+	if p.accept(TokenPunctuation, ":") {
+		fn.TypeAnnotation = p.parseTypeAnnotation()
+	}
+
+	// 重载签名和 declare function 没有函数体
+	if p.is(TokenPunctuation, "{") {
+		appendChild(fn, p.parseBlock())
+	} else {
+		p.parseSemicolon()
+	}
+	fn.End = p.lastEnd()
+	return fn
+}
+
+// parseParameter 解析一个参数 name[?][: T][= expr] 或 ...name[: T]，默认值是 Parameter 的子节点。
+// 解构模式被跳过，不产生 Parameter 节点
+func (p *Parser) parseParameter() (*ASTNode, bool) {
+	p.accept(TokenOperator, "...")
+	var param *ASTNode
+	if tok := p.peek(); tok.Kind == TokenPunctuation && (tok.Text == "{" || tok.Text == "[") {
+		p.skipBalanced()
+		p.skipFrom(tok.Pos)
+	} else {
+		paramTok, ok := p.expectIdentifier()
+		if !ok {
+			return nil, false
+		}
+		param = newNode(Parameter, paramTok.Text, paramTok.Pos)
+	}
+	p.accept(TokenOperator, "?")
+	if p.accept(TokenPunctuation, ":") {
+		annotation := p.parseTypeAnnotation()
+		if param != nil {
+			param.TypeAnnotation = annotation
+		}
+	}
+	if p.accept(TokenOperator, "=") {
+		initializer := p.parseAssignmentExpression()
+		if param != nil {
+			appendChild(param, initializer)
+		}
+	}
+	if param != nil {
+		param.End = p.lastEnd()
+	}
+	return param, true
+}
+
+// parseTypeAnnotation 解析类型注解，返回类型的文本（词法单元之间有空白的地方保留一个空格）。
+// 检查器只认识类型名和数组后缀（例如 number、Foo[]），其他形式的类型按 any 处理
+func (p *Parser) parseTypeAnnotation() string {
+	start := p.current
+	if !p.parseType() {
+		return ""
+	}
+	if p.current-start == 1 {
+		return p.tokens[start].Text
+	}
+	var sb strings.Builder
+	for i := start; i < p.current; i++ {
+		tok := p.tokens[i]
+		if i > start && tok.Pos.Offset > p.tokens[i-1].End.Offset {
+			sb.WriteByte(' ')
+		}
+		if tok.Kind == TokenString {
+			sb.WriteString(`"` + tok.Text + `"`)
+		} else {
+			sb.WriteString(tok.Text)
+		}
+	}
+	return sb.String()
+}
+
+// parseType 解析一个类型：联合和交叉类型、条件类型 A extends B ? C : D 以及类型谓词 x is T
+func (p *Parser) parseType() bool {
+	if p.isWord("asserts") && p.peekAt(1).Kind == TokenIdentifier {
+		p.next()
+	}
+	if !p.accept(TokenOperator, "|") {
+		p.accept(TokenOperator, "&")
+	}
+	if !p.parseTypeOperand() {
+		return false
+	}
+	for p.is(TokenOperator, "|") || p.is(TokenOperator, "&") {
+		p.next()
+		if !p.parseTypeOperand() {
+			return false
+		}
+	}
+	switch {
+	case p.isWord("is"):
+		p.next()
+		return p.parseType()
+	case p.isWord("extends"):
+		p.next()
+		return p.parseType() && p.expect(TokenOperator, "?") && p.parseType() &&
+			p.expect(TokenPunctuation, ":") && p.parseType()
+	}
+	return true
+}
+
+// parseTypeOperand 解析联合类型的一个成员。对象类型、元组类型和函数类型的参数整体跳过，只保留文本
+func (p *Parser) parseTypeOperand() bool {
+	for p.isWord("keyof") || p.isWord("readonly") || p.isWord("unique") || p.isWord("infer") || p.isWord("abstract") {
+		if next := p.peekAt(1); next.Kind != TokenIdentifier && next.Kind != TokenKeyword && !isOpenBracket(next) {
+			break
+		}
+		p.next()
+	}
+
+	tok := p.peek()
+	switch {
+	case tok.Kind == TokenPunctuation && tok.Text == "(", tok.Kind == TokenOperator && tok.Text == "<",
+		tok.Kind == TokenKeyword && tok.Text == "new":
+		// 函数类型 (a: T) => R、构造函数类型 new (...) => R 或括号中的类型
+		p.accept(TokenKeyword, "new")
+		if p.is(TokenOperator, "<") {
+			p.skipTypeArguments()
+		}
+		if !p.is(TokenPunctuation, "(") {
+			p.errorAt(p.peek(), "应为 '('")
+			return false
+		}
+		p.skipBalanced()
+		if p.accept(TokenOperator, "=>") {
+			return p.parseType()
+		}
+	case tok.Kind == TokenPunctuation && (tok.Text == "{" || tok.Text == "["):
+		p.skipBalanced()
+	case tok.Kind == TokenString || tok.Kind == TokenNumber ||
+		tok.Kind == TokenKeyword && (tok.Text == "true" || tok.Text == "false"):
+		p.next()
+	case tok.Kind == TokenOperator && tok.Text == "-" && p.peekAt(1).Kind == TokenNumber:
+		p.next()
+		p.next()
+	case tok.Kind == TokenIdentifier || tok.Kind == TokenKeyword && tok.Text == "typeof":
+		if p.accept(TokenKeyword, "typeof") && p.peek().Kind != TokenIdentifier {
+			p.errorAt(p.peek(), "应为标识符")
+			return false
+		}
+		// 类型名、限定名 A.B、import("./m").T 以及类型实参
+		if p.next().Text == "import" && p.is(TokenPunctuation, "(") {
+			p.skipBalanced()
+		}
+		for p.accept(TokenPunctuation, ".") {
+			if kind := p.peek().Kind; kind != TokenIdentifier && kind != TokenKeyword {
+				p.errorAt(p.peek(), "应为标识符")
+				return false
+			}
+			p.next()
+		}
+		if p.is(TokenOperator, "<") && !p.newLine() {
+			p.skipTypeArguments()
+		}
+	default:
+		p.errorAt(tok, "应为类型")
+		return false
+	}
+
+	// 数组类型 T[] 和索引访问类型 T[K]
+	for p.is(TokenPunctuation, "[") && !p.newLine() {
+		p.skipBalanced()
+	}
+	return true
+}
+
+func (p *Parser) parseBlock() *ASTNode {
+	block := newNode(Block, "", p.peek().Pos)
+	if !p.expect(TokenPunctuation, "{") {
+		p.synchronize()
+		return block
+	}
+	p.parseStatements(block, func() bool { return p.is(TokenPunctuation, "}") })
+	p.expect(TokenPunctuation, "}")
+	block.End = p.lastEnd()
+	return block
+}
+
+// parseClassLikeDeclaration 解析 class、interface、enum 声明：只保留名称，
+// 类型参数、extends/implements 子句和花括号内的成员被跳过
+func (p *Parser) parseClassLikeDeclaration(kind NodeKind) *ASTNode {
+	keyword := p.next()
+	node := newNode(kind, "", keyword.Pos)
+	if p.peek().Kind == TokenIdentifier && !p.isWord("extends") && !p.isWord("implements") {
+		node.Name = p.next().Text
+	} else if kind != ClassDeclaration {
+		// 只有 class 可以匿名（export default class、class 表达式）
+		p.errorAt(p.peek(), "应为标识符")
+		p.synchronize()
+		return nil
+	}
+
+	start := p.peek().Pos
+	for !p.is(TokenPunctuation, "{") && !p.is(TokenPunctuation, ";") && !p.atEOF() {
+		switch {
+		case p.is(TokenOperator, "<"):
+			p.skipTypeArguments()
+		case isOpenBracket(p.peek()):
+			p.skipBalanced()
+		default:
+			p.next()
+		}
+	}
+	if p.is(TokenPunctuation, "{") {
+		p.skipBalanced()
+	} else {
+		p.expect(TokenPunctuation, "{")
+	}
+	p.skipFrom(start)
+	node.End = p.lastEnd()
+	return node
+}
+
+// parseTypeAliasDeclaration 解析 type Name<T> = Type，类型参数被跳过，类型保存在 TypeAnnotation 中
+func (p *Parser) parseTypeAliasDeclaration() *ASTNode {
+	keyword := p.next()
+	nameTok, _ := p.expectIdentifier()
+	node := newNode(TypeAliasDeclaration, nameTok.Text, keyword.Pos)
+	if p.is(TokenOperator, "<") {
+		p.skipTypeArguments()
+	}
+	if p.expect(TokenOperator, "=") {
+		node.TypeAnnotation = p.parseTypeAnnotation()
+	}
+	p.parseSemicolon()
+	node.End = p.lastEnd()
+	return node
+}
+
+// parseVariableStatement 解析 let/const/var 语句
+func (p *Parser) parseVariableStatement() *ASTNode {
+	stmt, ok := p.parseVariableList()
+	if !ok {
+		p.synchronize()
+		return nil
+	}
+	p.parseSemicolon()
+	if stmt != nil {
+		stmt.End = p.lastEnd()
+	}
+	return stmt
+}
+
+// parseVariableList 解析 let/const/var 和逗号分隔的声明（不包括分号）。只有一个声明时返回这个
+// VariableDeclaration，有多个时返回以它们为子节点的 VariableStatement
+func (p *Parser) parseVariableList() (*ASTNode, bool) {
+	keyword := p.next()
+	var decls []*ASTNode
+	pos := keyword.Pos
+	for {
+		decl, ok := p.parseVariableDeclaration(keyword, pos)
+		if !ok {
+			return nil, false
+		}
+		if decl != nil {
+			decls = append(decls, decl)
+		}
+		if !p.accept(TokenPunctuation, ",") {
+			break
+		}
+		pos = p.peek().Pos
+	}
+
+	switch len(decls) {
+	case 0:
+		return nil, true
+	case 1:
+		return decls[0], true
+	}
+	stmt := newNode(VariableStatement, "", keyword.Pos)
+	for _, decl := range decls {
+		appendChild(stmt, decl)
+	}
+	stmt.End = p.lastEnd()
+	return stmt, true
+}
+
+// parseVariableDeclaration 解析一个声明 name[: T] [= expr]。解构模式被跳过，
+// 它的初始化表达式作为 ExpressionStatement 保留
+func (p *Parser) parseVariableDeclaration(keyword Token, pos Position) (*ASTNode, bool) {
+	if tok := p.peek(); tok.Kind == TokenPunctuation && (tok.Text == "{" || tok.Text == "[") {
+		p.skipBalanced()
+		p.skipFrom(tok.Pos)
+		if p.accept(TokenPunctuation, ":") {
+			p.parseTypeAnnotation()
+		}
+		if !p.accept(TokenOperator, "=") {
+			return nil, true // for (const [k, v] of m)
+		}
+		stmt := newNode(ExpressionStatement, "", tok.Pos)
+		appendChild(stmt, p.parseAssignmentExpression())
+		stmt.End = p.lastEnd()
+		return stmt, true
+	}
+
+	nameTok, ok := p.expectIdentifier()
+	if !ok {
+		return nil, false
+	}
+	decl := newNode(VariableDeclaration, nameTok.Text, pos)
+	if keyword.Text == "const" {
+		decl.Flags |= NodeFlagsConst
+	} else {
+		decl.Flags |= NodeFlagsLet
+	}
+
+	p.accept(TokenOperator, "!") // 明确赋值断言 let x!: T
+	if p.accept(TokenPunctuation, ":") {
+		decl.TypeAnnotation = p.parseTypeAnnotation()
+	}
+	if p.accept(TokenOperator, "=") {
+		appendChild(decl, p.parseAssignmentExpression())
+	} else if keyword.Text == "const" && !p.ambient && !p.isWord("of") && !p.is(TokenKeyword, "in") {
+		p.errorAt(p.peek(), "const 声明必须初始化")
+	}
+	decl.End = p.lastEnd()
+	return decl, true
+}
+
+func (p *Parser) parseReturnStatement() *ASTNode {
+	stmt := newNode(ReturnStatement, "", p.next().Pos)
+	if !p.is(TokenPunctuation, ";") && !p.is(TokenPunctuation, "}") && !p.atEOF() && !p.newLine() {
+		appendChild(stmt, p.parseSequenceExpression())
+	}
+	p.parseSemicolon()
+	stmt.End = p.lastEnd()
+	return stmt
+}
+
+func (p *Parser) parseThrowStatement() *ASTNode {
+	stmt := newNode(ThrowStatement, "", p.next().Pos)
+	appendChild(stmt, p.parseSequenceExpression())
+	p.parseSemicolon()
+	stmt.End = p.lastEnd()
+	return stmt
+}
+
+// parseJumpStatement 解析 break 和 continue，Name 为可选的标签
+func (p *Parser) parseJumpStatement(kind NodeKind) *ASTNode {
+	stmt := newNode(kind, "", p.next().Pos)
+	if p.peek().Kind == TokenIdentifier && !p.newLine() {
+		stmt.Name = p.next().Text
+	}
+	p.parseSemicolon()
+	stmt.End = p.lastEnd()
+	return stmt
+}
+
+// parseCondition 解析 if、while、switch 之后括号中的表达式
+func (p *Parser) parseCondition() *ASTNode {
+	if !p.expect(TokenPunctuation, "(") {
+		return nil
+	}
+	expr := p.parseSequenceExpression()
+	p.expect(TokenPunctuation, ")")
+	return expr
+}
+
+func (p *Parser) parseIfStatement() *ASTNode {
+	stmt := newNode(IfStatement, "", p.next().Pos)
+	appendChild(stmt, p.parseCondition())
+	appendChild(stmt, p.parseStatement())
+	if p.accept(TokenKeyword, "else") {
+		appendChild(stmt, p.parseStatement())
+	}
+	stmt.End = p.lastEnd()
+	return stmt
+}
+
+func (p *Parser) parseWhileStatement() *ASTNode {
+	stmt := newNode(WhileStatement, "", p.next().Pos)
+	appendChild(stmt, p.parseCondition())
+	appendChild(stmt, p.parseStatement())
+	stmt.End = p.lastEnd()
+	return stmt
+}
+
+func (p *Parser) parseDoStatement() *ASTNode {
+	stmt := newNode(WhileStatement, "do", p.next().Pos)
+	appendChild(stmt, p.parseStatement())
+	if p.expect(TokenKeyword, "while") {
+		appendChild(stmt, p.parseCondition())
+	}
+	p.accept(TokenPunctuation, ";")
+	stmt.End = p.lastEnd()
+	return stmt
+}
+
+func (p *Parser) parseForStatement() *ASTNode {
+	stmt := newNode(ForStatement, "", p.next().Pos)
+	p.accept(TokenIdentifier, "await") // for await (... of ...)
+	if !p.expect(TokenPunctuation, "(") {
+		p.synchronize()
+		return nil
+	}
+
+	var initializer *ASTNode
+	p.noIn = true
+	switch {
+	case p.is(TokenKeyword, "let") || p.is(TokenKeyword, "const") || p.isWord("var"):
+		var ok bool
+		if initializer, ok = p.parseVariableList(); !ok {
+			p.noIn = false
+			p.synchronize()
+			return nil
+		}
+	case !p.is(TokenPunctuation, ";"):
+		initializer = p.parseSequenceExpression()
+	}
+	p.noIn = false
+	appendChild(stmt, initializer)
+
+	if p.isWord("of") || p.is(TokenKeyword, "in") {
+		stmt.Name = p.next().Text
+		appendChild(stmt, p.parseAssignmentExpression())
+	} else {
+		p.expect(TokenPunctuation, ";")
+		if !p.is(TokenPunctuation, ";") {
+			appendChild(stmt, p.parseSequenceExpression())
+		}
+		p.expect(TokenPunctuation, ";")
+		if !p.is(TokenPunctuation, ")") {
+			appendChild(stmt, p.parseSequenceExpression())
+		}
+	}
+	p.expect(TokenPunctuation, ")")
+	appendChild(stmt, p.parseStatement())
+	stmt.End = p.lastEnd()
+	return stmt
+}
+
+func (p *Parser) parseSwitchStatement() *ASTNode {
+	stmt := newNode(SwitchStatement, "", p.next().Pos)
+	appendChild(stmt, p.parseCondition())
+	if !p.expect(TokenPunctuation, "{") {
+		p.synchronize()
+		return stmt
+	}
+	isClauseEnd := func() bool {
+		return p.is(TokenPunctuation, "}") || p.is(TokenKeyword, "case") ||
+			p.isWord("default") && p.peekAt(1).Kind == TokenPunctuation && p.peekAt(1).Text == ":"
+	}
+	for !p.is(TokenPunctuation, "}") && !p.atEOF() {
+		clause := newNode(CaseClause, "", p.peek().Pos)
+		switch {
+		case p.accept(TokenKeyword, "case"):
+			clause.Name = "case"
+			appendChild(clause, p.parseSequenceExpression())
+		case p.isWord("default"):
+			p.next()
+			clause.Name = "default"
+		default:
+			p.errorAt(p.peek(), "应为 'case' 或 'default'")
+			p.synchronize()
+			if !isClauseEnd() {
+				p.next()
+			}
+			continue
+		}
+		p.expect(TokenPunctuation, ":")
+		p.parseStatements(clause, isClauseEnd)
+		clause.End = p.lastEnd()
+		appendChild(stmt, clause)
+	}
+	p.expect(TokenPunctuation, "}")
+	stmt.End = p.lastEnd()
+	return stmt
+}
+
+func (p *Parser) parseTryStatement() *ASTNode {
+	stmt := newNode(TryStatement, "", p.next().Pos)
+	appendChild(stmt, p.parseBlock())
+	if p.is(TokenKeyword, "catch") {
+		clause := newNode(CatchClause, "", p.next().Pos)
+		if p.accept(TokenPunctuation, "(") {
+			if tok := p.peek(); tok.Kind == TokenIdentifier {
+				clause.Name = p.next().Text
+			} else if isOpenBracket(tok) {
+				p.skipBalanced()
+				p.skipFrom(tok.Pos)
+			}
+			if p.accept(TokenPunctuation, ":") {
+				clause.TypeAnnotation = p.parseTypeAnnotation()
+			}
+			p.expect(TokenPunctuation, ")")
+		}
+		appendChild(clause, p.parseBlock())
+		clause.End = p.lastEnd()
+		appendChild(stmt, clause)
+	}
+	if p.accept(TokenKeyword, "finally") {
+		appendChild(stmt, p.parseBlock())
+	}
+	stmt.End = p.lastEnd()
+	return stmt
+}
+
+func (p *Parser) parseExpressionStatement() *ASTNode {
+	stmt := newNode(ExpressionStatement, "", p.peek().Pos)
+	expr := p.parseSequenceExpression()
+	if expr == nil {
+		p.synchronize()
+		return nil
+	}
+	appendChild(stmt, expr)
+	p.parseSemicolon()
+	stmt.End = p.lastEnd()
+	return stmt
+}
+
+// 分号可省略：在 }、文件结尾之前或下一个词法单元在新的一行时自动插入
+func (p *Parser) parseSemicolon() {
+	if p.accept(TokenPunctuation, ";") || p.is(TokenPunctuation, "}") || p.atEOF() || p.newLine() {
+		return
+	}
+	p.errorAt(p.peek(), "应为 ';'")
+	p.synchronize()
+}
+
+// parseSequenceExpression 解析逗号分隔的表达式 a, b（BinaryExpression，Name 为 ,）
+func (p *Parser) parseSequenceExpression() *ASTNode {
+	left := p.parseAssignmentExpression()
+	for left != nil && p.accept(TokenPunctuation, ",") {
+		right := p.parseAssignmentExpression()
+		if right == nil {
+			return left
+		}
+		binary := newNode(BinaryExpression, ",", left.Pos)
+		appendChild(binary, left)
+		appendChild(binary, right)
+		binary.End = right.End
+		left = binary
+	}
+	return left
+}
+
+// parseAssignmentExpression 解析箭头函数、条件表达式和赋值（右结合，BinaryExpression 的 Name 为赋值运算符），
+// 其余交给 parseExpression
+func (p *Parser) parseAssignmentExpression() *ASTNode {
+	if p.isArrowFunction() {
+		return p.parseFunctionExpression()
+	}
+	expr := p.parseExpression(0)
+	if expr == nil {
+		return nil
+	}
+
+	if p.accept(TokenOperator, "?") {
+		cond := newNode(ConditionalExpression, "", expr.Pos)
+		appendChild(cond, expr)
+		appendChild(cond, p.parseAssignmentExpression())
+		p.expect(TokenPunctuation, ":")
+		appendChild(cond, p.parseAssignmentExpression())
+		cond.End = p.lastEnd()
+		return cond
+	}
+
+	op, n := p.peekOperator()
+	if !assignmentOperators[op] {
+		return expr
+	}
+	for i := 0; i < n; i++ {
+		p.next()
+	}
+	right := p.parseAssignmentExpression()
+	if right == nil {
+		return expr
+	}
+	binary := newNode(BinaryExpression, op, expr.Pos)
+	appendChild(binary, expr)
+	appendChild(binary, right)
+	binary.End = right.End
+	return binary
+}
+
+// peekOperator 返回当前位置的运算符和它占用的词法单元数。词法分析器不识别右移运算符，
+// 相邻（中间没有空白）的 > 和 >= 在这里合并为 >> >>> >>= >>>=
+func (p *Parser) peekOperator() (string, int) {
+	tok := p.peek()
+	switch {
+	case tok.Kind == TokenKeyword && (tok.Text == "instanceof" || tok.Text == "in" && !p.noIn):
+		return tok.Text, 1
+	case tok.Kind != TokenOperator:
+		return "", 0
+	case tok.Text != ">":
+		return tok.Text, 1
+	}
+	op, n := ">", 1
+	for n < 3 {
+		next := p.peekAt(n)
+		if next.Kind != TokenOperator || (next.Text != ">" && next.Text != ">=") ||
+			next.Pos.Offset != p.peekAt(n-1).End.Offset {
+			break
+		}
+		op += next.Text
+		n++
+		if next.Text == ">=" {
+			break
+		}
+	}
+	return op, n
+}
+
+// parseExpression 使用优先级爬升法解析二元表达式
+func (p *Parser) parseExpression(minPrecedence int) *ASTNode {
+	left := p.parseUnaryExpression()
+	if left == nil {
+		return nil
+	}
+	for {
+		if p.isWord("as") || p.isWord("satisfies") {
+			// 类型断言不改变表达式，类型被丢弃
+			p.next()
+			if !p.accept(TokenKeyword, "const") {
+				p.parseType()
+			}
+			continue
+		}
+		op, n := p.peekOperator()
+		precedence, ok := binaryPrecedence[op]
+		if !ok || precedence <= minPrecedence {
+			return left
+		}
+		for i := 0; i < n; i++ {
+			p.next()
+		}
+		if op == "**" {
+			precedence-- // 右结合
+		}
+		right := p.parseExpression(precedence)
+		if right == nil {
+			return left
+		}
+		binary := newNode(BinaryExpression, op, left.Pos)
+		appendChild(binary, left)
+		appendChild(binary, right)
+		binary.End = right.End
+		left = binary
+	}
+}
+
+// parseUnaryExpression 解析前缀运算（! - + ~ ++ -- ... typeof delete void await yield）、
+// 类型断言 <T>expr 和后缀 ++ --
+func (p *Parser) parseUnaryExpression() *ASTNode {
+	tok := p.peek()
+	if p.isPrefixOperator(tok) {
+		p.next()
+		operand := p.parseUnaryExpression()
+		if operand == nil {
+			return nil
+		}
+		unary := newNode(UnaryExpression, tok.Text, tok.Pos)
+		appendChild(unary, operand)
+		unary.End = operand.End
+		return unary
+	}
+	if tok.Kind == TokenOperator && tok.Text == "<" {
+		p.skipTypeArguments()
+		return p.parseUnaryExpression()
+	}
+
+	expr := p.parseCallExpression()
+	if expr != nil && !p.newLine() && (p.is(TokenOperator, "++") || p.is(TokenOperator, "--")) {
+		op := p.next()
+		unary := newNode(UnaryExpression, "x"+op.Text, expr.Pos)
+		appendChild(unary, expr)
+		unary.End = op.End
+		return unary
+	}
+	return expr
+}
+
+func (p *Parser) isPrefixOperator(tok Token) bool {
+	switch tok.Kind {
+	case TokenOperator:
+		return prefixOperators[tok.Text]
+	case TokenKeyword:
+		return tok.Text == "typeof" || tok.Text == "delete"
+	case TokenIdentifier:
+		// void、await、yield 后面紧跟操作数时才是运算符
+		if tok.Text != "void" && tok.Text != "await" && tok.Text != "yield" {
+			return false
+		}
+		next := p.peekAt(1)
+		if next.Pos.Line != tok.End.Line {
+			return false
+		}
+		switch next.Kind {
+		case TokenIdentifier, TokenNumber, TokenString, TokenRegExp:
+			return true
+		case TokenKeyword:
+			return next.Text != "in" && next.Text != "instanceof"
+		case TokenPunctuation:
+			return next.Text == "(" || next.Text == "[" || next.Text == "{"
+		case TokenOperator:
+			return prefixOperators[next.Text]
+		}
+	}
+	return false
+}
+
+// parseCallExpression 解析主表达式之后的调用、属性访问（a.b、a?.b、a[i]）、非空断言 a! 和类型实参 f<T>()
+func (p *Parser) parseCallExpression() *ASTNode {
+	expr := p.parsePrimaryExpression()
+	for expr != nil {
+		tok := p.peek()
+		switch {
+		case tok.Kind == TokenPunctuation && tok.Text == ".":
+			p.next()
+			access := p.parsePropertyAccess(expr)
+			if access == nil {
+				return expr
+			}
+			expr = access
+		case tok.Kind == TokenOperator && tok.Text == "?.":
+			p.next()
+			if !p.is(TokenPunctuation, "(") && !p.is(TokenPunctuation, "[") {
+				access := p.parsePropertyAccess(expr)
+				if access == nil {
+					return expr
+				}
+				expr = access
+			}
+		case tok.Kind == TokenPunctuation && tok.Text == "[":
+			p.next()
+			access := newNode(ElementAccessExpression, "", expr.Pos)
+			appendChild(access, expr)
+			appendChild(access, p.parseSequenceExpression())
+			p.expect(TokenPunctuation, "]")
+			access.End = p.lastEnd()
+			expr = access
+		case tok.Kind == TokenPunctuation && tok.Text == "(":
+			p.next()
+			call := newNode(CallExpression, "", expr.Pos)
+			if expr.Kind == Identifier {
+				call.Name = expr.Name
+			}
+			appendChild(call, expr)
+			p.parseArguments(call)
+			call.End = p.lastEnd()
+			expr = call
+		case tok.Kind == TokenOperator && tok.Text == "!" && tok.Pos.Offset == p.lastEnd().Offset:
+			p.next() // 非空断言
+		case tok.Kind == TokenOperator && tok.Text == "<" && p.isTypeArgumentsCall():
+			p.skipTypeArguments()
+		case tok.Kind == TokenString && tok.Pos.Offset == p.lastEnd().Offset:
+			p.next() // 带标签的模板字符串 tag`...`
+		default:
+			return expr
+		}
+	}
+	return expr
+}
+
+// parsePropertyAccess 解析 . 或 ?. 之后的属性名（可以是关键字），返回 expr.name
+func (p *Parser) parsePropertyAccess(expr *ASTNode) *ASTNode {
+	name := p.peek()
+	if name.Kind != TokenIdentifier && name.Kind != TokenKeyword {
+		p.errorAt(name, "应为属性名")
+		return nil
+	}
+	p.next()
+	access := newNode(PropertyAccessExpression, name.Text, expr.Pos)
+	appendChild(access, expr)
+	access.End = name.End
+	return access
+}
+
+// parseArguments 解析 ( 之后直到 ) 的实参列表，实参加入 node 的子节点
+func (p *Parser) parseArguments(node *ASTNode) {
+	for !p.is(TokenPunctuation, ")") && !p.atEOF() {
+		arg := p.parseAssignmentExpression()
+		if arg == nil {
+			break
+		}
+		appendChild(node, arg)
+		if !p.accept(TokenPunctuation, ",") {
+			break
+		}
+	}
+	p.expect(TokenPunctuation, ")")
+}
+
+// isTypeArgumentsCall 向前看判断当前的 < 是否开始调用的类型实参 f<T>(x)，而不是小于号
+func (p *Parser) isTypeArgumentsCall() bool {
+	depth := 0
+	for i := p.current; i < len(p.tokens) && i < p.current+64; i++ {
+		tok := p.tokens[i]
+		switch {
+		case tok.Kind == TokenOperator && tok.Text == "<":
+			depth++
+		case tok.Kind == TokenOperator && tok.Text == ">":
+			if depth--; depth == 0 {
+				next := p.tokens[i+1]
+				return next.Kind == TokenPunctuation && next.Text == "("
+			}
+		case tok.Kind == TokenIdentifier || tok.Kind == TokenString || tok.Kind == TokenNumber,
+			tok.Kind == TokenKeyword && (tok.Text == "typeof" || tok.Text == "true" || tok.Text == "false"),
+			tok.Kind == TokenPunctuation && tok.Text != ";",
+			tok.Kind == TokenOperator && (tok.Text == "|" || tok.Text == "&" || tok.Text == "=>" || tok.Text == "?" || tok.Text == "..."):
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// isArrowFunction 向前看判断当前位置是否是箭头函数 x => ...、(...) => ... 或 (...): T => ...，
+// 可以有 async 前缀和类型参数
+func (p *Parser) isArrowFunction() bool {
+	i := p.current
+	if p.isWord("async") && p.peekAt(1).Pos.Line == p.peek().End.Line {
+		i++
+	}
+	tok := p.tokens[i]
+	switch {
+	case tok.Kind == TokenIdentifier:
+		next := p.tokens[i+1]
+		return next.Kind == TokenOperator && next.Text == "=>"
+	case tok.Kind == TokenOperator && tok.Text == "<":
+		if i = p.matchingIndex(i); i < 0 {
+			return false
+		}
+		i++
+	}
+	if tok = p.tokens[i]; tok.Kind != TokenPunctuation || tok.Text != "(" {
+		return false
+	}
+	j := p.matchingIndex(i)
+	if j < 0 {
+		return false
+	}
+	next := p.tokens[j+1]
+	if next.Kind == TokenOperator && next.Text == "=>" {
+		return true
+	}
+	if next.Kind != TokenPunctuation || next.Text != ":" {
+		return false
+	}
+	// 返回类型注解之后应为 =>
+	depth := 0
+	for k := j + 2; k < len(p.tokens); k++ {
+		tok := p.tokens[k]
+		switch {
+		case tok.Kind == TokenEOF:
+			return false
+		case isOpenBracket(tok), tok.Kind == TokenOperator && tok.Text == "<":
+			depth++
+		case isCloseBracket(tok), tok.Kind == TokenOperator && tok.Text == ">":
+			if depth == 0 {
+				return false
+			}
+			depth--
+		case depth == 0 && tok.Kind == TokenOperator && tok.Text == "=>":
+			return true
+		case depth == 0 && tok.Kind == TokenPunctuation && (tok.Text == ";" || tok.Text == ","),
+			depth == 0 && tok.Kind == TokenOperator && assignmentOperators[tok.Text]:
+			return false
+		}
+	}
+	return false
+}
+
+// parseFunctionExpression 解析函数表达式或箭头函数，只产生一个 FunctionExpression 节点：
+// 参数和函数体被跳过（箭头函数的表达式体按表达式解析，但不进入 AST）
+func (p *Parser) parseFunctionExpression() *ASTNode {
+	start := p.peek().Pos
+	skipped := p.skipped
+	node := newNode(FunctionExpression, "", start)
+	p.accept(TokenIdentifier, "async")
+	if p.accept(TokenKeyword, "function") {
+		p.accept(TokenOperator, "*")
+		if p.peek().Kind == TokenIdentifier {
+			node.Name = p.next().Text
+		}
+		if p.is(TokenOperator, "<") {
+			p.skipTypeArguments()
+		}
+		if p.is(TokenPunctuation, "(") {
+			p.skipBalanced()
+		} else {
+			p.expect(TokenPunctuation, "(")
+		}
+		if p.accept(TokenPunctuation, ":") {
+			p.parseType()
+		}
+		if p.is(TokenPunctuation, "{") {
+			p.skipBalanced()
+		} else {
+			p.expect(TokenPunctuation, "{")
+		}
+	} else {
+		if p.is(TokenOperator, "<") {
+			p.skipTypeArguments()
+		}
+		if p.is(TokenPunctuation, "(") {
+			p.skipBalanced()
+		} else {
+			p.next() // 单个参数 x => ...
+		}
+		if p.accept(TokenPunctuation, ":") {
+			p.parseType()
+		}
+		p.expect(TokenOperator, "=>")
+		if p.is(TokenPunctuation, "{") {
+			p.skipBalanced()
+		} else {
+			p.parseAssignmentExpression()
+		}
+	}
+	node.End = p.lastEnd()
+	// 函数体内嵌套的跳过不重复计算
+	p.skipped = skipped + node.End.Offset - start.Offset
+	return node
+}
+
+// parseNewExpression 解析 new C<T>(args)，省略实参列表时没有实参
+func (p *Parser) parseNewExpression() *ASTNode {
+	node := newNode(NewExpression, "", p.next().Pos)
+	callee := p.parsePrimaryExpression()
+	if callee == nil {
+		return nil
+	}
+	// 构造函数表达式只包括属性访问，之后的调用属于外层：new a.B().c()
+	for p.accept(TokenPunctuation, ".") {
+		access := p.parsePropertyAccess(callee)
+		if access == nil {
+			break
+		}
+		callee = access
+	}
+	if callee.Kind == Identifier {
+		node.Name = callee.Name
+	}
+	appendChild(node, callee)
+	if p.is(TokenOperator, "<") {
+		p.skipTypeArguments()
+	}
+	if p.accept(TokenPunctuation, "(") {
+		p.parseArguments(node)
+	}
+	node.End = p.lastEnd()
+	return node
+}
+
+func (p *Parser) parseArrayLiteral() *ASTNode {
+	node := newNode(ArrayLiteralExpression, "", p.next().Pos)
+	for !p.is(TokenPunctuation, "]") && !p.atEOF() {
+		if p.accept(TokenPunctuation, ",") {
+			continue // 空位 [a, , b]
+		}
+		element := p.parseAssignmentExpression()
+		if element == nil {
+			break
+		}
+		appendChild(node, element)
+		if !p.accept(TokenPunctuation, ",") {
+			break
+		}
+	}
+	p.expect(TokenPunctuation, "]")
+	node.End = p.lastEnd()
+	return node
+}
+
+// parseObjectLiteral 解析对象字面量：属性值和展开的表达式作为子节点，属性名不保留
+func (p *Parser) parseObjectLiteral() *ASTNode {
+	node := newNode(ObjectLiteralExpression, "", p.next().Pos)
+	for !p.is(TokenPunctuation, "}") && !p.atEOF() {
+		if p.is(TokenOperator, "...") {
+			appendChild(node, p.parseUnaryExpression())
+		} else if !p.parseObjectProperty(node) {
+			break
+		}
+		if !p.accept(TokenPunctuation, ",") {
+			break
+		}
+	}
+	p.expect(TokenPunctuation, "}")
+	node.End = p.lastEnd()
+	return node
+}
+
+// parseObjectProperty 解析属性 name: value、简写属性 name 或方法，属性值加入 node 的子节点。
+// 方法和 get/set 访问器整体跳过，计算属性名 [expr] 也被跳过
+func (p *Parser) parseObjectProperty(node *ASTNode) bool {
+	start := p.peek().Pos
+	for p.isWord("get") || p.isWord("set") || p.isWord("async") || p.is(TokenOperator, "*") {
+		next := p.peekAt(1)
+		if next.Kind != TokenIdentifier && next.Kind != TokenKeyword && next.Kind != TokenString &&
+			next.Kind != TokenNumber && !(next.Kind == TokenPunctuation && next.Text == "[") {
+			break
+		}
+		p.next()
+	}
+
+	name := p.peek()
+	switch {
+	case name.Kind == TokenPunctuation && name.Text == "[":
+		p.skipBalanced()
+	case name.Kind == TokenIdentifier || name.Kind == TokenKeyword || name.Kind == TokenString || name.Kind == TokenNumber:
+		p.next()
+	default:
+		p.errorAt(name, "应为属性名")
+		return false
+	}
+
+	switch {
+	case p.accept(TokenPunctuation, ":"):
+		if name.Text == "[" {
+			p.skipFrom(start)
+		}
+		appendChild(node, p.parseAssignmentExpression())
+	case p.is(TokenPunctuation, "(") || p.is(TokenOperator, "<"):
+		if p.is(TokenOperator, "<") {
+			p.skipTypeArguments()
+		}
+		p.skipBalanced()
+		if p.accept(TokenPunctuation, ":") {
+			p.parseType()
+		}
+		if p.is(TokenPunctuation, "{") {
+			p.skipBalanced()
+		} else {
+			p.expect(TokenPunctuation, "{")
+		}
+		p.skipFrom(start)
+	case name.Kind == TokenIdentifier:
+		// 简写属性 { a }，解构赋值中还可以有默认值 { a = 1 }
+		ident := newNode(Identifier, name.Text, name.Pos)
+		ident.End = name.End
+		appendChild(node, ident)
+		if p.accept(TokenOperator, "=") {
+			appendChild(node, p.parseAssignmentExpression())
+		}
+	default:
+		p.errorAt(p.peek(), "应为 ':'")
+		return false
+	}
+	return true
+}
+
+func (p *Parser) parsePrimaryExpression() *ASTNode {
+	tok := p.peek()
+	var node *ASTNode
+	switch {
+	case tok.Kind == TokenIdentifier:
+		next := p.peekAt(1)
+		switch {
+		case tok.Text == "async" && next.Kind == TokenKeyword && next.Text == "function" && next.Pos.Line == tok.End.Line:
+			return p.parseFunctionExpression()
+		case tok.Text == "class" && (next.Kind == TokenIdentifier || next.Kind == TokenPunctuation && next.Text == "{"):
+			return p.parseClassLikeDeclaration(ClassDeclaration)
+		}
+		node = newNode(Identifier, tok.Text, tok.Pos)
+	case tok.Kind == TokenNumber:
+		node = newNode(NumericLiteral, tok.Text, tok.Pos)
+	case tok.Kind == TokenString:
+		node = newNode(StringLiteral, tok.Text, tok.Pos)
+	case tok.Kind == TokenRegExp:
+		node = newNode(RegularExpressionLiteral, tok.Text, tok.Pos)
+	case tok.Kind == TokenKeyword && (tok.Text == "true" || tok.Text == "false"):
+		node = newNode(BooleanLiteral, tok.Text, tok.Pos)
+	case tok.Kind == TokenKeyword && tok.Text == "function":
+		return p.parseFunctionExpression()
+	case tok.Kind == TokenKeyword && tok.Text == "new":
+		return p.parseNewExpression()
+	case tok.Kind == TokenPunctuation && tok.Text == "(":
+		p.next()
+		noIn := p.noIn
+		p.noIn = false
+		expr := p.parseSequenceExpression()
+		p.noIn = noIn
+		p.expect(TokenPunctuation, ")")
+		return expr
+	case tok.Kind == TokenPunctuation && tok.Text == "[":
+		return p.parseArrayLiteral()
+	case tok.Kind == TokenPunctuation && tok.Text == "{":
+		return p.parseObjectLiteral()
+	default:
+		p.errorAt(tok, "应为表达式")
+		return nil
+	}
+	p.next()
+	node.End = tok.End
+	return node
+}
+
+// 生成测试用的 TypeScript 源码：若干函数声明，每个函数体内有变量声明、
+// 二元运算以及对之前声明函数的调用
+func generateSource(functionCount, statementsPerFunction int, rng func(int) int) string {
+	var sb strings.Builder
+	for i := 0; i < functionCount; i++ {
+		fmt.Fprintf(&sb, "export function fn_%d(a: number, b: number): number {\n", i)
+		for j := 0; j < statementsPerFunction; j++ {
+			switch rng(4) {
+			case 0:
+				fmt.Fprintf(&sb, "  const v_%d = a * %d + b;\n", j, rng(100))
+			case 1:
+				fmt.Fprintf(&sb, "  let s_%d: string = \"item_%d\";\n", j, rng(1000))
+			case 2:
+				if i > 0 {
+					fmt.Fprintf(&sb, "  const c_%d = fn_%d(a + %d, b - 1);\n", j, rng(i), j)
+				} else {
+					fmt.Fprintf(&sb, "  const c_%d = a + b;\n", j)
+				}
+			default:
+				fmt.Fprintf(&sb, "  let f_%d = (a + b) * (a - %d) > %d && true;\n", j, j, rng(50))
+			}
+		}
+		sb.WriteString("  return a + b;\n}\n\n")
+	}
+	return sb.String()
+}
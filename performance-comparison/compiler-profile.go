@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"slices"
+	"strings"
+)
+
+// 按测试阶段采集 pprof 性能剖析（-profile 参数）。
+// 每个阶段（即一项测试，例如 large-scale-concurrent）只剖析计入统计的采样，不含预热，
+// 输出文件以阶段名命名：<目录>/<阶段>[-<文件数>][-<参数>...].<类型>.pprof，参数见 profileNameParams。
+// 类型 trace 采集执行跟踪（<阶段>[-<文件数>][-<参数>...].trace），用 go tool trace 查看
+
+// profileKinds 支持的剖析类型，顺序即默认的输出顺序
+var profileKinds = []string{"cpu", "heap", "allocs", "mutex", "block"}
+
+// traceKind 执行跟踪。开销较大，不在默认的剖析类型中
+const traceKind = "trace"
+
+const (
+	profileMutexFraction = 1 // 剖析期间记录每一次互斥锁竞争
+	profileBlockRate     = 1 // 剖析期间记录每一次阻塞（纳秒阈值）
+)
+
+// profileNameParams 同一阶段在一次运行中可能以不同的取值出现的参数，按顺序附加到剖析文件名中，
+// 例如 -pool-bench 的 pool-concurrent 依次使用三种并发策略
+var profileNameParams = []string{"pool", "symbolTable", "backend", "goroutines", "layout", "interned"}
+
+// Profiler 决定哪些阶段需要剖析，以及采集哪些类型
+type Profiler struct {
+	Dir     string
+	phases  []string
+	kinds   map[string]bool
+	written int             // 已剖析的阶段数
+	bases   map[string]bool // 已使用的文件名（不含类型后缀），避免覆盖
+}
+
+// NewProfiler 创建剖析器。phases 和 kinds 均为逗号分隔的列表，
+// phases 为空时返回 nil（不剖析），"all" 表示所有阶段
+func NewProfiler(dir, phases, kinds string) (*Profiler, error) {
+	if phases == "" {
+		return nil, nil
+	}
+	p := &Profiler{Dir: dir, kinds: make(map[string]bool), bases: make(map[string]bool)}
+	for _, phase := range strings.Split(phases, ",") {
+		if phase = strings.TrimSpace(phase); phase != "" {
+			p.phases = append(p.phases, phase)
+		}
+	}
+	for _, kind := range strings.Split(kinds, ",") {
+		kind = strings.TrimSpace(kind)
+		if kind != traceKind && !slices.Contains(profileKinds, kind) {
+			return nil, fmt.Errorf("未知的剖析类型 %q，可选: %s, %s", kind, strings.Join(profileKinds, ", "), traceKind)
+		}
+		p.kinds[kind] = true
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// matches 阶段名与列表中的某一项相同，或以 "-<该项>" 结尾时剖析，
+// 例如 "concurrent" 匹配 large-scale-concurrent 和 batch-concurrent
+func (p *Profiler) matches(name string) bool {
+	for _, phase := range p.phases {
+		if phase == "all" || name == phase || strings.HasSuffix(name, "-"+phase) {
+			return true
+		}
+	}
+	return false
+}
+
+// Written 返回已剖析的阶段数，用于提示 -profile 没有匹配任何阶段
+func (p *Profiler) Written() int {
+	if p == nil {
+		return 0
+	}
+	return p.written
+}
+
+// profileSession 一个阶段正在进行的剖析
+type profileSession struct {
+	profiler  *Profiler
+	base      string
+	cpuFile   *os.File
+	traceFile *os.File
+}
+
+// begin 开始剖析阶段 name。p 为 nil 或阶段不匹配时返回 nil，对 nil 调用 end 不做任何事。
+// params 中有 files 时文件名附带文件数，区分不同规模的同一阶段；再依次附带 profileNameParams 中的参数
+// （布尔参数为 true 时附带参数名）。文件名仍与本次运行中之前的阶段相同时加上序号，不覆盖之前的文件
+func (p *Profiler) begin(name string, params map[string]interface{}) *profileSession {
+	if p == nil || !p.matches(name) {
+		return nil
+	}
+	base := name
+	if files, ok := params["files"]; ok {
+		base = fmt.Sprintf("%s-%v", name, files)
+	}
+	for _, key := range profileNameParams {
+		switch value := params[key].(type) {
+		case nil:
+		case bool:
+			if value {
+				base += "-" + key
+			}
+		default:
+			base += fmt.Sprintf("-%v", value)
+		}
+	}
+	for i, unique := 2, base; ; i++ {
+		if !p.bases[unique] {
+			base = unique
+			break
+		}
+		unique = fmt.Sprintf("%s-%d", base, i)
+	}
+	p.bases[base] = true
+	s := &profileSession{profiler: p, base: filepath.Join(p.Dir, base)}
+
+	if p.kinds["mutex"] {
+		runtime.SetMutexProfileFraction(profileMutexFraction)
+	}
+	if p.kinds["block"] {
+		runtime.SetBlockProfileRate(profileBlockRate)
+	}
+	if p.kinds["cpu"] {
+		f, err := os.Create(s.base + ".cpu.pprof")
+		if err == nil {
+			if err = pprof.StartCPUProfile(f); err != nil {
+				f.Close()
+			} else {
+				s.cpuFile = f
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "开始 CPU 剖析失败:", err)
+		}
+	}
+	if p.kinds[traceKind] {
+		f, err := os.Create(s.base + ".trace")
+		if err == nil {
+			if err = trace.Start(f); err != nil {
+				f.Close()
+			} else {
+				s.traceFile = f
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "开始执行跟踪失败:", err)
+		}
+	}
+	return s
+}
+
+// end 停止剖析并写出其余类型的剖析文件
+func (s *profileSession) end() {
+	if s == nil {
+		return
+	}
+	if s.traceFile != nil {
+		trace.Stop()
+		s.traceFile.Close()
+		fmt.Printf("  执行跟踪: %s.trace\n", s.base)
+	}
+	var written []string
+	if s.cpuFile != nil {
+		pprof.StopCPUProfile()
+		s.cpuFile.Close()
+		written = append(written, "cpu")
+	}
+	// mutex/block 只在剖析期间开启。它们和 allocs 一样是累计值：剖析多个阶段时，
+	// 后面阶段的文件也包含前面阶段的事件，可以用 go tool pprof -base 相减
+	runtime.SetMutexProfileFraction(0)
+	runtime.SetBlockProfileRate(0)
+
+	for _, kind := range profileKinds[1:] {
+		if !s.profiler.kinds[kind] {
+			continue
+		}
+		if kind == "heap" {
+			runtime.GC() // 使堆剖析反映阶段结束时的存活对象
+		}
+		if err := writeProfile(kind, s.base+"."+kind+".pprof"); err != nil {
+			fmt.Fprintf(os.Stderr, "写入 %s 剖析失败: %v\n", kind, err)
+			continue
+		}
+		written = append(written, kind)
+	}
+	s.profiler.written++
+	switch len(written) {
+	case 0:
+	case 1:
+		fmt.Printf("  性能剖析: %s.%s.pprof\n", s.base, written[0])
+	default:
+		fmt.Printf("  性能剖析: %s.{%s}.pprof\n", s.base, strings.Join(written, ","))
+	}
+}
+
+func writeProfile(kind, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := pprof.Lookup(kind).WriteTo(f, 0); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"sort"
+	"sync"
+)
+
+// 转到定义和查找引用：检查器带有 ReferenceIndex 时，visitNode 把每个声明和每个标识符的解析结果记入索引，
+// 之后可以查询某个位置的标识符指向哪个符号，以及某个符号的全部声明和引用
+
+// Reference 符号在源码中的一次出现
+type Reference struct {
+	File        string
+	Node        *ASTNode // 声明节点或标识符节点；跨文件的 import 引用没有节点
+	Symbol      *Symbol  // 找不到声明的标识符为 nil
+	Declaration bool
+}
+
+// ReferenceIndex 声明和引用的索引，可以在 fork 出的检查器之间共享（并发安全）
+type ReferenceIndex struct {
+	mu          sync.Mutex
+	identifiers map[string][]Reference        // 文件 -> 标识符引用，查询前按位置排序
+	bySymbol    map[*Symbol][]Reference       // 符号 -> 声明和引用，按记录顺序
+	topLevel    map[string]map[string]*Symbol // 文件 -> 文件作用域中声明的符号，用于跨文件解析
+	definedIn   map[*Symbol]string            // 符号 -> 定义它的文件
+	sorted      map[string]bool
+}
+
+// NewReferenceIndex 创建空的索引
+func NewReferenceIndex() *ReferenceIndex {
+	return &ReferenceIndex{
+		identifiers: make(map[string][]Reference),
+		bySymbol:    make(map[*Symbol][]Reference),
+		topLevel:    make(map[string]map[string]*Symbol),
+		definedIn:   make(map[*Symbol]string),
+		sorted:      make(map[string]bool),
+	}
+}
+
+// addDeclaration 记录 symbol 在 file 中由 node 声明，topLevel 表示声明在文件作用域中
+func (x *ReferenceIndex) addDeclaration(file string, node *ASTNode, symbol *Symbol, topLevel bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.bySymbol[symbol] = append(x.bySymbol[symbol], Reference{File: file, Node: node, Symbol: symbol, Declaration: true})
+	if _, ok := x.definedIn[symbol]; !ok {
+		x.definedIn[symbol] = file
+	}
+	if topLevel {
+		if x.topLevel[file] == nil {
+			x.topLevel[file] = make(map[string]*Symbol)
+		}
+		x.topLevel[file][symbol.Name] = symbol
+	}
+}
+
+// addDefinition 记录 symbol 定义于 file，用于没有声明节点的符号（例如生成的项目中的导出符号），
+// 只记在索引中，不修改符号本身。已经记录过定义的文件时不覆盖
+func (x *ReferenceIndex) addDefinition(file string, symbol *Symbol) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if _, ok := x.definedIn[symbol]; !ok {
+		x.definedIn[symbol] = file
+	}
+}
+
+// DefinitionFile 返回定义 symbol 的文件，索引中没有记录时为 symbol.File
+func (x *ReferenceIndex) DefinitionFile(symbol *Symbol) string {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if file, ok := x.definedIn[symbol]; ok {
+		return file
+	}
+	return symbol.File
+}
+
+// addReference 记录 file 中的一次引用。node 为 nil 时（跨文件的 import）只能按符号查找，不能按位置查找
+func (x *ReferenceIndex) addReference(file string, node *ASTNode, symbol *Symbol) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	ref := Reference{File: file, Node: node, Symbol: symbol}
+	if node != nil {
+		x.identifiers[file] = append(x.identifiers[file], ref)
+		x.sorted[file] = false
+	}
+	if symbol != nil {
+		x.bySymbol[symbol] = append(x.bySymbol[symbol], ref)
+	}
+}
+
+// resolveExternal 对 file 中找不到声明的标识符调用 resolve（例如按 import 查找其他文件的导出），
+// 找到时补记为对该符号的引用。返回补记的引用数
+func (x *ReferenceIndex) resolveExternal(file string, resolve func(name string) *Symbol) int {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	resolved := 0
+	refs := x.identifiers[file]
+	for i := range refs {
+		if refs[i].Symbol != nil {
+			continue
+		}
+		if symbol := resolve(refs[i].Node.Name); symbol != nil {
+			refs[i].Symbol = symbol
+			x.bySymbol[symbol] = append(x.bySymbol[symbol], refs[i])
+			resolved++
+		}
+	}
+	return resolved
+}
+
+// TopLevelSymbol 返回 file 的文件作用域中名为 name 的符号
+func (x *ReferenceIndex) TopLevelSymbol(file, name string) *Symbol {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.topLevel[file][name]
+}
+
+// Identifiers 返回 file 中按位置排序的全部标识符引用（包括找不到声明的）
+func (x *ReferenceIndex) Identifiers(file string) []Reference {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.sortedIdentifiers(file)
+}
+
+func (x *ReferenceIndex) sortedIdentifiers(file string) []Reference {
+	refs := x.identifiers[file]
+	if !x.sorted[file] {
+		sort.Slice(refs, func(i, j int) bool { return positionBefore(refs[i].Node.Pos, refs[j].Node.Pos) })
+		x.sorted[file] = true
+	}
+	return refs
+}
+
+// DefinitionAt 转到定义：返回 file 中位置 pos（行、列）处的标识符所指的符号，
+// 符号的 Declaration 和 DefinitionFile 即定义的位置。该位置不是已解析的标识符时返回 nil
+func (x *ReferenceIndex) DefinitionAt(file string, pos Position) *Symbol {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	refs := x.sortedIdentifiers(file)
+	// 标识符互不重叠：找到最后一个起点不在 pos 之后的标识符，再检查 pos 是否在它的范围内
+	i := sort.Search(len(refs), func(i int) bool { return positionBefore(pos, refs[i].Node.Pos) }) - 1
+	if i < 0 || !positionBefore(pos, refs[i].Node.End) {
+		return nil
+	}
+	return refs[i].Symbol
+}
+
+// References 查找引用：返回 symbol 的全部声明和引用，声明在前，其余按文件和位置排序
+func (x *ReferenceIndex) References(symbol *Symbol) []Reference {
+	x.mu.Lock()
+	refs := append([]Reference(nil), x.bySymbol[symbol]...)
+	x.mu.Unlock()
+	sort.SliceStable(refs, func(i, j int) bool {
+		a, b := refs[i], refs[j]
+		if a.Declaration != b.Declaration {
+			return a.Declaration
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Node != nil && (b.Node == nil || positionBefore(a.Node.Pos, b.Node.Pos))
+	})
+	return refs
+}
+
+// Symbols 返回索引中有声明或引用的全部符号
+func (x *ReferenceIndex) Symbols() []*Symbol {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	symbols := make([]*Symbol, 0, len(x.bySymbol))
+	for symbol := range x.bySymbol {
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+// positionBefore 按行、列比较位置
+func positionBefore(a, b Position) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Column < b.Column
+}
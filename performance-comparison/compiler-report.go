@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"runtime"
+	"time"
+)
+
+// BenchmarkResult 机器可读的测试结果，所有 Go 测试程序的 -json 输出共用这一格式。
+// memory-test、cpu-intensive-test 和 js-limitations-test 通过指向本文件的符号链接使用同一份定义，
+// 因此本文件只能依赖标准库
+type BenchmarkResult struct {
+	Benchmark  string                 `json:"benchmark"`
+	Language   string                 `json:"language"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	WallTimeMs float64                `json:"wallTimeMs"`
+	AllocBytes uint64                 `json:"allocBytes"` // 测量期间累计分配的堆内存
+	GCCount    uint32                 `json:"gcCount"`    // 测量期间完成的 GC 次数
+	CPUCount   int                    `json:"cpuCount"`
+	GoVersion  string                 `json:"goVersion"`
+	GOOS       string                 `json:"goos"`
+	GOARCH     string                 `json:"goarch"`
+	Metrics    map[string]float64     `json:"metrics,omitempty"` // 各测试特有的数值，例如节点数、加速比
+	Stats      *TimingStats           `json:"stats,omitempty"`   // 多次采样时的统计，此时 WallTimeMs 为中位数
+}
+
+// TimingStats 多次采样的耗时统计，单位均为毫秒
+type TimingStats struct {
+	Warmup    int       `json:"warmup"`
+	Samples   int       `json:"samples"`
+	MeanMs    float64   `json:"meanMs"`
+	MedianMs  float64   `json:"medianMs"`
+	P95Ms     float64   `json:"p95Ms"`
+	StdDevMs  float64   `json:"stddevMs"`
+	CILowMs   float64   `json:"ciLowMs"` // 均值的 95% 置信区间
+	CIHighMs  float64   `json:"ciHighMs"`
+	MinMs     float64   `json:"minMs"`
+	MaxMs     float64   `json:"maxMs"`
+	SamplesMs []float64 `json:"samplesMs"`
+}
+
+// benchmarkRun 一次正在进行的测量
+type benchmarkRun struct {
+	name   string
+	params map[string]interface{}
+	before runtime.MemStats
+	start  time.Time
+}
+
+// startBenchmark 开始测量，记录起始时间和内存统计
+func startBenchmark(name string, params map[string]interface{}) *benchmarkRun {
+	run := &benchmarkRun{name: name, params: params}
+	runtime.ReadMemStats(&run.before)
+	run.start = time.Now()
+	return run
+}
+
+// stop 结束测量并生成结果，metrics 可以为 nil
+func (r *benchmarkRun) stop(metrics map[string]float64) BenchmarkResult {
+	elapsed := time.Since(r.start)
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+
+	return BenchmarkResult{
+		Benchmark:  r.name,
+		Language:   "go",
+		Parameters: r.params,
+		WallTimeMs: float64(elapsed.Nanoseconds()) / 1000000,
+		AllocBytes: after.TotalAlloc - r.before.TotalAlloc,
+		GCCount:    after.NumGC - r.before.NumGC,
+		CPUCount:   runtime.NumCPU(),
+		GoVersion:  runtime.Version(),
+		GOOS:       runtime.GOOS,
+		GOARCH:     runtime.GOARCH,
+		Metrics:    metrics,
+	}
+}
+
+// writeResultsJSON 以 JSON 数组输出全部结果
+func writeResultsJSON(w io.Writer, results []BenchmarkResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"runtime/trace"
+)
+
+// 执行跟踪（-profile-kinds trace）中使用的任务和区域。
+// 每个文件是一个任务，文件的各个处理步骤是任务中的区域，
+// go tool trace 的 User-defined tasks/regions 视图按文件展示信号量等待、GC 暂停和耗时分布。
+// 未开启跟踪时 trace.NewTask/StartRegion 几乎没有开销
+
+// 区域名称
+const (
+	regionSemaphoreWait    = "semaphore-wait"    // 等待并发信号量
+	regionASTVisit         = "ast-visit"         // AST 遍历
+	regionSymbolResolution = "symbol-resolution" // 符号解析
+	regionTypeCheck        = "type-check"        // 类型检查
+	regionImportResolution = "import-resolution" // import 解析
+	regionIncrementalCheck = "incremental-check" // 记录增量检查的哈希
+)
+
+// startFileTask 为文件 path 创建 trace 任务，返回的 context 用于在任务中开启区域
+func startFileTask(ctx context.Context, path string) (context.Context, *trace.Task) {
+	ctx, task := trace.NewTask(ctx, "file")
+	if trace.IsEnabled() {
+		trace.Log(ctx, "path", path)
+	}
+	return ctx, task
+}
+
+// acquireSemaphore 获取信号量，等待时间记录为 semaphore-wait 区域。
+// 等待期间 ctx 被取消时放弃获取并返回 ctx.Err()
+func acquireSemaphore(ctx context.Context, semaphore chan struct{}) error {
+	region := trace.StartRegion(ctx, regionSemaphoreWait)
+	defer region.End()
+	select {
+	case semaphore <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,638 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 结构化类型：原始类型、对象类型（属性可以是可选的，方法是函数类型的属性）、函数签名、联合类型，
+// 以及按名称引用的类型（比较时由 TypeRelation 解析）。对象类型可以是带类型参数的泛型类型，
+// 引用泛型类型时给出的类型实参在解析时实例化（见 InstantiationCache）。
+// 可赋值性按结构判断：对象类型只比较成员，不比较名称
+
+// TypeKind 结构化类型的种类
+type TypeKind int
+
+const (
+	PrimitiveType TypeKind = iota
+	ObjectType
+	FunctionType
+	UnionType
+	ReferenceType
+	TypeParameter
+)
+
+var typeKindNames = map[TypeKind]string{
+	PrimitiveType: "primitive",
+	ObjectType:    "object",
+	FunctionType:  "function",
+	UnionType:     "union",
+	ReferenceType: "reference",
+	TypeParameter: "type parameter",
+}
+
+func (k TypeKind) String() string {
+	if name, ok := typeKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("TypeKind(%d)", int(k))
+}
+
+// Type 结构化类型，创建后不再修改，可以在 goroutine 之间共享
+type Type struct {
+	Kind       TypeKind
+	Name       string      // 原始类型、引用类型和类型参数的名称；对象类型为声明的名称，只用于显示
+	Properties []*Property // ObjectType，按名称排序
+	Params     []*Type     // FunctionType
+	Return     *Type       // FunctionType
+	Members    []*Type     // UnionType，已展开嵌套的联合并去重
+	TypeParams []*Type     // 泛型的 ObjectType 声明的类型参数
+	TypeArgs   []*Type     // ReferenceType 给出的类型实参；实例化得到的 ObjectType 记录所用的实参
+	Constraint *Type       // TypeParameter 的约束，没有约束时为 nil
+
+	id uint64 // 创建顺序编号，实例化缓存按类型实参的编号区分实参
+}
+
+// nextTypeID 最近分配的类型编号
+var nextTypeID atomic.Uint64
+
+// newType 为 t 分配编号并返回 t
+func newType(t *Type) *Type {
+	t.id = nextTypeID.Add(1)
+	return t
+}
+
+// Property 对象类型的成员
+type Property struct {
+	Name     string
+	Type     *Type
+	Optional bool
+}
+
+// primitiveTypes 原始类型，每种只有一个实例，可以按指针比较
+var primitiveTypes = func() map[string]*Type {
+	types := make(map[string]*Type)
+	for _, name := range []string{TypeAny, "unknown", "never", TypeVoid, "undefined", "null", TypeString, TypeNumber, TypeBoolean, "bigint", "symbol"} {
+		types[name] = newType(&Type{Kind: PrimitiveType, Name: name})
+	}
+	return types
+}()
+
+// NewObjectType 创建对象类型，属性按名称排序，同名属性保留最后一个
+func NewObjectType(name string, properties []*Property) *Type {
+	byName := make(map[string]*Property, len(properties))
+	for _, property := range properties {
+		byName[property.Name] = property
+	}
+	sorted := make([]*Property, 0, len(byName))
+	for _, property := range byName {
+		sorted = append(sorted, property)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return newType(&Type{Kind: ObjectType, Name: name, Properties: sorted})
+}
+
+// NewGenericType 创建带类型参数的对象类型，properties 中可以使用 typeParams
+func NewGenericType(name string, typeParams []*Type, properties []*Property) *Type {
+	t := NewObjectType(name, properties)
+	t.TypeParams = typeParams
+	return t
+}
+
+// NewFunctionType 创建函数签名
+func NewFunctionType(params []*Type, ret *Type) *Type {
+	return newType(&Type{Kind: FunctionType, Params: params, Return: ret})
+}
+
+// NewUnionType 创建联合类型，展开嵌套的联合并去重；只剩一个成员时返回该成员
+func NewUnionType(members ...*Type) *Type {
+	var flat []*Type
+	seen := make(map[*Type]bool)
+	for _, member := range members {
+		parts := []*Type{member}
+		if member.Kind == UnionType {
+			parts = member.Members
+		}
+		for _, part := range parts {
+			if !seen[part] {
+				seen[part] = true
+				flat = append(flat, part)
+			}
+		}
+	}
+	if len(flat) == 1 {
+		return flat[0]
+	}
+	return newType(&Type{Kind: UnionType, Members: flat})
+}
+
+// NewReferenceType 创建按名称引用的类型，引用泛型类型时 typeArgs 为类型实参
+func NewReferenceType(name string, typeArgs ...*Type) *Type {
+	return newType(&Type{Kind: ReferenceType, Name: name, TypeArgs: typeArgs})
+}
+
+// NewTypeParameter 创建类型参数，constraint 为 nil 表示没有约束
+func NewTypeParameter(name string, constraint *Type) *Type {
+	return newType(&Type{Kind: TypeParameter, Name: name, Constraint: constraint})
+}
+
+// parseTypeText 解析类型文本：以 | 分隔的联合、"() => T" 形式的无参函数、原始类型名、typeParams 中的类型参数名
+// 和其他类型名（引用类型，可以带 <...> 类型实参）。
+// 无法识别的文本（例如从真实仓库中只取到第一个词法单元 "{"）视为 any
+func parseTypeText(text string, typeParams map[string]*Type) *Type {
+	text = strings.TrimSpace(text)
+	if ret, ok := strings.CutPrefix(text, "() => "); ok {
+		return NewFunctionType(nil, parseTypeText(ret, typeParams))
+	}
+	if parts := splitTopLevel(text, '|'); len(parts) > 1 {
+		members := make([]*Type, len(parts))
+		for i, part := range parts {
+			members[i] = parseTypeText(part, typeParams)
+		}
+		return NewUnionType(members...)
+	}
+	if primitive, ok := primitiveTypes[text]; ok {
+		return primitive
+	}
+	if param, ok := typeParams[text]; ok {
+		return param
+	}
+	if name, args, ok := strings.Cut(text, "<"); ok && strings.HasSuffix(args, ">") && isIdentifierText(name) {
+		parts := splitTopLevel(strings.TrimSuffix(args, ">"), ',')
+		typeArgs := make([]*Type, len(parts))
+		for i, part := range parts {
+			typeArgs[i] = parseTypeText(part, typeParams)
+		}
+		return NewReferenceType(name, typeArgs...)
+	}
+	if !isIdentifierText(text) {
+		return primitiveTypes[TypeAny]
+	}
+	return NewReferenceType(text)
+}
+
+// splitTopLevel 按不在 <> 中的 sep 切分类型文本（=> 中的 > 不算）
+func splitTopLevel(text string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '<':
+			depth++
+		case c == '>' && (i == 0 || text[i-1] != '='):
+			depth--
+		case c == sep && depth == 0:
+			parts = append(parts, text[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, text[start:])
+}
+
+func isIdentifierText(text string) bool {
+	for i, c := range text {
+		if !(c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return text != ""
+}
+
+func (t *Type) String() string {
+	switch t.Kind {
+	case ObjectType:
+		if t.Name != "" {
+			return t.Name + typeListString(t.TypeArgs)
+		}
+		members := make([]string, len(t.Properties))
+		for i, property := range t.Properties {
+			optional := ""
+			if property.Optional {
+				optional = "?"
+			}
+			members[i] = property.Name + optional + ": " + property.Type.String()
+		}
+		return "{ " + strings.Join(members, "; ") + " }"
+	case FunctionType:
+		params := make([]string, len(t.Params))
+		for i, param := range t.Params {
+			params[i] = fmt.Sprintf("arg%d: %s", i, param)
+		}
+		return "(" + strings.Join(params, ", ") + ") => " + t.Return.String()
+	case UnionType:
+		members := make([]string, len(t.Members))
+		for i, member := range t.Members {
+			members[i] = member.String()
+		}
+		return strings.Join(members, " | ")
+	case ReferenceType:
+		return t.Name + typeListString(t.TypeArgs)
+	}
+	return t.Name
+}
+
+// typeListString 返回 <A, B> 形式的类型列表，列表为空时返回空字符串
+func typeListString(types []*Type) string {
+	if len(types) == 0 {
+		return ""
+	}
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = t.String()
+	}
+	return "<" + strings.Join(names, ", ") + ">"
+}
+
+// typePair 关系缓存的键
+type typePair struct {
+	source, target *Type
+}
+
+// TypeRelation 可赋值关系及其缓存，对应 TypeScript 检查器的 assignableRelation：
+// 一次检查使用一个 TypeRelation，结构比较的结果按类型对缓存，可以被并发检查的 goroutine 共享。
+// 解析引用泛型类型的引用时使用自己的实例化缓存
+type TypeRelation struct {
+	resolve        func(name string) *Type // 解析引用类型，找不到时返回 nil（按名称比较）
+	instantiations *InstantiationCache
+
+	mu    sync.RWMutex
+	cache map[typePair]bool
+
+	checks   atomic.Int64
+	lookups  atomic.Int64
+	hits     atomic.Int64
+	computed atomic.Int64
+}
+
+// RelationStats 关系缓存的统计
+type RelationStats struct {
+	Checks   int64 // isAssignableTo 的调用次数
+	Lookups  int64 // 需要结构比较的类型对查询缓存的次数（包括嵌套的比较）
+	Hits     int64 // 其中命中缓存的次数
+	Computed int64 // 实际做了结构比较的类型对数
+	Cached   int   // 缓存中的类型对数
+}
+
+// HitRate 缓存命中率
+func (s RelationStats) HitRate() float64 {
+	if s.Lookups == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Lookups)
+}
+
+func (s RelationStats) String() string {
+	return fmt.Sprintf("%d 次比较，缓存查询 %d 次（命中率 %.1f%%），结构比较 %d 次，缓存 %d 个类型对",
+		s.Checks, s.Lookups, s.HitRate()*100, s.Computed, s.Cached)
+}
+
+// NewTypeRelation 创建空的关系缓存，resolve 用于解析引用类型，可以为 nil
+func NewTypeRelation(resolve func(name string) *Type) *TypeRelation {
+	return &TypeRelation{resolve: resolve, instantiations: NewInstantiationCache(), cache: make(map[typePair]bool)}
+}
+
+// Stats 返回到目前为止的统计
+func (r *TypeRelation) Stats() RelationStats {
+	r.mu.RLock()
+	cached := len(r.cache)
+	r.mu.RUnlock()
+	return RelationStats{
+		Checks:   r.checks.Load(),
+		Lookups:  r.lookups.Load(),
+		Hits:     r.hits.Load(),
+		Computed: r.computed.Load(),
+		Cached:   cached,
+	}
+}
+
+// InstantiationStats 返回解析引用时实例化泛型类型的统计
+func (r *TypeRelation) InstantiationStats() InstantiationStats {
+	return r.instantiations.Stats()
+}
+
+// isAssignableTo 报告 source 类型的值能否赋给 target 类型
+func (r *TypeRelation) isAssignableTo(source, target *Type) bool {
+	r.checks.Add(1)
+	c := relationComparison{relation: r}
+	result, _ := c.compare(source, target)
+	return result
+}
+
+// noAssumption 比较结果不依赖任何假定
+const noAssumption = math.MaxInt
+
+// maxRelationDepth 嵌套比较的最大深度。泛型类型的引用可以无限展开（例如 Foo<T> 的成员是 Foo<Foo<T>>），
+// 超过这个深度时与 TypeScript 一样假定可赋值
+const maxRelationDepth = 100
+
+// relationComparison 一次顶层比较的状态。stack 是正在比较的类型对：再次遇到栈中的类型对（递归类型）时假定可赋值。
+// 依赖栈中更外层类型对假定的 true 不写入缓存，因为外层比较最终可能失败；false 总可以缓存
+type relationComparison struct {
+	relation *TypeRelation
+	stack    []typePair
+}
+
+// compare 返回 source 能否赋给 target，以及结果依赖的最外层假定在 stack 中的位置（没有依赖时为 noAssumption）
+func (c *relationComparison) compare(source, target *Type) (bool, int) {
+	source, target = c.relation.resolved(source), c.relation.resolved(target)
+	if result, known := simpleRelation(source, target); known {
+		return result, noAssumption
+	}
+
+	pair := typePair{source, target}
+	c.relation.lookups.Add(1)
+	c.relation.mu.RLock()
+	result, cached := c.relation.cache[pair]
+	c.relation.mu.RUnlock()
+	if cached {
+		c.relation.hits.Add(1)
+		return result, noAssumption
+	}
+	for i, p := range c.stack {
+		if p == pair {
+			return true, i
+		}
+	}
+	if len(c.stack) >= maxRelationDepth {
+		return true, 0
+	}
+
+	depth := len(c.stack)
+	c.stack = append(c.stack, pair)
+	result, assumed := c.structuredRelation(source, target)
+	c.stack = c.stack[:depth]
+	c.relation.computed.Add(1)
+	if !result || assumed >= depth {
+		c.relation.mu.Lock()
+		c.relation.cache[pair] = result
+		c.relation.mu.Unlock()
+		assumed = noAssumption
+	}
+	return result, assumed
+}
+
+// resolved 把引用类型解析为它指向的类型，指向泛型类型时用引用的类型实参实例化；找不到时保持引用类型
+func (r *TypeRelation) resolved(t *Type) *Type {
+	for seen := 0; t.Kind == ReferenceType && r.resolve != nil && seen < 8; seen++ {
+		target := r.resolve(t.Name)
+		if target == nil {
+			break
+		}
+		if len(target.TypeParams) > 0 {
+			target = r.instantiations.instantiate(target, t.TypeArgs, r)
+		}
+		t = target
+	}
+	return t
+}
+
+// simpleRelation 不需要结构比较（也不缓存）的情况：相同类型、any/unknown/never、目标是类型参数、
+// 原始类型之间和无法解析的引用类型
+func simpleRelation(source, target *Type) (result, known bool) {
+	switch {
+	case source == target:
+		return true, true
+	case target.Kind == PrimitiveType && (target.Name == TypeAny || target.Name == "unknown"):
+		return true, true
+	case source.Kind == PrimitiveType && (source.Name == TypeAny || source.Name == "never"):
+		return true, true
+	case target.Kind == TypeParameter:
+		// 只有类型参数自身能赋给它
+		return false, true
+	case source.Kind == TypeParameter || source.Kind == UnionType || target.Kind == UnionType:
+		return false, false
+	case source.Kind == PrimitiveType && target.Kind == PrimitiveType:
+		return source.Name == target.Name || source.Name == "undefined" && target.Name == TypeVoid, true
+	case source.Kind == ReferenceType || target.Kind == ReferenceType:
+		return source.Kind == target.Kind && source.Name == target.Name, true
+	case source.Kind != target.Kind:
+		return false, true
+	}
+	return false, false
+}
+
+// structuredRelation 比较联合、对象和函数类型，返回值同 compare
+func (c *relationComparison) structuredRelation(source, target *Type) (bool, int) {
+	assumed := noAssumption
+	related := func(s, t *Type) bool {
+		result, a := c.compare(s, t)
+		assumed = min(assumed, a)
+		return result
+	}
+
+	switch {
+	case source.Kind == UnionType:
+		// 每个成员都能赋给目标
+		for _, member := range source.Members {
+			if !related(member, target) {
+				return false, noAssumption
+			}
+		}
+		return true, assumed
+	case source.Kind == TypeParameter:
+		// 类型参数按约束比较，没有约束时相当于 unknown
+		constraint := source.Constraint
+		if constraint == nil {
+			constraint = primitiveTypes["unknown"]
+		}
+		if !related(constraint, target) {
+			return false, noAssumption
+		}
+		return true, assumed
+	case target.Kind == UnionType:
+		// 能赋给目标的某个成员
+		for _, member := range target.Members {
+			if result, a := c.compare(source, member); result {
+				return true, min(assumed, a)
+			}
+		}
+		return false, noAssumption
+	case source.Kind == ObjectType:
+		// 目标的每个必需属性在源中都存在，可选属性存在时类型也要兼容；两者按名称排序，合并遍历
+		i := 0
+		for _, want := range target.Properties {
+			for i < len(source.Properties) && source.Properties[i].Name < want.Name {
+				i++
+			}
+			if i == len(source.Properties) || source.Properties[i].Name != want.Name {
+				if want.Optional {
+					continue
+				}
+				return false, noAssumption
+			}
+			have := source.Properties[i]
+			if have.Optional && !want.Optional || !related(have.Type, want.Type) {
+				return false, noAssumption
+			}
+		}
+		return true, assumed
+	case source.Kind == FunctionType:
+		// 源的参数不能比目标多，参数逆变，返回值协变；目标返回 void 时不检查返回值
+		if len(source.Params) > len(target.Params) {
+			return false, noAssumption
+		}
+		for i, param := range source.Params {
+			if !related(target.Params[i], param) {
+				return false, noAssumption
+			}
+		}
+		if target.Return != primitiveTypes[TypeVoid] && !related(source.Return, target.Return) {
+			return false, noAssumption
+		}
+		return true, assumed
+	}
+	return false, noAssumption
+}
+
+// InstantiationCache 泛型类型的实例化及其缓存，对应 TypeScript 中泛型类型的 instantiations：
+// 同一个泛型类型用同一组类型实参（按类型的身份，即同一个 *Type）实例化时只替换一次并返回同一个实例，
+// 因此实例之间的比较也能命中关系缓存。可以被并发检查的 goroutine 共享
+type InstantiationCache struct {
+	mu    sync.Mutex
+	cache map[instantiationKey]*Type
+
+	requests   atomic.Int64
+	hits       atomic.Int64
+	violations atomic.Int64
+}
+
+// instantiationKey 实例化缓存的键：泛型类型和类型实参的编号
+type instantiationKey struct {
+	generic *Type
+	args    string
+}
+
+// InstantiationStats 实例化缓存的统计
+type InstantiationStats struct {
+	Requests             int64 // 请求实例化的次数
+	Hits                 int64 // 其中命中缓存的次数
+	Instances            int   // 创建的实例数
+	ConstraintViolations int64 // 类型实参不满足约束的实例数
+}
+
+// HitRate 缓存命中率
+func (s InstantiationStats) HitRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Requests)
+}
+
+func (s InstantiationStats) String() string {
+	return fmt.Sprintf("%d 次实例化请求（命中率 %.1f%%），%d 个实例，其中 %d 个的类型实参不满足约束",
+		s.Requests, s.HitRate()*100, s.Instances, s.ConstraintViolations)
+}
+
+// NewInstantiationCache 创建空的实例化缓存
+func NewInstantiationCache() *InstantiationCache {
+	return &InstantiationCache{cache: make(map[instantiationKey]*Type)}
+}
+
+// Stats 返回到目前为止的统计
+func (c *InstantiationCache) Stats() InstantiationStats {
+	c.mu.Lock()
+	instances := len(c.cache)
+	c.mu.Unlock()
+	return InstantiationStats{
+		Requests:             c.requests.Load(),
+		Hits:                 c.hits.Load(),
+		Instances:            instances,
+		ConstraintViolations: c.violations.Load(),
+	}
+}
+
+// instantiate 用 args 实例化泛型类型 generic。实参少于类型参数时，缺少的参数取它的约束（没有约束时为 unknown），
+// 多余的实参忽略。创建实例时用 relation 检查每个实参是否满足约束，不满足时计入统计但仍然返回实例
+// （与 TypeScript 报告错误后继续检查一致）
+func (c *InstantiationCache) instantiate(generic *Type, args []*Type, relation *TypeRelation) *Type {
+	c.requests.Add(1)
+	filled := make([]*Type, len(generic.TypeParams))
+	ids := make([]string, len(filled))
+	for i, param := range generic.TypeParams {
+		switch {
+		case i < len(args):
+			filled[i] = args[i]
+		case param.Constraint != nil:
+			filled[i] = param.Constraint
+		default:
+			filled[i] = primitiveTypes["unknown"]
+		}
+		ids[i] = fmt.Sprint(filled[i].id)
+	}
+	key := instantiationKey{generic, strings.Join(ids, ",")}
+	c.mu.Lock()
+	instance, ok := c.cache[key]
+	c.mu.Unlock()
+	if ok {
+		c.hits.Add(1)
+		return instance
+	}
+
+	mapping := make(map[*Type]*Type, len(filled))
+	violated := false
+	for i, param := range generic.TypeParams {
+		mapping[param] = filled[i]
+		if param.Constraint != nil && !relation.isAssignableTo(filled[i], substitute(param.Constraint, mapping)) {
+			violated = true
+		}
+	}
+	properties := make([]*Property, len(generic.Properties))
+	for i, property := range generic.Properties {
+		properties[i] = &Property{Name: property.Name, Type: substitute(property.Type, mapping), Optional: property.Optional}
+	}
+	instance = newType(&Type{Kind: ObjectType, Name: generic.Name, Properties: properties, TypeArgs: filled})
+
+	// 并发时可能有多个 goroutine 同时创建同一个实例，以先写入缓存的为准
+	c.mu.Lock()
+	if existing, ok := c.cache[key]; ok {
+		instance = existing
+	} else {
+		c.cache[key] = instance
+		if violated {
+			c.violations.Add(1)
+		}
+	}
+	c.mu.Unlock()
+	return instance
+}
+
+// substitute 把 t 中的类型参数替换为 mapping 中对应的类型；没有需要替换的部分时返回 t 本身。
+// 对象类型只通过引用出现在其他类型中，替换的是引用的类型实参
+func substitute(t *Type, mapping map[*Type]*Type) *Type {
+	switch t.Kind {
+	case TypeParameter:
+		if target, ok := mapping[t]; ok {
+			return target
+		}
+	case FunctionType:
+		params, changed := substituteAll(t.Params, mapping)
+		ret := substitute(t.Return, mapping)
+		if changed || ret != t.Return {
+			return NewFunctionType(params, ret)
+		}
+	case UnionType:
+		if members, changed := substituteAll(t.Members, mapping); changed {
+			return NewUnionType(members...)
+		}
+	case ReferenceType:
+		if args, changed := substituteAll(t.TypeArgs, mapping); changed {
+			return NewReferenceType(t.Name, args...)
+		}
+	}
+	return t
+}
+
+// substituteAll 对每个类型调用 substitute，报告是否有类型被替换
+func substituteAll(types []*Type, mapping map[*Type]*Type) ([]*Type, bool) {
+	result := make([]*Type, len(types))
+	changed := false
+	for i, t := range types {
+		result[i] = substitute(t, mapping)
+		changed = changed || result[i] != t
+	}
+	return result, changed
+}
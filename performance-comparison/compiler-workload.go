@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"strconv"
+)
+
+// 可复现的工作负载生成。
+// workloadRand 与 src/ 下 JavaScript/TypeScript 测试中的 WorkloadRandom（TS 大规模测试中为 LargeTestRandom）
+// 使用同一算法（mulberry32），
+// 相同种子在三种语言中产生完全相同的随机数序列，因此生成的 AST 也逐字节一致。
+// 修改这里的算法时必须同步修改 JS/TS 版本，并用 fingerprintAST 核对
+
+// workloadRand mulberry32 伪随机数生成器，只使用 32 位无符号整数运算，便于在 JS 中精确复现
+type workloadRand struct {
+	state uint32
+}
+
+// newWorkloadRand 创建以 seed 为种子的生成器
+func newWorkloadRand(seed uint32) *workloadRand {
+	return &workloadRand{state: seed}
+}
+
+func (r *workloadRand) next() uint32 {
+	r.state += 0x6D2B79F5
+	t := r.state
+	t = (t ^ (t >> 15)) * (t | 1)
+	t ^= t + (t^(t>>7))*(t|61)
+	return t ^ (t >> 14)
+}
+
+// intn 返回 [0, n) 内的整数。直接取模，n 很小时的偏差可以忽略，并且与 JS 版本一致
+func (r *workloadRand) intn(n int) int {
+	return int(r.next() % uint32(n))
+}
+
+// float64 返回 [0, 1) 内的浮点数
+func (r *workloadRand) float64() float64 {
+	return float64(r.next()) / (1 << 32)
+}
+
+// deriveSeed 为第 index 个子任务（例如第 index 个文件）派生独立的种子，
+// 使每个文件的内容只取决于 seed 和文件编号
+func deriveSeed(seed uint32, index int) uint32 {
+	return seed + uint32(index+1)*0x9E3779B9
+}
+
+// workloadNodeKinds 生成 AST 时可选的节点类型，顺序与 JS/TS 版本的 kinds 数组一致
+var workloadNodeKinds = []NodeKind{
+	FunctionDeclaration,
+	VariableDeclaration,
+	CallExpression,
+	BinaryExpression,
+	Identifier,
+}
+
+// generateWorkloadAST 生成深度为 depth、每个节点 breadth 个子节点的 AST。
+// 每个节点依次消耗两个随机数：先决定类型，再决定名称
+func generateWorkloadAST(rng *workloadRand, depth, breadth int) *ASTNode {
+	kind := workloadNodeKinds[rng.intn(len(workloadNodeKinds))]
+	name := "node_" + strconv.FormatUint(uint64(rng.next()), 36)
+	node := &ASTNode{
+		Kind:     kind,
+		Name:     name,
+		Children: make([]*ASTNode, 0, breadth),
+	}
+
+	if depth > 0 {
+		for i := 0; i < breadth; i++ {
+			child := generateWorkloadAST(rng, depth-1, breadth)
+			child.Parent = node
+			node.Children = append(node.Children, child)
+		}
+	}
+	return node
+}
+
+// assignSyntheticPositions 为生成的 AST 按前序遍历顺序分配位置（每个节点占一行），
+// 使诊断可以定位并排序
+func assignSyntheticPositions(root *ASTNode) {
+	line := 0
+	var walk func(node *ASTNode)
+	walk = func(node *ASTNode) {
+		node.Pos = Position{Offset: line, Line: line + 1, Column: 1}
+		line++
+		for _, child := range node.Children {
+			walk(child)
+		}
+		node.End = Position{Offset: line, Line: line + 1, Column: 1}
+	}
+	walk(root)
+}
+
+// generateWorkloadFlatAST 与 generateWorkloadAST 相同（同一种子生成同一棵树），但生成扁平 AST
+func generateWorkloadFlatAST(rng *workloadRand, depth, breadth int) *FlatAST {
+	nodes := 1
+	for level, width := 0, 1; level < depth; level++ {
+		width *= breadth
+		nodes += width
+	}
+	a := NewFlatAST(nodes)
+	name := []byte("node_")
+	var add func(parent NodeIndex, depth int)
+	add = func(parent NodeIndex, depth int) {
+		kind := workloadNodeKinds[rng.intn(len(workloadNodeKinds))]
+		name = strconv.AppendUint(name[:len("node_")], uint64(rng.next()), 36)
+		node := a.AddNode(parent, kind, string(name)) // 名称复制到 a 的文本中，转换不需要分配
+		if depth > 0 {
+			for i := 0; i < breadth; i++ {
+				add(node, depth-1)
+			}
+		}
+	}
+	add(NoNode, depth)
+	a.Finish()
+	return a
+}
+
+// writeASTFingerprint 以前序遍历把 AST 写成 "类型编号 名称 子节点数\n" 的规范形式，
+// JS/TS 版本写出的字节完全相同
+func writeASTFingerprint(h hash.Hash32, node *ASTNode) {
+	h.Write([]byte(strconv.Itoa(int(node.Kind)) + " " + node.Name + " " + strconv.Itoa(len(node.Children)) + "\n"))
+	for _, child := range node.Children {
+		writeASTFingerprint(h, child)
+	}
+}
+
+// fingerprintASTs 返回一组 AST 规范形式的 FNV-1a 32 位哈希（8 位十六进制），
+// 用于核对不同语言生成的工作负载是否一致
+func fingerprintASTs(roots ...*ASTNode) string {
+	h := fnv.New32a()
+	for _, root := range roots {
+		writeASTFingerprint(h, root)
+	}
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// fingerprintFlatASTs 与 fingerprintASTs 相同，用于核对扁平 AST 与指针形式的 AST 是否表示同一棵树
+func fingerprintFlatASTs(asts ...*FlatAST) string {
+	h := fnv.New32a()
+	for _, a := range asts {
+		a.Walk(a.Root(), func(node NodeIndex) bool {
+			h.Write([]byte(strconv.Itoa(int(a.Kind(node))) + " " + a.Name(node) + " " + strconv.Itoa(a.ChildCount(node)) + "\n"))
+			return true
+		}, nil)
+	}
+	return fmt.Sprintf("%08x", h.Sum32())
+}
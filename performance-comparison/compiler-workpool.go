@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ConcurrencyStrategy 并发处理文件的方式
+type ConcurrencyStrategy int
+
+const (
+	StrategyGoroutine ConcurrencyStrategy = iota // 每个文件一个 goroutine，用信号量限制同时运行的数量
+	StrategyQueue                                // 固定数量的 worker 从一个共享队列取任务
+	StrategySteal                                // 固定数量的 worker，各自有双端队列，空闲时从其他 worker 窃取
+)
+
+var strategyNames = map[ConcurrencyStrategy]string{
+	StrategyGoroutine: "goroutine",
+	StrategyQueue:     "queue",
+	StrategySteal:     "steal",
+}
+
+// concurrencyStrategies 全部策略，按定义顺序
+var concurrencyStrategies = []ConcurrencyStrategy{StrategyGoroutine, StrategyQueue, StrategySteal}
+
+func (s ConcurrencyStrategy) String() string {
+	return strategyNames[s]
+}
+
+// parseConcurrencyStrategy 解析命令行中的并发策略名称
+func parseConcurrencyStrategy(name string) (ConcurrencyStrategy, error) {
+	for strategy, strategyName := range strategyNames {
+		if strategyName == name {
+			return strategy, nil
+		}
+	}
+	names := make([]string, 0, len(strategyNames))
+	for _, strategyName := range strategyNames {
+		names = append(names, strategyName)
+	}
+	sort.Strings(names)
+	return StrategyGoroutine, fmt.Errorf("未知的并发策略 %q（可选: %s）", name, strings.Join(names, ", "))
+}
+
+// workDeque 加锁的双端队列。所有者从尾部取（后进先出，缓存更热），
+// 窃取者从头部取（先进先出，取走最早提交、通常也最大的任务）
+type workDeque struct {
+	mu    sync.Mutex
+	tasks []func(worker int)
+}
+
+func (d *workDeque) push(task func(worker int)) {
+	d.mu.Lock()
+	d.tasks = append(d.tasks, task)
+	d.mu.Unlock()
+}
+
+func (d *workDeque) popBack() func(worker int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := len(d.tasks)
+	if n == 0 {
+		return nil
+	}
+	task := d.tasks[n-1]
+	d.tasks[n-1] = nil
+	d.tasks = d.tasks[:n-1]
+	return task
+}
+
+func (d *workDeque) popFront() func(worker int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.tasks) == 0 {
+		return nil
+	}
+	task := d.tasks[0]
+	d.tasks[0] = nil
+	d.tasks = d.tasks[1:]
+	return task
+}
+
+// WorkerPool 固定数量 worker 的任务池，只使用一次：提交任务后调用 Wait，
+// 所有任务（包括任务中再提交的任务）完成后 worker 退出。
+// 共享队列模式下所有 worker 按提交顺序取任务；窃取模式下每个 worker 优先处理自己的队列
+type WorkerPool struct {
+	workers int
+	steal   bool
+	deques  []*workDeque // 共享队列模式下只有一个
+
+	queued  atomic.Int64 // 已提交但尚未被取走的任务数
+	pending atomic.Int64 // 未完成的任务数，另加 1 直到调用 Wait
+	idle    atomic.Int32
+	next    atomic.Uint32 // 外部提交时轮流选择队列
+	steals  atomic.Int64
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	done bool
+	wg   sync.WaitGroup
+}
+
+// NewWorkerPool 创建并启动 workers 个 worker。steal 为 false 时使用共享队列
+func NewWorkerPool(workers int, steal bool) *WorkerPool {
+	workers = max(workers, 1)
+	p := &WorkerPool{workers: workers, steal: steal}
+	p.cond = sync.NewCond(&p.mu)
+	queues := 1
+	if steal {
+		queues = workers
+	}
+	for i := 0; i < queues; i++ {
+		p.deques = append(p.deques, &workDeque{})
+	}
+	p.pending.Store(1)
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.run(i)
+	}
+	return p
+}
+
+// Submit 从池外提交任务，task 的参数是执行它的 worker 编号
+func (p *WorkerPool) Submit(task func(worker int)) {
+	p.submit(int(p.next.Add(1)-1)%len(p.deques), task)
+}
+
+// SubmitFrom 由正在执行任务的 worker 提交后续任务。窃取模式下任务放入该 worker 自己的队列
+func (p *WorkerPool) SubmitFrom(worker int, task func(worker int)) {
+	p.submit(worker%len(p.deques), task)
+}
+
+func (p *WorkerPool) submit(queue int, task func(worker int)) {
+	p.pending.Add(1)
+	p.deques[queue].push(task)
+	p.queued.Add(1)
+	if p.idle.Load() > 0 {
+		p.mu.Lock()
+		p.cond.Signal()
+		p.mu.Unlock()
+	}
+}
+
+// Wait 等待全部任务完成并停止 worker，返回窃取次数
+func (p *WorkerPool) Wait() int64 {
+	p.finish()
+	p.wg.Wait()
+	return p.steals.Load()
+}
+
+// finish 一个任务完成（或者 Wait 释放初始的计数）；计数归零时唤醒所有 worker 退出
+func (p *WorkerPool) finish() {
+	if p.pending.Add(-1) == 0 {
+		p.mu.Lock()
+		p.done = true
+		p.cond.Broadcast()
+		p.mu.Unlock()
+	}
+}
+
+func (p *WorkerPool) run(worker int) {
+	defer p.wg.Done()
+	for {
+		if task := p.take(worker); task != nil {
+			task(worker)
+			p.finish()
+			continue
+		}
+
+		// 没有可取的任务：在锁内再次检查，避免与 submit 之间丢失唤醒
+		p.mu.Lock()
+		p.idle.Add(1)
+		for p.queued.Load() == 0 && !p.done {
+			p.cond.Wait()
+		}
+		p.idle.Add(-1)
+		done := p.done
+		p.mu.Unlock()
+		if done {
+			return
+		}
+	}
+}
+
+// take 先从自己的队列取任务，窃取模式下再依次尝试其他 worker 的队列
+func (p *WorkerPool) take(worker int) func(worker int) {
+	if !p.steal {
+		if task := p.deques[0].popFront(); task != nil {
+			p.queued.Add(-1)
+			return task
+		}
+		return nil
+	}
+	if task := p.deques[worker].popBack(); task != nil {
+		p.queued.Add(-1)
+		return task
+	}
+	for i := 1; i < len(p.deques); i++ {
+		if task := p.deques[(worker+i)%len(p.deques)].popFront(); task != nil {
+			p.queued.Add(-1)
+			p.steals.Add(1)
+			return task
+		}
+	}
+	return nil
+}
@@ -1,147 +1,58 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"math"
 	"math/rand"
+	"os"
 	"runtime"
+	"runtime/trace"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// NodeKind 枚举
-type NodeKind int
-
-const (
-	FunctionDeclaration NodeKind = iota + 1
-	VariableDeclaration
-	CallExpression
-	BinaryExpression
-	Identifier
-)
-
-// ASTNode 结构体
-type ASTNode struct {
-	Kind     NodeKind
-	Name     string
-	Children []*ASTNode
-	Parent   *ASTNode
-}
-
-// Symbol 结构体
-type Symbol struct {
-	Name  string
-	Type  string
-	Scope int
-}
-
-// SymbolTable 结构体
-type SymbolTable struct {
-	symbols map[string]*Symbol
-	parent  *SymbolTable
-}
-
-// TypeChecker 结构体
-type TypeChecker struct {
-	symbolTable *SymbolTable
-	mu          sync.RWMutex // 用于并发安全
-}
-
-// NewTypeChecker 创建新的类型检查器
-func NewTypeChecker() *TypeChecker {
-	return &TypeChecker{
-		symbolTable: &SymbolTable{
-			symbols: make(map[string]*Symbol),
-		},
-	}
-}
-
-// 1. AST 节点遍历测试
-func (tc *TypeChecker) visitNode(node *ASTNode) int {
-	count := 1
-
-	switch node.Kind {
-	case FunctionDeclaration:
-		tc.checkFunctionDeclaration(node)
-	case VariableDeclaration:
-		tc.checkVariableDeclaration(node)
-	case CallExpression:
-		tc.checkCallExpression(node)
-	default:
-		// 其他节点类型
-	}
-
-	// 递归处理子节点
-	for _, child := range node.Children {
-		count += tc.visitNode(child)
-	}
-
-	return count
-}
-
-func (tc *TypeChecker) checkFunctionDeclaration(node *ASTNode) {
-	// 模拟函数声明检查
-	for i := 0; i < 100; i++ {
-		_ = fmt.Sprintf("%s%d", node.Name, i)
-	}
-}
-
-func (tc *TypeChecker) checkVariableDeclaration(node *ASTNode) {
-	// 模拟变量声明检查
-	for i := 0; i < 50; i++ {
-		_ = fmt.Sprintf("%s%d", node.Name, i)
-	}
-}
-
-func (tc *TypeChecker) checkCallExpression(node *ASTNode) {
-	// 模拟函数调用检查
-	for i := 0; i < 75; i++ {
-		_ = fmt.Sprintf("%s%d", node.Name, i)
-	}
-}
-
-// 2. 符号表查找测试
-func (tc *TypeChecker) resolveSymbol(name string) *Symbol {
-	tc.mu.RLock()
-	defer tc.mu.RUnlock()
-
-	current := tc.symbolTable
-	for current != nil {
-		if symbol, exists := current.symbols[name]; exists {
-			return symbol
-		}
-		current = current.parent
-	}
-	return nil
-}
-
-func (tc *TypeChecker) addSymbol(name, symbolType string, scope int) {
-	tc.mu.Lock()
-	defer tc.mu.Unlock()
-
-	tc.symbolTable.symbols[name] = &Symbol{
-		Name:  name,
-		Type:  symbolType,
-		Scope: scope,
-	}
-}
-
 // 3. 并发处理测试
-func (tc *TypeChecker) processFilesConcurrent(files []*ASTNode) int {
+// 每个文件由 fork 出的检查器处理，诊断汇总到并发安全的 diagnostics 中。
+// ctx 被取消后不再开始新的文件，正在检查的文件在下一个节点处停止，其诊断丢弃；
+// 返回总节点数（含未完成的文件）和已完成的文件
+func (tc *TypeChecker) processFilesConcurrent(ctx context.Context, files []*ASTNode, diagnostics *DiagnosticCollector) (int, CheckResult) {
 	var wg sync.WaitGroup
 	var totalNodes int64
 	var mu sync.Mutex
+	completion := newCompletionTracker(len(files))
 
 	// 使用 goroutine 并发处理
 	semaphore := make(chan struct{}, runtime.NumCPU())
 
 	for _, file := range files {
+		if ctx.Err() != nil {
+			break
+		}
 		wg.Add(1)
 		go func(f *ASTNode) {
 			defer wg.Done()
-			semaphore <- struct{}{} // 获取信号量
+			fileCtx, task := startFileTask(ctx, f.Name)
+			defer task.End()
+			if acquireSemaphore(fileCtx, semaphore) != nil { // 获取信号量
+				return
+			}
 			defer func() { <-semaphore }()
 
-			count := tc.visitNode(f)
+			fc := tc.fork()
+			fc.ctx = fileCtx
+			fc.fileName = f.Name
+			region := trace.StartRegion(fileCtx, regionASTVisit)
+			count := fc.visitNode(f)
+			region.End()
+
+			if fc.err == nil {
+				diagnostics.add(fc.diagnostics...)
+				completion.done(f.Name)
+			}
 
 			mu.Lock()
 			totalNodes += int64(count)
@@ -150,54 +61,86 @@ func (tc *TypeChecker) processFilesConcurrent(files []*ASTNode) int {
 	}
 
 	wg.Wait()
-	return int(totalNodes)
+	return int(totalNodes), completion.result(ctx)
+}
+
+// 任务池处理：NumCPU 个 worker 取代每个文件一个 goroutine，steal 为 true 时使用工作窃取
+func (tc *TypeChecker) processFilesPool(files []*ASTNode, diagnostics *DiagnosticCollector, steal bool) int {
+	var totalNodes atomic.Int64
+	pool := NewWorkerPool(runtime.NumCPU(), steal)
+	for _, file := range files {
+		pool.Submit(func(int) {
+			ctx, task := startFileTask(context.Background(), file.Name)
+			defer task.End()
+
+			fc := tc.fork()
+			fc.fileName = file.Name
+			region := trace.StartRegion(ctx, regionASTVisit)
+			count := fc.visitNode(file)
+			region.End()
+
+			diagnostics.add(fc.diagnostics...)
+			totalNodes.Add(int64(count))
+		})
+	}
+	pool.Wait()
+	return int(totalNodes.Load())
 }
 
 // 单线程处理（用于对比）
-func (tc *TypeChecker) processFiles(files []*ASTNode) int {
+func (tc *TypeChecker) processFiles(files []*ASTNode, diagnostics *DiagnosticCollector) int {
 	totalNodes := 0
 	for _, file := range files {
+		tc.diagnostics = nil
+		tc.fileName = file.Name // 解析得到的 AST 的根节点是 SourceFile，名称就是文件名
 		totalNodes += tc.visitNode(file)
+		diagnostics.add(tc.diagnostics...)
 	}
 	return totalNodes
 }
 
-// 生成测试数据
-func generateAST(depth, breadth int) *ASTNode {
-	kinds := []NodeKind{
-		FunctionDeclaration,
-		VariableDeclaration,
-		CallExpression,
-		BinaryExpression,
-		Identifier,
+// visitWorkloadNode 与 JS/TS 版本的 visitNode 做同样的工作：函数声明、变量声明和调用表达式
+// 分别把名称与 0..99、0..49、0..74 拼接，再递归处理子节点。返回节点数，拼接结果的总长度累加到 length
+func visitWorkloadNode(node *ASTNode, length *int) int {
+	iterations := 0
+	switch node.Kind {
+	case FunctionDeclaration:
+		iterations = 100
+	case VariableDeclaration:
+		iterations = 50
+	case CallExpression:
+		iterations = 75
 	}
-
-	node := &ASTNode{
-		Kind:     kinds[rand.Intn(len(kinds))],
-		Name:     fmt.Sprintf("node_%d", rand.Int()),
-		Children: make([]*ASTNode, 0),
+	for i := 0; i < iterations; i++ {
+		*length += len(node.Name + strconv.Itoa(i))
 	}
-
-	if depth > 0 {
-		for i := 0; i < breadth; i++ {
-			child := generateAST(depth-1, breadth)
-			child.Parent = node
-			node.Children = append(node.Children, child)
-		}
+	count := 1
+	for _, child := range node.Children {
+		count += visitWorkloadNode(child, length)
 	}
+	return count
+}
 
-	return node
+// 生成测试数据：生成 TypeScript 源码并解析为 AST
+func generateParsedAST(fileName string, functionCount, statementsPerFunction int, rng *rand.Rand) *ASTNode {
+	src := generateSource(functionCount, statementsPerFunction, rng.Intn)
+	ast, errs := parseSource(fileName, src)
+	if len(errs) > 0 {
+		panic(fmt.Sprintf("生成的源码解析失败: %v", errs[0]))
+	}
+	return ast
 }
 
 func generateSymbols(count int) []*Symbol {
 	symbols := make([]*Symbol, count)
 	for i := 0; i < count; i++ {
-		symbolType := "variable"
+		kind, symbolType := SymbolVariable, TypeNumber
 		if i%2 == 0 {
-			symbolType = "function"
+			kind, symbolType = SymbolFunction, "() => void"
 		}
 		symbols[i] = &Symbol{
 			Name:  fmt.Sprintf("symbol_%d", i),
+			Kind:  kind,
 			Type:  symbolType,
 			Scope: i / 100,
 		}
@@ -205,6 +148,36 @@ func generateSymbols(count int) []*Symbol {
 	return symbols
 }
 
+// 含有类型错误的示例代码，与 type-checking-test/sum.ts 中的错误用法一致
+const diagnosticsSample = `function sum(a: number, b: number): number {
+    return a + b;
+}
+
+sum(1, '2');
+sum(1);
+sum(true, 2);
+
+const total: string = sum(1, 2);
+let label = "total: " + total;
+let broken = label - 1;
+missing(total);
+function sum(x: number): number {
+    return x;
+}
+`
+
+// checkSource 解析并检查一段源码，返回解析错误之外的所有诊断
+func checkSource(src string) []*Diagnostic {
+	ast, errs := parseSource("sum.ts", src)
+	if len(errs) > 0 {
+		panic(fmt.Sprintf("示例源码解析失败: %v", errs[0]))
+	}
+	checker := NewTypeChecker()
+	checker.visitNode(ast)
+	sortDiagnostics(checker.diagnostics)
+	return checker.diagnostics
+}
+
 // 内存使用统计
 func getMemStats() (float64, float64) {
 	var m runtime.MemStats
@@ -212,74 +185,242 @@ func getMemStats() (float64, float64) {
 	return float64(m.Alloc) / 1024 / 1024, float64(m.Sys) / 1024 / 1024
 }
 
-// 性能测试
-func runPerformanceTest() {
-	fmt.Println("=== Go 性能测试 ===\n")
+// runPerformanceTest 运行全部测试，返回每一项的机器可读结果。
+// 除内存测试外，每项测试按 config 预热后多次采样，报告中位数及其统计。
+// 生成的源码只取决于 seed。deadline 大于 0 时另外以此为时限运行一次并发处理
+func runPerformanceTest(config MeasureConfig, seed uint32, deadline time.Duration) []BenchmarkResult {
+	fmt.Println("=== Go 性能测试 ===")
+	fmt.Printf("每项测试预热 %d 次，采样 %d 次，随机种子 %d\n", config.Warmup, config.Samples, seed)
+	fmt.Println()
 
 	checker := NewTypeChecker()
+	var results []BenchmarkResult
 
 	// 1. AST 遍历测试
 	fmt.Println("1. AST 节点遍历测试")
-	ast := generateAST(6, 4) // 深度6，每层4个子节点
+	rng := rand.New(rand.NewSource(int64(seed)))
+	src := generateSource(32, 20, rng.Intn) // 32 个函数，每个函数 20 条语句
+
+	sourceParams := map[string]interface{}{"functions": 32, "statementsPerFunction": 20, "seed": seed}
+	var ast *ASTNode
+	var parseErrors []*ParseError
+	parseResult := measureBenchmark("parse", sourceParams, config, nil, func() {
+		ast, parseErrors = parseSource("bench.ts", src)
+	})
+	parseResult.Metrics = map[string]float64{"sourceBytes": float64(len(src))}
+	if len(parseErrors) > 0 {
+		panic(fmt.Sprintf("生成的源码解析失败: %v", parseErrors[0]))
+	}
 
-	astStart := time.Now()
-	nodeCount := checker.visitNode(ast)
-	astTime := time.Since(astStart)
+	// 每次采样使用新的检查器，避免类型推断缓存影响后续采样
+	var astChecker *TypeChecker
+	nodeCount := 0
+	astResult := measureBenchmark("ast-traversal", sourceParams, config, func() {
+		astChecker = NewTypeChecker()
+	}, func() {
+		nodeCount = astChecker.visitNode(ast)
+	})
+	astResult.Metrics = map[string]float64{
+		"nodes":       float64(nodeCount),
+		"diagnostics": float64(len(astChecker.diagnostics)),
+	}
 
+	// 与 JS/TS 版本相同的工作负载：同一种子、深度 6、每个节点 4 个子节点的 AST，做与 JS/TS 的 visitNode 相同的工作，
+	// workload 为 AST 的指纹。生成的 AST 中节点类型和名称是随机的，不经过检查器
+	workloadAST := generateWorkloadAST(newWorkloadRand(seed), 6, 4)
+	workloadParams := map[string]interface{}{"depth": 6, "breadth": 4, "seed": seed, "workload": fingerprintASTs(workloadAST)}
+	workloadNodes := 0
+	workloadResult := measureBenchmark("workload-traversal", workloadParams, config, nil, func() {
+		length := 0
+		workloadNodes = visitWorkloadNode(workloadAST, &length)
+	})
+	workloadResult.Metrics = map[string]float64{"nodes": float64(workloadNodes)}
+	results = append(results, parseResult, astResult, workloadResult)
+
+	fmt.Printf("   源码大小: %d 字节\n", len(src))
+	fmt.Printf("   解析耗时: %s\n", parseResult.Stats)
 	fmt.Printf("   处理节点数: %d\n", nodeCount)
-	fmt.Printf("   耗时: %.2f ms\n\n", float64(astTime.Nanoseconds())/1000000)
+	fmt.Printf("   诊断数: %d\n", len(astChecker.diagnostics))
+	fmt.Printf("   耗时: %s\n", astResult.Stats)
+	fmt.Printf("   共享工作负载（指纹 %s，%d 个节点）: %s\n\n", workloadParams["workload"], workloadNodes, workloadResult.Stats)
 
 	// 2. 符号表测试
 	fmt.Println("2. 符号表查找测试")
 	symbols := generateSymbols(10000)
 
-	// 添加符号到符号表
+	// 按 Scope 把符号放入嵌套的作用域中：Scope 为 0 的在全局作用域，
+	// 其余每个层级进入一个新的函数作用域
+	depth := 0
 	for _, symbol := range symbols {
-		checker.addSymbol(symbol.Name, symbol.Type, symbol.Scope)
+		for depth < symbol.Scope {
+			checker.enterScope(FunctionScope, fmt.Sprintf("scope_%d", depth+1))
+			depth++
+		}
+		checker.declareSymbol(symbol)
+	}
+	// 在最内层的块级作用域中遮蔽一部分外层符号
+	checker.enterScope(BlockScope, "shadow")
+	depth++
+	for i := 0; i < 100; i++ {
+		checker.addSymbol(fmt.Sprintf("symbol_%d", i*100), TypeString)
 	}
 
-	symbolStart := time.Now()
 	foundCount := 0
-	for i := 0; i < 50000; i++ {
-		symbolName := fmt.Sprintf("symbol_%d", i%10000)
-		if checker.resolveSymbol(symbolName) != nil {
-			foundCount++
+	shadowedCount := 0
+	scopeWalk := 0
+	symbolResult := measureBenchmark("symbol-lookup", map[string]interface{}{"symbols": 10000, "lookups": 50000}, config, func() {
+		foundCount, shadowedCount, scopeWalk = 0, 0, 0
+	}, func() {
+		for i := 0; i < 50000; i++ {
+			symbolName := fmt.Sprintf("symbol_%d", i%10000)
+			if _, scope := checker.lookupSymbol(symbolName); scope != nil {
+				foundCount++
+				scopeWalk += depth - scope.depth
+				if scope.depth == depth {
+					shadowedCount++
+				}
+			}
 		}
+	})
+	symbolResult.Metrics = map[string]float64{
+		"found":      float64(foundCount),
+		"shadowed":   float64(shadowedCount),
+		"scopeDepth": float64(depth),
+	}
+	results = append(results, symbolResult)
+	scopeDepth := depth
+
+	for ; depth > 0; depth-- {
+		checker.exitScope()
 	}
-	symbolTime := time.Since(symbolStart)
 
 	fmt.Printf("   查找次数: 50000\n")
-	fmt.Printf("   找到符号: %d\n", foundCount)
-	fmt.Printf("   耗时: %.2f ms\n\n", float64(symbolTime.Nanoseconds())/1000000)
+	fmt.Printf("   找到符号: %d（其中 %d 次命中遮蔽符号）\n", foundCount, shadowedCount)
+	fmt.Printf("   作用域深度: %d，平均向外查找层数: %.2f\n", scopeDepth, float64(scopeWalk)/float64(foundCount))
+	fmt.Printf("   耗时: %s\n\n", symbolResult.Stats)
 
 	// 3. 批量文件处理测试（单线程）
 	fmt.Println("3. 批量文件处理测试（单线程）")
 	files := make([]*ASTNode, 10)
-	for i := 0; i < 10; i++ {
-		files[i] = generateAST(5, 3)
+	for i := range files {
+		files[i] = generateParsedAST(fmt.Sprintf("file_%d.ts", i), 2, 20, rng)
 	}
 
-	batchStart := time.Now()
-	totalNodes := checker.processFiles(files)
-	batchTime := time.Since(batchStart)
+	batchParams := map[string]interface{}{"files": len(files), "functionsPerFile": 2, "statementsPerFunction": 20, "seed": seed}
+	var batchChecker *TypeChecker
+	var batchDiagnostics *DiagnosticCollector
+	totalNodes := 0
+	prepareBatch := func() {
+		batchChecker = NewTypeChecker()
+		batchDiagnostics = NewDiagnosticCollector()
+	}
+	batchResult := measureBenchmark("batch-single-thread", batchParams, config, prepareBatch, func() {
+		totalNodes = batchChecker.processFiles(files, batchDiagnostics)
+	})
+	batchResult.Metrics = map[string]float64{
+		"nodes":       float64(totalNodes),
+		"diagnostics": float64(batchDiagnostics.len()),
+	}
+	singleFingerprint := batchDiagnostics.fingerprint()
+
+	// 与 JS/TS 版本相同的 10 个文件：第 i 个文件使用派生的种子，深度 5、每个节点 3 个子节点
+	workloadFiles := make([]*ASTNode, 10)
+	for i := range workloadFiles {
+		workloadFiles[i] = generateWorkloadAST(newWorkloadRand(deriveSeed(seed, i)), 5, 3)
+	}
+	workloadBatchParams := map[string]interface{}{"files": len(workloadFiles), "seed": seed, "workload": fingerprintASTs(workloadFiles...)}
+	workloadTotal := 0
+	workloadBatchResult := measureBenchmark("workload-batch-single-thread", workloadBatchParams, config, nil, func() {
+		length := 0
+		workloadTotal = 0
+		for _, file := range workloadFiles {
+			workloadTotal += visitWorkloadNode(file, &length)
+		}
+	})
+	workloadBatchResult.Metrics = map[string]float64{"nodes": float64(workloadTotal)}
+	results = append(results, batchResult, workloadBatchResult)
 
 	fmt.Printf("   处理文件数: %d\n", len(files))
 	fmt.Printf("   总节点数: %d\n", totalNodes)
-	fmt.Printf("   耗时: %.2f ms\n\n", float64(batchTime.Nanoseconds())/1000000)
+	fmt.Printf("   诊断: %s（摘要 %s）\n", batchDiagnostics.summary(), singleFingerprint)
+	fmt.Printf("   耗时: %s\n", batchResult.Stats)
+	fmt.Printf("   共享工作负载（指纹 %s，%d 个节点）: %s\n\n", workloadBatchParams["workload"], workloadTotal, workloadBatchResult.Stats)
 
 	// 4. 并发处理测试
 	fmt.Println("4. 批量文件处理测试（并发）")
-	concurrentStart := time.Now()
-	totalNodesConcurrent := checker.processFilesConcurrent(files)
-	concurrentTime := time.Since(concurrentStart)
+	totalNodesConcurrent := 0
+	concurrentResult := measureBenchmark("batch-concurrent", batchParams, config, prepareBatch, func() {
+		totalNodesConcurrent, _ = batchChecker.processFilesConcurrent(context.Background(), files, batchDiagnostics)
+	})
+	concurrentResult.Metrics = map[string]float64{
+		"nodes":       float64(totalNodesConcurrent),
+		"diagnostics": float64(batchDiagnostics.len()),
+		"speedup":     batchResult.WallTimeMs / concurrentResult.WallTimeMs,
+	}
+	results = append(results, concurrentResult)
 
 	fmt.Printf("   处理文件数: %d\n", len(files))
 	fmt.Printf("   总节点数: %d\n", totalNodesConcurrent)
-	fmt.Printf("   耗时: %.2f ms\n", float64(concurrentTime.Nanoseconds())/1000000)
-	fmt.Printf("   并发提升: %.2fx\n\n", float64(batchTime.Nanoseconds())/float64(concurrentTime.Nanoseconds()))
+	fmt.Printf("   诊断: %s（摘要 %s）\n", batchDiagnostics.summary(), batchDiagnostics.fingerprint())
+	if singleFingerprint != batchDiagnostics.fingerprint() {
+		fmt.Println("   警告: 并发处理的诊断结果与单线程不一致")
+	}
+	fmt.Printf("   耗时: %s\n", concurrentResult.Stats)
+	fmt.Printf("   并发提升: %s\n", speedupString(batchResult.Stats, concurrentResult.Stats))
+
+	// 限时检查：部分结果的诊断应当等于完整结果中已完成文件的诊断
+	if deadline > 0 {
+		full := batchDiagnostics
+		partial := NewDiagnosticCollector()
+		ctx, cancel := context.WithTimeout(context.Background(), deadline)
+		run := startBenchmark("batch-deadline", map[string]interface{}{"files": len(files), "seed": seed, "deadlineMs": float64(deadline.Nanoseconds()) / 1000000})
+		_, check := batchChecker.processFilesConcurrent(ctx, files, partial)
+		cancel()
+		results = append(results, run.stop(map[string]float64{
+			"completed":   float64(len(check.Completed)),
+			"total":       float64(check.Total),
+			"diagnostics": float64(partial.len()),
+		}))
+		fmt.Printf("   限时 %s: %s\n", deadline, check)
+		if filterDiagnostics(full, check.Completed).fingerprint() != partial.fingerprint() {
+			fmt.Println("   警告: 部分结果的诊断与完整检查不一致")
+		}
+	}
 
-	// 5. 内存使用测试
-	fmt.Println("5. 内存使用测试")
+	// 同样的并发度，用固定数量 worker 的任务池代替每个文件一个 goroutine
+	poolParams := map[string]interface{}{"files": len(files), "seed": seed, "pool": StrategySteal.String()}
+	totalNodesPool := 0
+	poolResult := measureBenchmark("batch-worker-pool", poolParams, config, prepareBatch, func() {
+		totalNodesPool = batchChecker.processFilesPool(files, batchDiagnostics, true)
+	})
+	poolResult.Metrics = map[string]float64{
+		"nodes":       float64(totalNodesPool),
+		"diagnostics": float64(batchDiagnostics.len()),
+		"speedup":     batchResult.WallTimeMs / poolResult.WallTimeMs,
+	}
+	results = append(results, poolResult)
+	if singleFingerprint != batchDiagnostics.fingerprint() {
+		fmt.Println("   警告: 任务池处理的诊断结果与单线程不一致")
+	}
+	fmt.Printf("   任务池（工作窃取）耗时: %s\n", poolResult.Stats)
+	fmt.Printf("   任务池相对每文件一个 goroutine: %s\n\n", speedupString(concurrentResult.Stats, poolResult.Stats))
+
+	// 5. 类型检查诊断示例（对应 type-checking-test/sum.ts）
+	fmt.Println("5. 类型检查诊断示例")
+	var diagnostics []*Diagnostic
+	diagResult := measureBenchmark("diagnostics-sample", nil, config, nil, func() {
+		diagnostics = checkSource(diagnosticsSample)
+	})
+	diagResult.Metrics = map[string]float64{"diagnostics": float64(len(diagnostics))}
+	results = append(results, diagResult)
+
+	writeDiagnostics(os.Stdout, diagnostics, "   ", 0)
+	fmt.Printf("   诊断数: %d\n", len(diagnostics))
+	fmt.Printf("   耗时: %s\n\n", diagResult.Stats)
+
+	// 6. 内存使用测试：测量的是堆内存增长，只运行一次
+	fmt.Println("6. 内存使用测试")
+	memoryRun := startBenchmark("memory", map[string]interface{}{"objects": 100000})
 	allocBefore, _ := getMemStats()
 
 	// 创建大量对象
@@ -294,23 +435,132 @@ func runPerformanceTest() {
 
 	runtime.GC() // 强制垃圾回收
 	allocAfter, _ := getMemStats()
+	results = append(results, memoryRun.stop(map[string]float64{"heapGrowthMB": allocAfter - allocBefore}))
 
 	fmt.Printf("   创建对象数: 100000\n")
 	fmt.Printf("   堆内存增长: %.2f MB\n\n", allocAfter-allocBefore)
 
-	// 总结
+	// 7. 转到定义与查找引用：检查批量文件时建立引用索引，然后在每个标识符的位置查询定义，
+	// 对每个有声明的符号查询引用
+	fmt.Println("7. 转到定义与查找引用")
+	var index *ReferenceIndex
+	indexResult := measureBenchmark("reference-index", batchParams, config, nil, func() {
+		index = NewReferenceIndex()
+		checker := NewTypeChecker()
+		checker.references = index
+		checker.processFiles(files, NewDiagnosticCollector())
+	})
+	indexResult.Metrics = map[string]float64{"overhead": indexResult.WallTimeMs / batchResult.WallTimeMs}
+
+	type queryPosition struct {
+		file string
+		pos  Position
+	}
+	var positions []queryPosition
+	for _, file := range files {
+		for _, ref := range index.Identifiers(file.Name) {
+			positions = append(positions, queryPosition{file.Name, ref.Node.Pos})
+		}
+	}
+	var declared []*Symbol
+	for _, symbol := range index.Symbols() {
+		if symbol.Declaration != nil {
+			declared = append(declared, symbol)
+		}
+	}
+
+	resolved := 0
+	definitionResult := measureBenchmark("query-definition", batchParams, config, nil, func() {
+		resolved = 0
+		for _, q := range positions {
+			if index.DefinitionAt(q.file, q.pos) != nil {
+				resolved++
+			}
+		}
+	})
+	definitionResult.Metrics = map[string]float64{
+		"queries":    float64(len(positions)),
+		"resolved":   float64(resolved),
+		"usPerQuery": definitionResult.WallTimeMs * 1000 / float64(max(len(positions), 1)),
+	}
+	referenceCount := 0
+	referencesResult := measureBenchmark("query-references", batchParams, config, nil, func() {
+		referenceCount = 0
+		for _, symbol := range declared {
+			referenceCount += len(index.References(symbol))
+		}
+	})
+	referencesResult.Metrics = map[string]float64{
+		"queries":    float64(len(declared)),
+		"references": float64(referenceCount),
+		"usPerQuery": referencesResult.WallTimeMs * 1000 / float64(max(len(declared), 1)),
+	}
+	results = append(results, indexResult, definitionResult, referencesResult)
+
+	fmt.Printf("   建立索引的检查耗时: %s（不建索引的 %.2fx）\n", indexResult.Stats, indexResult.Metrics["overhead"])
+	fmt.Printf("   转到定义: %d 个位置，%d 个解析到声明，%s，每次 %.2f µs\n",
+		len(positions), resolved, definitionResult.Stats, definitionResult.Metrics["usPerQuery"])
+	fmt.Printf("   查找引用: %d 个符号，共 %d 处，%s，每次 %.2f µs\n",
+		len(declared), referenceCount, referencesResult.Stats, referencesResult.Metrics["usPerQuery"])
+	if len(positions) > 0 {
+		q := positions[len(positions)-1]
+		if symbol := index.DefinitionAt(q.file, q.pos); symbol != nil && symbol.Declaration != nil {
+			fmt.Printf("   示例: %s(%d,%d) 的 %s 定义于 %s(%d,%d)，共 %d 处声明和引用\n", q.file, q.pos.Line, q.pos.Column,
+				symbol.Name, index.DefinitionFile(symbol), symbol.Declaration.Pos.Line, symbol.Declaration.Pos.Column, len(index.References(symbol)))
+		}
+	}
+	fmt.Println()
+
+	// 总结（中位数）
 	fmt.Println("=== 总结 ===")
-	fmt.Printf("AST 遍历: %.2f ms\n", float64(astTime.Nanoseconds())/1000000)
-	fmt.Printf("符号查找: %.2f ms\n", float64(symbolTime.Nanoseconds())/1000000)
-	fmt.Printf("批量处理（单线程）: %.2f ms\n", float64(batchTime.Nanoseconds())/1000000)
-	fmt.Printf("批量处理（并发）: %.2f ms\n", float64(concurrentTime.Nanoseconds())/1000000)
+	fmt.Printf("源码解析: %.2f ms\n", parseResult.WallTimeMs)
+	fmt.Printf("AST 遍历: %.2f ms\n", astResult.WallTimeMs)
+	fmt.Printf("符号查找: %.2f ms\n", symbolResult.WallTimeMs)
+	fmt.Printf("批量处理（单线程）: %.2f ms\n", batchResult.WallTimeMs)
+	fmt.Printf("批量处理（并发）: %.2f ms\n", concurrentResult.WallTimeMs)
+	fmt.Printf("批量处理（任务池）: %.2f ms\n", poolResult.WallTimeMs)
 	fmt.Printf("内存使用: %.2f MB\n", allocAfter-allocBefore)
+	return results
 }
 
 func main() {
-	// 设置随机种子
-	rand.Seed(time.Now().UnixNano())
+	jsonOutput := flag.Bool("json", false, "在标准输出输出 JSON 格式的结果，文字报告改写到标准错误")
+	warmup := flag.Int("warmup", 2, "每项测试的预热次数")
+	samples := flag.Int("samples", 10, "每项测试的采样次数")
+	seed := flag.Uint("seed", 1, "生成测试源码和工作负载的随机种子（32 位），相同种子在 Go/JS/TS 中生成相同的工作负载")
+	profilePhases := flag.String("profile", "", "逗号分隔的测试名（例如 parse,batch-concurrent），或 all；只剖析这些测试")
+	profileDir := flag.String("profile-dir", "profiles", "剖析文件的输出目录")
+	profileKindList := flag.String("profile-kinds", "cpu,heap,allocs,mutex,block", "采集的剖析类型")
+	deadline := flag.Duration("deadline", 0, "大于 0 时，并发处理测试后再以此为时限（例如 5ms）运行一次，报告部分结果")
+	flag.Parse()
+
+	// JSON 模式下标准输出只保留 JSON，其余输出全部转到标准错误
+	stdout := os.Stdout
+	if *jsonOutput {
+		os.Stdout = os.Stderr
+	}
+
+	if *seed > math.MaxUint32 { // JS/TS 以 >>> 0 取 32 位种子，更大的值在各语言中不再对应同一工作负载
+		fmt.Fprintf(os.Stderr, "-seed: 种子 %d 超出 32 位无符号整数的范围\n", *seed)
+		os.Exit(2)
+	}
+
+	profiler, err := NewProfiler(*profileDir, *profilePhases, *profileKindList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
 	// 运行性能测试
-	runPerformanceTest()
-}
\ No newline at end of file
+	results := runPerformanceTest(MeasureConfig{Warmup: *warmup, Samples: *samples, Profile: profiler}, uint32(*seed), *deadline)
+	if profiler != nil && profiler.Written() == 0 {
+		fmt.Fprintf(os.Stderr, "警告: -profile %s 没有匹配任何测试\n", *profilePhases)
+	}
+
+	if *jsonOutput {
+		if err := writeResultsJSON(stdout, results); err != nil {
+			fmt.Fprintln(os.Stderr, "输出 JSON 失败:", err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 语料目录：把生成的项目写成真实的 .ts 文件和一个 manifest.json，
+// Go、JavaScript 和 TypeScript 的大规模测试都可以读取同一份文件，读取本身也计入测量。
+//
+// 目录结构：
+//
+//	<dir>/manifest.json
+//	<dir>/src/file_0.ts
+//	<dir>/src/file_1.ts
+//	...
+//
+// 文件内容是合法的 TypeScript，但格式固定（见 renderCorpusFile），读取时按行解析。
+// src/large-scale-test.js 和 src/large-scale-test.ts 中的读取逻辑与这里一致，修改格式时需要同步
+
+const (
+	corpusManifestName = "manifest.json"
+	corpusVersion      = 1
+)
+
+// CorpusManifest 语料目录的描述
+type CorpusManifest struct {
+	Version        int                  `json:"version"`
+	Seed           uint32               `json:"seed"`
+	Workload       string               `json:"workload"` // 全部文件 AST 的指纹，读取后用于核对
+	Graph          string               `json:"graph"`
+	ImportsPerFile int                  `json:"importsPerFile"`
+	MissingRate    float64              `json:"missingRate"`
+	Files          []CorpusManifestFile `json:"files"`
+}
+
+// CorpusManifestFile 语料中的一个文件，Path 相对于语料目录，使用 / 分隔
+type CorpusManifestFile struct {
+	Path string `json:"path"`
+	Size int    `json:"size"`
+}
+
+// writeCorpus 把项目写入 dir，manifest 中除 Files 以外的字段由调用方填写
+func writeCorpus(project *LargeProject, dir string, manifest CorpusManifest) error {
+	manifest.Version = corpusVersion
+	manifest.Files = make([]CorpusManifestFile, len(project.Files))
+	for i, file := range project.Files {
+		content := renderCorpusFile(file)
+
+		target := filepath.Join(dir, filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, content, 0o644); err != nil {
+			return err
+		}
+		manifest.Files[i] = CorpusManifestFile{Path: file.Path, Size: len(content)}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, corpusManifestName), append(data, '\n'), 0o644)
+}
+
+// renderCorpusFile 生成一个文件的源码：import 语句、文件声明的类型、导出符号，
+// 以及以前序遍历展开的模拟 AST（每个元素为 [类型编号, 名称, 子节点数]）
+func renderCorpusFile(file *SourceFile) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// %s：由 large-scale-test.go -write-corpus 生成，请勿手动修改\n", path.Base(file.Path))
+
+	if len(file.Imports) > 0 {
+		b.WriteString("\n")
+	}
+	for _, decl := range file.Imports {
+		fmt.Fprintf(&b, "import { %s } from %q;\n", strings.Join(decl.Names, ", "), importSpecifier(file.Path, decl.From))
+	}
+
+	for _, typeInfo := range file.Types {
+		fmt.Fprintf(&b, "\nexport interface %s%s {\n", typeInfo.Name, typeInfo.typeParamsText())
+		properties := make([]string, 0, len(typeInfo.Properties))
+		for name := range typeInfo.Properties {
+			properties = append(properties, name)
+		}
+		sort.Strings(properties)
+		for _, name := range properties {
+			fmt.Fprintf(&b, "  %s: %s;\n", name, typeInfo.Properties[name])
+		}
+		for _, method := range typeInfo.Methods {
+			fmt.Fprintf(&b, "  %s(): void;\n", method)
+		}
+		b.WriteString("}\n")
+	}
+
+	b.WriteString("\n")
+	for _, symbol := range file.Symbols {
+		if symbol.Type == "function" {
+			fmt.Fprintf(&b, "export function %s(): void {}\n", symbol.Name)
+		} else {
+			fmt.Fprintf(&b, "export let %s: number = 0;\n", symbol.Name)
+		}
+	}
+
+	b.WriteString("\nexport const ast: [number, string, number][] = [\n")
+	var writeNode func(node *ASTNode)
+	writeNode = func(node *ASTNode) {
+		fmt.Fprintf(&b, "  [%d, %q, %d],\n", int(node.Kind), node.Name, len(node.Children))
+		for _, child := range node.Children {
+			writeNode(child)
+		}
+	}
+	writeNode(file.AST)
+	b.WriteString("];\n")
+	return b.Bytes()
+}
+
+// importSpecifier 返回 from 导入 to 时使用的相对模块路径，例如 "./file_3"
+func importSpecifier(from, to string) string {
+	rel := strings.TrimSuffix(to, ".ts")
+	if dir := path.Dir(from) + "/"; strings.HasPrefix(rel, dir) {
+		return "./" + strings.TrimPrefix(rel, dir)
+	}
+	return rel
+}
+
+// resolveSpecifier 把相对模块路径解析为项目内的文件路径
+func resolveSpecifier(from, specifier string) string {
+	return path.Join(path.Dir(from), specifier) + ".ts"
+}
+
+// findCorpusDirs 返回 root 下的语料目录：root 本身有 manifest.json 时只返回 root，
+// 否则返回包含 manifest.json 的直接子目录，按文件数排序
+func findCorpusDirs(root string) ([]string, error) {
+	if _, err := os.Stat(filepath.Join(root, corpusManifestName)); err == nil {
+		return []string{root}, nil
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	type corpusDir struct {
+		dir   string
+		files int
+	}
+	var found []corpusDir
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		manifest, err := readCorpusManifest(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, corpusDir{dir: dir, files: len(manifest.Files)})
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%s 中没有找到语料（%s）", root, corpusManifestName)
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].files < found[j].files })
+	dirs := make([]string, len(found))
+	for i, c := range found {
+		dirs[i] = c.dir
+	}
+	return dirs, nil
+}
+
+// readCorpusManifest 读取 dir/manifest.json
+func readCorpusManifest(dir string) (*CorpusManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, corpusManifestName))
+	if err != nil {
+		return nil, err
+	}
+	var manifest CorpusManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("%s: %v", filepath.Join(dir, corpusManifestName), err)
+	}
+	if manifest.Version != corpusVersion {
+		return nil, fmt.Errorf("%s: 不支持的语料版本 %d（当前为 %d）", filepath.Join(dir, corpusManifestName), manifest.Version, corpusVersion)
+	}
+	return &manifest, nil
+}
+
+// loadCorpus 读取并解析 manifest 列出的全部文件，重建与 createLargeProject 相同结构的项目
+func loadCorpus(dir string, manifest *CorpusManifest, symbolTable SymbolTableKind) (*LargeProject, error) {
+	project := newLargeProject(len(manifest.Files), symbolTable)
+	for i, entry := range manifest.Files {
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(entry.Path)))
+		if err != nil {
+			return nil, err
+		}
+		if len(content) != entry.Size {
+			return nil, fmt.Errorf("%s: 文件大小 %d 与 manifest 中的 %d 不一致", entry.Path, len(content), entry.Size)
+		}
+		file, err := parseCorpusFile(entry.Path, content)
+		if err != nil {
+			return nil, err
+		}
+		project.GlobalSymbols.Define(file.Symbols, file.Types)
+		project.Files[i] = file
+		project.fileByPath[file.Path] = file
+	}
+
+	for _, file := range project.Files {
+		for _, decl := range file.Imports {
+			project.Dependencies[file.Path] = append(project.Dependencies[file.Path], decl.From)
+		}
+		assignSyntheticPositions(file.AST)
+	}
+	for _, file := range project.Files {
+		checkIncrementalChanges(file, project.GlobalSymbols)
+	}
+	return project, nil
+}
+
+// parseCorpusFile 按 renderCorpusFile 的格式逐行解析一个文件
+func parseCorpusFile(filePath string, content []byte) (*SourceFile, error) {
+	file := &SourceFile{
+		Path:    filePath,
+		Content: content,
+		Size:    len(content),
+		exports: make(map[string]*Symbol),
+	}
+	var typeInfo *TypeInfo
+	var rows []corpusASTRow
+
+	const (
+		stateTop = iota
+		stateInterface
+		stateAST
+	)
+	state := stateTop
+	lineNo := 0
+	fail := func(format string, args ...interface{}) (*SourceFile, error) {
+		return nil, fmt.Errorf("%s:%d: %s", filePath, lineNo, fmt.Sprintf(format, args...))
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		lineNo++
+		line = strings.TrimSpace(line)
+
+		switch state {
+		case stateInterface:
+			if line == "}" {
+				state = stateTop
+			} else if name, ok := strings.CutSuffix(line, "(): void;"); ok {
+				typeInfo.Methods = append(typeInfo.Methods, name)
+			} else if name, typ, ok := strings.Cut(strings.TrimSuffix(line, ";"), ": "); ok {
+				typeInfo.Properties[name] = typ
+			} else {
+				return fail("无法解析的类型成员 %q", line)
+			}
+			continue
+		case stateAST:
+			if line == "];" {
+				state = stateTop
+				continue
+			}
+			row, err := parseCorpusASTRow(line)
+			if err != nil {
+				return fail("%v", err)
+			}
+			rows = append(rows, row)
+			continue
+		}
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "//"):
+		case strings.HasPrefix(line, "import {"):
+			names, specifier, ok := strings.Cut(strings.TrimPrefix(line, "import {"), "} from ")
+			if !ok {
+				return fail("无法解析的 import %q", line)
+			}
+			specifier, err := strconv.Unquote(strings.TrimSuffix(specifier, ";"))
+			if err != nil {
+				return fail("无法解析的模块路径 %q", line)
+			}
+			decl := &ImportDeclaration{From: resolveSpecifier(filePath, specifier)}
+			for _, name := range strings.Split(names, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					decl.Names = append(decl.Names, name)
+				}
+			}
+			file.Imports = append(file.Imports, decl)
+		case strings.HasPrefix(line, "export interface "):
+			name := strings.TrimSuffix(strings.TrimPrefix(line, "export interface "), " {")
+			name, typeParams, generic := strings.Cut(name, "<")
+			typeInfo = &TypeInfo{Name: name, Properties: make(map[string]string)}
+			if generic {
+				typeInfo.TypeParams = parseTypeParamsText(strings.TrimSuffix(typeParams, ">"))
+			}
+			file.Types = append(file.Types, typeInfo)
+			state = stateInterface
+		case strings.HasPrefix(line, "export function "):
+			name, _, _ := strings.Cut(strings.TrimPrefix(line, "export function "), "(")
+			file.addExport(name, "function")
+		case strings.HasPrefix(line, "export let "):
+			name, _, _ := strings.Cut(strings.TrimPrefix(line, "export let "), ":")
+			file.addExport(name, "variable")
+		case strings.HasPrefix(line, "export const ast"):
+			state = stateAST
+		default:
+			return fail("无法解析的语句 %q", line)
+		}
+	}
+	if state != stateTop {
+		return nil, fmt.Errorf("%s: 文件意外结束", filePath)
+	}
+
+	ast, rest, err := buildCorpusAST(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filePath, err)
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%s: AST 之后还有 %d 个多余的节点", filePath, len(rest))
+	}
+	file.AST = ast
+	return file, nil
+}
+
+// addExport 追加一个导出符号，作用域编号与 createLargeProject 相同（每 10 个符号一个作用域）
+func (f *SourceFile) addExport(name, symbolType string) {
+	symbol := &Symbol{Name: name, Type: symbolType, Scope: len(f.Symbols) / 10}
+	f.Symbols = append(f.Symbols, symbol)
+	f.exports[name] = symbol
+}
+
+// corpusASTRow AST 展开形式中的一个元素
+type corpusASTRow struct {
+	kind       NodeKind
+	name       string
+	childCount int
+}
+
+// parseCorpusASTRow 解析形如 [1, "node_abc", 3], 的一行
+func parseCorpusASTRow(line string) (corpusASTRow, error) {
+	inner, hasPrefix := strings.CutPrefix(strings.TrimSuffix(line, ","), "[")
+	inner, hasSuffix := strings.CutSuffix(inner, "]")
+	if !hasPrefix || !hasSuffix {
+		return corpusASTRow{}, fmt.Errorf("无法解析的 AST 节点 %q", line)
+	}
+	fields := strings.Split(inner, ", ")
+	if len(fields) != 3 {
+		return corpusASTRow{}, fmt.Errorf("无法解析的 AST 节点 %q", line)
+	}
+	kind, err1 := strconv.Atoi(fields[0])
+	name, err2 := strconv.Unquote(fields[1])
+	childCount, err3 := strconv.Atoi(fields[2])
+	if err1 != nil || err2 != nil || err3 != nil {
+		return corpusASTRow{}, fmt.Errorf("无法解析的 AST 节点 %q", line)
+	}
+	return corpusASTRow{kind: NodeKind(kind), name: name, childCount: childCount}, nil
+}
+
+// buildCorpusAST 从前序展开的第一个元素重建子树，返回剩余的元素
+func buildCorpusAST(rows []corpusASTRow) (*ASTNode, []corpusASTRow, error) {
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf("AST 节点不足")
+	}
+	row := rows[0]
+	rows = rows[1:]
+	// 每个子节点至少占一个元素，子节点数超过剩余的元素时语料已损坏，不按它分配内存
+	if row.childCount < 0 || row.childCount > len(rows) {
+		return nil, nil, fmt.Errorf("AST 节点 %q 的子节点数 %d 无效（剩余 %d 个节点）", row.name, row.childCount, len(rows))
+	}
+	node := &ASTNode{
+		Kind:     row.kind,
+		Name:     row.name,
+		Children: make([]*ASTNode, 0, row.childCount),
+	}
+	for i := 0; i < row.childCount; i++ {
+		child, rest, err := buildCorpusAST(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		child.Parent = node
+		node.Children = append(node.Children, child)
+		rows = rest
+	}
+	return node, rows, nil
+}
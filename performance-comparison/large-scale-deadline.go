@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// deadlineMode 限时检查中的一种处理模式
+type deadlineMode struct {
+	name  string // 与完整测试相同的模式名，例如 concurrent
+	label string
+	run   func(ctx context.Context, diagnostics *DiagnosticCollector) CheckResult
+	full  *DiagnosticCollector // 同一模式完整检查的诊断，用于验证部分结果
+}
+
+// benchmarkDeadline 以 deadline 为时限把每种处理模式各运行一次（模拟编辑器中被新的按键打断的检查），
+// 报告完成的文件数和取消延迟（时限到达到检查返回的时间），
+// 并验证部分结果的诊断与完整检查中已完成文件的诊断完全一致
+func benchmarkDeadline(params map[string]interface{}, deadline time.Duration, modes []deadlineMode) []BenchmarkResult {
+	deadlineParams := map[string]interface{}{"deadlineMs": float64(deadline.Nanoseconds()) / 1000000}
+	for key, value := range params {
+		deadlineParams[key] = value
+	}
+
+	var results []BenchmarkResult
+	var lines []string
+	for _, mode := range modes {
+		diagnostics := NewDiagnosticCollector()
+		ctx, cancel := context.WithTimeout(context.Background(), deadline)
+		deadlineAt, _ := ctx.Deadline()
+		run := startBenchmark("large-scale-deadline-"+mode.name, deadlineParams)
+		result := mode.run(ctx, diagnostics)
+		latency := time.Since(deadlineAt)
+		cancel()
+
+		metrics := map[string]float64{
+			"completed":   float64(len(result.Completed)),
+			"total":       float64(result.Total),
+			"diagnostics": float64(diagnostics.len()),
+		}
+		line := fmt.Sprintf("  %s: %s", mode.label, result)
+		if result.Err != nil {
+			metrics["cancelLatencyMs"] = float64(latency.Nanoseconds()) / 1000000
+			line += fmt.Sprintf("，取消延迟 %s", latency.Round(time.Microsecond))
+		}
+		results = append(results, run.stop(metrics))
+
+		if filterDiagnostics(mode.full, result.Completed).fingerprint() == diagnostics.fingerprint() {
+			line += fmt.Sprintf("，%d 条诊断与完整检查一致", diagnostics.len())
+		} else {
+			line += "，警告: 部分结果的诊断与完整检查不一致"
+		}
+		lines = append(lines, line)
+	}
+
+	fmt.Printf("\n限时检查（%s）:\n", deadline)
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+	return results
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// 诊断代码：模块解析
+const (
+	DiagModuleHasNoExportedMember = 2305
+	DiagCannotFindModule          = 2307
+)
+
+// GraphShape 依赖图形状
+type GraphShape int
+
+const (
+	GraphNone      GraphShape = iota // 没有 import
+	GraphChain                       // file_i 导入 file_{i-1}，形成一条长链
+	GraphHub                         // 少数 hub 文件被其他所有文件导入（扇入）
+	GraphRandomDAG                   // 每个文件随机导入编号更小的文件，无环
+	GraphCyclic                      // 随机 DAG 之外再加入指向编号更大文件的回边，形成环
+)
+
+var graphShapeNames = map[GraphShape]string{
+	GraphNone:      "none",
+	GraphChain:     "chain",
+	GraphHub:       "hub",
+	GraphRandomDAG: "dag",
+	GraphCyclic:    "cycle",
+}
+
+func (g GraphShape) String() string {
+	return graphShapeNames[g]
+}
+
+// parseGraphShape 解析命令行中的依赖图形状名称
+func parseGraphShape(name string) (GraphShape, error) {
+	for shape, shapeName := range graphShapeNames {
+		if shapeName == name {
+			return shape, nil
+		}
+	}
+	names := make([]string, 0, len(graphShapeNames))
+	for _, shapeName := range graphShapeNames {
+		names = append(names, shapeName)
+	}
+	sort.Strings(names)
+	return GraphNone, fmt.Errorf("未知的依赖图形状 %q（可选: %s）", name, strings.Join(names, ", "))
+}
+
+// DependencyConfig 依赖图生成配置
+type DependencyConfig struct {
+	Shape            GraphShape
+	ImportsPerFile   int     // dag/cycle: 每个文件最多导入的文件数
+	HubCount         int     // hub: hub 文件数量
+	SymbolsPerImport int     // 每条 import 导入的符号数
+	CycleRate        float64 // cycle: 每个文件额外产生一条回边的概率
+	MissingRate      float64 // 每个导入符号被替换为不存在名称的概率，用于产生诊断
+}
+
+// defaultDependencyConfig 默认配置：随机 DAG，每个文件最多导入 3 个文件
+func defaultDependencyConfig() DependencyConfig {
+	return DependencyConfig{
+		Shape:            GraphRandomDAG,
+		ImportsPerFile:   3,
+		HubCount:         5,
+		SymbolsPerImport: 5,
+		CycleRate:        0.05,
+	}
+}
+
+// ImportDeclaration 对应 import { a, b } from "./file_1"
+type ImportDeclaration struct {
+	From  string
+	Names []string
+}
+
+// buildDependencyGraph 按配置为项目生成 import 关系，
+// 同时填充 SourceFile.Imports 和 LargeProject.Dependencies
+func buildDependencyGraph(project *LargeProject, config DependencyConfig, rng *rand.Rand) {
+	n := len(project.Files)
+	for i, file := range project.Files {
+		var targets []int
+		switch config.Shape {
+		case GraphChain:
+			if i > 0 {
+				targets = []int{i - 1}
+			}
+		case GraphHub:
+			if i >= config.HubCount {
+				for h := 0; h < config.HubCount; h++ {
+					targets = append(targets, h)
+				}
+			}
+		case GraphRandomDAG, GraphCyclic:
+			if i > 0 {
+				count := rng.Intn(config.ImportsPerFile + 1)
+				for k := 0; k < count; k++ {
+					targets = append(targets, rng.Intn(i))
+				}
+			}
+			if config.Shape == GraphCyclic && i < n-1 && rng.Float64() < config.CycleRate {
+				targets = append(targets, i+1+rng.Intn(n-i-1))
+			}
+		}
+
+		seen := make(map[int]bool, len(targets))
+		for _, target := range targets {
+			if seen[target] {
+				continue
+			}
+			seen[target] = true
+			addImport(project, file, project.Files[target], config, rng)
+		}
+	}
+}
+
+func addImport(project *LargeProject, from, to *SourceFile, config DependencyConfig, rng *rand.Rand) {
+	decl := &ImportDeclaration{From: to.Path}
+	for k := 0; k < config.SymbolsPerImport && k < len(to.Symbols); k++ {
+		name := to.Symbols[rng.Intn(len(to.Symbols))].Name
+		if rng.Float64() < config.MissingRate {
+			name += "_missing"
+		}
+		decl.Names = append(decl.Names, name)
+	}
+	from.Imports = append(from.Imports, decl)
+	project.Dependencies[from.Path] = append(project.Dependencies[from.Path], to.Path)
+}
+
+// countDependencyEdges 返回 import 边数和导入的符号总数
+func countDependencyEdges(project *LargeProject) (edges, names int) {
+	for _, file := range project.Files {
+		edges += len(file.Imports)
+		for _, decl := range file.Imports {
+			names += len(decl.Names)
+		}
+	}
+	return edges, names
+}
+
+// resolveImports 将文件的每条 import 解析为对被导入文件 Symbols 的查找
+func resolveImports(file *SourceFile, project *LargeProject) []*Diagnostic {
+	var diagnostics []*Diagnostic
+	for _, decl := range file.Imports {
+		target, ok := project.fileByPath[decl.From]
+		if !ok {
+			diagnostics = append(diagnostics, newImportDiagnostic(file, DiagCannotFindModule,
+				"Cannot find module '%s' or its corresponding type declarations.", decl.From))
+			continue
+		}
+		for _, name := range decl.Names {
+			if _, ok := target.exports[name]; !ok {
+				diagnostics = append(diagnostics, newImportDiagnostic(file, DiagModuleHasNoExportedMember,
+					"Module '\"%s\"' has no exported member '%s'.", decl.From, name))
+			}
+		}
+	}
+	return diagnostics
+}
+
+// 生成的文件没有 import 语句的源码位置，诊断统一定位到文件开头
+func newImportDiagnostic(file *SourceFile, code int, format string, args ...interface{}) *Diagnostic {
+	return &Diagnostic{
+		Severity: SeverityError,
+		File:     file.Path,
+		Pos:      Position{Line: 1, Column: 1},
+		End:      Position{Line: 1, Column: 1},
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// AST 布局对比（-ast-bench）：用同样的种子分别生成指针形式的 AST（ASTNode）和扁平 AST（FlatAST），
+// 比较构建时的分配次数、存活堆大小、存活 AST 对垃圾回收的影响和遍历速度。
+// 两种布局分别测量，测量一种时另一种已被回收
+
+// astTraversal 遍历的结果。两种布局的遍历做同样的工作：统计节点数、函数声明数和名称总长度，
+// 并像 visitNode 判断 Block 的父节点那样读取每个节点的父节点类型
+type astTraversal struct {
+	nodes          int
+	functions      int
+	nameBytes      int
+	sameKindParent int // 与父节点类型相同的节点数
+}
+
+func traversePointerAST(node *ASTNode, t *astTraversal) {
+	t.nodes++
+	t.nameBytes += len(node.Name)
+	if node.Kind == FunctionDeclaration {
+		t.functions++
+	}
+	if node.Parent != nil && node.Parent.Kind == node.Kind {
+		t.sameKindParent++
+	}
+	for _, child := range node.Children {
+		traversePointerAST(child, t)
+	}
+}
+
+func traverseFlatAST(a *FlatAST, node NodeIndex, t *astTraversal) {
+	t.nodes++
+	t.nameBytes += len(a.Name(node))
+	kind := a.Kind(node)
+	if kind == FunctionDeclaration {
+		t.functions++
+	}
+	if parent := a.Parent(node); parent != NoNode && a.Kind(parent) == kind {
+		t.sameKindParent++
+	}
+	for child := a.FirstChild(node); child != NoNode; child = a.NextSibling(child) {
+		traverseFlatAST(a, child, t)
+	}
+}
+
+// scanFlatAST 与 traverseFlatAST 做同样的工作，但按下标顺序扫描（节点按前序加入，顺序相同），不需要递归
+func scanFlatAST(a *FlatAST, t *astTraversal) {
+	for i := 0; i < a.Len(); i++ {
+		node := NodeIndex(i)
+		t.nodes++
+		t.nameBytes += len(a.Name(node))
+		kind := a.Kind(node)
+		if kind == FunctionDeclaration {
+			t.functions++
+		}
+		if parent := a.Parent(node); parent != NoNode && a.Kind(parent) == kind {
+			t.sameKindParent++
+		}
+	}
+}
+
+// assignSyntheticFlatPositions 与 assignSyntheticPositions 相同，为扁平 AST 分配位置
+func assignSyntheticFlatPositions(a *FlatAST) {
+	line := 0
+	a.Walk(a.Root(), func(node NodeIndex) bool {
+		a.SetRange(node, Position{Offset: line, Line: line + 1, Column: 1}, Position{})
+		line++
+		return true
+	}, func(node NodeIndex) {
+		a.SetRange(node, a.Pos(node), Position{Offset: line, Line: line + 1, Column: 1})
+	})
+}
+
+// flattenMatches 用 flattenAST 转换第一个文件的指针 AST，检查它与直接生成的扁平 AST 的结构和位置是否相同
+func flattenMatches(seed uint32, astDepth int) bool {
+	pointer := generateWorkloadAST(newWorkloadRand(deriveSeed(seed, 0)), astDepth, 3)
+	assignSyntheticPositions(pointer)
+	converted := flattenAST(pointer)
+	generated := generateWorkloadFlatAST(newWorkloadRand(deriveSeed(seed, 0)), astDepth, 3)
+	assignSyntheticFlatPositions(generated)
+	if converted.Len() != generated.Len() || fingerprintFlatASTs(converted) != fingerprintFlatASTs(generated) {
+		return false
+	}
+	for i := 0; i < converted.Len(); i++ {
+		node := NodeIndex(i)
+		if converted.Pos(node) != generated.Pos(node) || converted.End(node) != generated.End(node) ||
+			converted.Flags(node) != generated.Flags(node) || converted.Parent(node) != generated.Parent(node) {
+			return false
+		}
+	}
+	return true
+}
+
+// astLayout 一种 AST 布局：build 生成项目全部文件的 AST，返回遍历函数（可以有多种）和计算指纹的函数
+type astLayout struct {
+	name  string
+	label string
+	build func() (traversals map[string]func(*astTraversal), fingerprint func() string)
+}
+
+// astLayoutResult 一种布局的测量结果
+type astLayoutResult struct {
+	fingerprint string
+	allocs      uint64  // 构建时的分配次数
+	allocMB     float64 // 构建时分配的内存
+	liveMB      float64 // GC 后存活的堆
+	heapObjects uint64  // GC 后增加的堆对象数
+	gc          BenchmarkResult
+	pauseUs     float64 // 每次 GC 的平均 STW 暂停
+	traversals  map[string]BenchmarkResult
+	traversal   astTraversal
+}
+
+// measureASTLayout 生成一次 AST 并保留，测量构建的分配、存活堆、runtime.GC() 的耗时和 STW 暂停，
+// 以及遍历的耗时；然后再多次构建测量构建耗时
+func measureASTLayout(layout astLayout, params map[string]interface{}, measure MeasureConfig) (astLayoutResult, []BenchmarkResult) {
+	layoutParams := map[string]interface{}{"layout": layout.name}
+	for k, v := range params {
+		layoutParams[k] = v
+	}
+	var result astLayoutResult
+	var before, after runtime.MemStats
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	traversals, fingerprint := layout.build()
+	runtime.ReadMemStats(&after)
+	result.fingerprint = fingerprint()
+	result.allocs = after.Mallocs - before.Mallocs
+	result.allocMB = float64(after.TotalAlloc-before.TotalAlloc) / 1024 / 1024
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	result.liveMB = (float64(after.HeapAlloc) - float64(before.HeapAlloc)) / 1024 / 1024
+	result.heapObjects = after.HeapObjects - min(before.HeapObjects, after.HeapObjects)
+
+	// AST 存活时每次 GC 都要标记它，runtime.GC() 的耗时主要是标记存活堆的时间
+	fmt.Printf("%s: GC...\n", layout.label)
+	runtime.ReadMemStats(&before)
+	result.gc = measureBenchmark("ast-layout-gc", layoutParams, measure, nil, runtime.GC)
+	runtime.ReadMemStats(&after)
+	if gcs := after.NumGC - before.NumGC; gcs > 0 {
+		result.pauseUs = float64(after.PauseTotalNs-before.PauseTotalNs) / float64(gcs) / 1000
+	}
+	result.gc.Metrics = map[string]float64{"liveHeapMB": result.liveMB, "heapObjects": float64(result.heapObjects), "pauseUs": result.pauseUs}
+
+	fmt.Printf("%s: 遍历...\n", layout.label)
+	result.traversals = make(map[string]BenchmarkResult)
+	results := []BenchmarkResult{result.gc}
+	for _, name := range []string{"traverse", "scan"} {
+		traverse := traversals[name]
+		if traverse == nil {
+			continue
+		}
+		var t astTraversal
+		traversal := measureBenchmark("ast-layout-"+name, layoutParams, measure, func() { t = astTraversal{} }, func() { traverse(&t) })
+		traversal.Metrics = map[string]float64{"nodes": float64(t.nodes), "nsPerNode": traversal.WallTimeMs * 1000000 / float64(max(t.nodes, 1))}
+		result.traversals[name] = traversal
+		result.traversal = t
+		results = append(results, traversal)
+	}
+	runtime.KeepAlive(traversals)
+
+	fmt.Printf("%s: 构建...\n", layout.label)
+	traversals = nil
+	build := measureBenchmark("ast-layout-build", layoutParams, measure, func() { traversals = nil }, func() { traversals, _ = layout.build() })
+	build.Metrics = map[string]float64{"allocs": float64(result.allocs), "allocMB": result.allocMB}
+	results = append(results, build)
+
+	fmt.Printf("  构建: %s，%d 次分配（%.2f MB）\n", build.Stats, result.allocs, result.allocMB)
+	fmt.Printf("  存活堆: %.2f MB，%d 个堆对象\n", result.liveMB, result.heapObjects)
+	fmt.Printf("  runtime.GC(): %s，平均 STW 暂停 %.1f µs\n", result.gc.Stats, result.pauseUs)
+	for _, name := range []string{"traverse", "scan"} {
+		if traversal, ok := result.traversals[name]; ok {
+			label := map[string]string{"traverse": "递归遍历", "scan": "顺序扫描"}[name]
+			fmt.Printf("  %s: %s，每个节点 %.1f ns\n", label, traversal.Stats, traversal.Metrics["nsPerNode"])
+		}
+	}
+	return result, results
+}
+
+// benchmarkASTLayouts 对每个文件数比较两种 AST 布局。每个文件的 AST 与大规模测试相同（深度 astDepth，每个节点 3 个子节点）
+func benchmarkASTLayouts(fileCounts []int, astDepth int, seed uint32, measure MeasureConfig) []BenchmarkResult {
+	var results []BenchmarkResult
+	for _, fileCount := range fileCounts {
+		fmt.Printf("测试项目规模: %d 个文件（AST 深度 %d）\n", fileCount, astDepth)
+		fmt.Println("----------------------------------------")
+		params := map[string]interface{}{"files": fileCount, "astDepth": astDepth, "seed": seed}
+
+		pointer := astLayout{name: "pointer", label: "指针 AST（ASTNode）", build: func() (map[string]func(*astTraversal), func() string) {
+			asts := make([]*ASTNode, fileCount)
+			for i := range asts {
+				asts[i] = generateWorkloadAST(newWorkloadRand(deriveSeed(seed, i)), astDepth, 3)
+				assignSyntheticPositions(asts[i])
+			}
+			return map[string]func(*astTraversal){
+				"traverse": func(t *astTraversal) {
+					for _, ast := range asts {
+						traversePointerAST(ast, t)
+					}
+				},
+			}, func() string { return fingerprintASTs(asts...) }
+		}}
+		flat := astLayout{name: "flat", label: "扁平 AST（FlatAST）", build: func() (map[string]func(*astTraversal), func() string) {
+			asts := make([]*FlatAST, fileCount)
+			for i := range asts {
+				asts[i] = generateWorkloadFlatAST(newWorkloadRand(deriveSeed(seed, i)), astDepth, 3)
+				assignSyntheticFlatPositions(asts[i])
+			}
+			return map[string]func(*astTraversal){
+				"traverse": func(t *astTraversal) {
+					for _, ast := range asts {
+						traverseFlatAST(ast, ast.Root(), t)
+					}
+				},
+				"scan": func(t *astTraversal) {
+					for _, ast := range asts {
+						scanFlatAST(ast, t)
+					}
+				},
+			}, func() string { return fingerprintFlatASTs(asts...) }
+		}}
+
+		pointerResult, pointerResults := measureASTLayout(pointer, params, measure)
+		flatResult, flatResults := measureASTLayout(flat, params, measure)
+		results = append(results, pointerResults...)
+		results = append(results, flatResults...)
+
+		fmt.Printf("\n扁平 AST 相对指针 AST:\n")
+		if pointerResult.fingerprint != flatResult.fingerprint || pointerResult.traversal != flatResult.traversal {
+			fmt.Println("  警告: 两种布局生成的 AST 不一致")
+		} else {
+			fmt.Printf("  AST 一致（指纹 %s，%d 个节点）\n", flatResult.fingerprint, flatResult.traversal.nodes)
+		}
+		if !flattenMatches(seed, astDepth) {
+			fmt.Println("  警告: flattenAST 转换的 AST 与直接生成的扁平 AST 不一致")
+		}
+		fmt.Printf("  分配次数: %d -> %d，存活堆: %.2f MB -> %.2f MB，堆对象: %d -> %d\n", pointerResult.allocs, flatResult.allocs,
+			pointerResult.liveMB, flatResult.liveMB, pointerResult.heapObjects, flatResult.heapObjects)
+		fmt.Printf("  runtime.GC(): %s，STW 暂停 %.1f µs -> %.1f µs\n", speedupString(pointerResult.gc.Stats, flatResult.gc.Stats),
+			pointerResult.pauseUs, flatResult.pauseUs)
+		fmt.Printf("  递归遍历: %s\n", speedupString(pointerResult.traversals["traverse"].Stats, flatResult.traversals["traverse"].Stats))
+		fmt.Printf("  顺序扫描相对指针 AST 的递归遍历: %s\n\n", speedupString(pointerResult.traversals["traverse"].Stats, flatResult.traversals["scan"].Stats))
+	}
+	return results
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"math/rand"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// hashContent 计算文件内容的哈希
+func hashContent(file *SourceFile) uint64 {
+	h := fnv.New64a()
+	h.Write(file.Content)
+	return h.Sum64()
+}
+
+// hashSignature 计算文件导出签名的哈希：只包含导出符号的名称和类型，
+// 函数体等内部改动不会改变它。符号类型以全局符号表中的为准，类型已驻留时使用 TypeID
+func hashSignature(file *SourceFile, globalSymbols GlobalSymbolTable) uint64 {
+	h := fnv.New64a()
+	for _, symbol := range file.Symbols {
+		if resolved := resolveSymbolWithGlobal(symbol.Name, globalSymbols); resolved != nil {
+			symbol = resolved
+		}
+		h.Write([]byte(symbol.Name))
+		h.Write([]byte{0})
+		if symbol.TypeID != 0 {
+			h.Write(binary.LittleEndian.AppendUint32(nil, uint32(symbol.TypeID)))
+		} else {
+			h.Write([]byte(symbol.Type))
+		}
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
+// 增量编译检查：记录本次检查时的内容哈希和导出签名哈希，
+// 下次增量检查据此判断文件是否需要重新检查
+func checkIncrementalChanges(file *SourceFile, globalSymbols GlobalSymbolTable) {
+	file.ContentHash = hashContent(file)
+	file.SignatureHash = hashSignature(file, globalSymbols)
+}
+
+// mutateFiles 随机修改 n 个文件的内容，其中约 signatureRate 比例的文件
+// 同时修改一个导出符号的类型（导出签名变化）。返回签名发生变化的文件数
+func mutateFiles(project *LargeProject, n int, signatureRate float64, rng *rand.Rand) int {
+	n = min(n, len(project.Files))
+	signatureChanges := 0
+	for _, i := range rng.Perm(len(project.Files))[:n] {
+		file := project.Files[i]
+		if len(file.Content) > 0 {
+			file.Content[rng.Intn(len(file.Content))]++
+		} else {
+			file.Content = append(file.Content, '\n')
+		}
+
+		// 真实仓库中的文件可能没有导出符号
+		if len(file.Symbols) > 0 && rng.Float64() < signatureRate {
+			symbol := file.Symbols[rng.Intn(len(file.Symbols))]
+			if symbol.Type == "function" {
+				project.setSymbolType(symbol, "variable")
+			} else {
+				project.setSymbolType(symbol, "function")
+			}
+			signatureChanges++
+		}
+	}
+	return signatureChanges
+}
+
+// IncrementalResult 增量检查的统计
+type IncrementalResult struct {
+	Changed          int // 内容发生变化的文件数
+	SignatureChanged int // 重新检查后导出签名发生变化的文件数
+	Rechecked        int // 实际重新检查的文件数（含受影响的依赖方）
+	Waves            int
+	Duration         time.Duration
+	Files            []*SourceFile // 重新检查的文件，按波次顺序
+}
+
+// recheckIncremental 只重新检查内容哈希变化的文件；如果某个文件的导出签名
+// 发生变化，再重新检查直接导入它的文件，如此逐波传播，直到签名不再变化
+func recheckIncremental(project *LargeProject, diagnostics *DiagnosticCollector) IncrementalResult {
+	start := time.Now()
+	result := IncrementalResult{}
+
+	dependents := make(map[string][]*SourceFile)
+	for _, file := range project.Files {
+		for _, dep := range project.Dependencies[file.Path] {
+			dependents[dep] = append(dependents[dep], file)
+		}
+	}
+
+	var wave []*SourceFile
+	for _, file := range project.Files {
+		if hashContent(file) != file.ContentHash {
+			wave = append(wave, file)
+		}
+	}
+	result.Changed = len(wave)
+
+	rechecked := make(map[*SourceFile]bool)
+	for _, file := range wave {
+		rechecked[file] = true
+	}
+
+	semaphore := make(chan struct{}, runtime.NumCPU())
+	for len(wave) > 0 {
+		result.Waves++
+		result.Rechecked += len(wave)
+		result.Files = append(result.Files, wave...)
+
+		changed := make([]bool, len(wave))
+		var wg sync.WaitGroup
+		for i, file := range wave {
+			wg.Add(1)
+			go func(i int, f *SourceFile) {
+				defer wg.Done()
+				ctx, task := startFileTask(context.Background(), f.Path)
+				defer task.End()
+				acquireSemaphore(ctx, semaphore) // Background 不会被取消
+				defer func() { <-semaphore }()
+
+				previous := f.SignatureHash
+				fileDiagnostics, _ := processFileWithDependencies(ctx, f, project)
+				diagnostics.add(fileDiagnostics...)
+				changed[i] = f.SignatureHash != previous
+			}(i, file)
+		}
+		wg.Wait()
+
+		var next []*SourceFile
+		for i, file := range wave {
+			if !changed[i] {
+				continue
+			}
+			result.SignatureChanged++
+			for _, dependent := range dependents[file.Path] {
+				if !rechecked[dependent] {
+					rechecked[dependent] = true
+					next = append(next, dependent)
+				}
+			}
+		}
+		wave = next
+	}
+
+	result.Duration = time.Since(start)
+	return result
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// 类型驻留对比（-intern-bench）：对每个文件数分别生成不驻留和驻留类型的项目，
+// 比较 GC 后的堆大小和类型检查阶段的耗时。类型检查阶段与 processFile 中的相同
+// （每个文件 AST 节点数/10 轮 performTypeCheck），不包括 AST 遍历等与类型无关的步骤
+
+// countTypeNodes 统计项目的类型结构中不同的类型和属性实例数（共享的实例只计一次）
+func countTypeNodes(project *LargeProject) (types, properties int) {
+	seenTypes := make(map[*Type]bool)
+	seenProperties := make(map[*Property]bool)
+	var walk func(t *Type)
+	walkAll := func(list []*Type) {
+		for _, t := range list {
+			walk(t)
+		}
+	}
+	walk = func(t *Type) {
+		if t == nil || seenTypes[t] {
+			return
+		}
+		seenTypes[t] = true
+		for _, property := range t.Properties {
+			if !seenProperties[property] {
+				seenProperties[property] = true
+				walk(property.Type)
+			}
+		}
+		walkAll(t.Params)
+		walk(t.Return)
+		walkAll(t.Members)
+		walkAll(t.TypeParams)
+		walkAll(t.TypeArgs)
+		walk(t.Constraint)
+	}
+	for _, file := range project.Files {
+		for _, typeInfo := range file.Types {
+			walk(typeInfo.Structure())
+		}
+	}
+	return len(seenTypes), len(seenProperties)
+}
+
+// typeChecksPerFile 每个文件的 performTypeCheck 轮数，与 processFile 相同
+func typeChecksPerFile(project *LargeProject) []int {
+	checks := make([]int, len(project.Files))
+	var count func(node *ASTNode) int
+	count = func(node *ASTNode) int {
+		n := 1
+		for _, child := range node.Children {
+			n += count(child)
+		}
+		return n
+	}
+	for i, file := range project.Files {
+		checks[i] = count(file.AST) / 10
+	}
+	return checks
+}
+
+// benchmarkInterning 对每个文件数测量不驻留和驻留两种情况，输出对比
+func benchmarkInterning(fileCounts []int, deps DependencyConfig, seed uint32, symbolTable SymbolTableKind, measure MeasureConfig) []BenchmarkResult {
+	var results []BenchmarkResult
+	for _, fileCount := range fileCounts {
+		fmt.Printf("测试项目规模: %d 个文件\n", fileCount)
+		fmt.Println("----------------------------------------")
+
+		var modes [2]BenchmarkResult
+		for i, intern := range []bool{false, true} {
+			runtime.GC()
+			heapBefore, _ := getMemStats()
+			project := createLargeProject(fileCount, deps, seed, symbolTable)
+			runtime.GC()
+			heapProject, _ := getMemStats()
+
+			// 建立全部类型结构（驻留时再驻留并释放文本形式的成员）
+			start := time.Now()
+			for _, file := range project.Files {
+				for _, typeInfo := range file.Types {
+					typeInfo.Structure()
+				}
+			}
+			if intern {
+				project.internTypes()
+			}
+			build := time.Since(start)
+			runtime.GC()
+			heapTypes, _ := getMemStats()
+			typeNodes, propertyNodes := countTypeNodes(project)
+
+			checks := typeChecksPerFile(project)
+			params := map[string]interface{}{
+				"files":       fileCount,
+				"seed":        seed,
+				"graph":       deps.Shape.String(),
+				"symbolTable": symbolTable.String(),
+				"interned":    intern,
+			}
+			label := map[bool]string{false: "不驻留", true: "驻留"}[intern]
+			fmt.Printf("%s: 类型检查...\n", label)
+			result := measureBenchmark("intern-type-check", params, measure, project.resetTypeRelation, func() {
+				for j, file := range project.Files {
+					performTypeCheck(project, file, checks[j])
+				}
+			})
+			relations := project.Relations.Stats()
+			instantiations := project.Relations.InstantiationStats()
+			result.Metrics = map[string]float64{
+				"heapMB":               heapTypes - heapBefore,
+				"typeHeapMB":           heapTypes - heapProject,
+				"buildMs":              float64(build.Nanoseconds()) / 1000000,
+				"typeNodes":            float64(typeNodes),
+				"propertyNodes":        float64(propertyNodes),
+				"relationHitRate":      relations.HitRate(),
+				"instantiationHitRate": instantiations.HitRate(),
+			}
+			if intern {
+				result.Metrics["internHitRate"] = project.Interner.Stats().HitRate()
+				fmt.Printf("  驻留表: %s\n", project.Interner.Stats())
+			}
+			fmt.Printf("  项目堆大小 %.2f MB（其中类型结构 %+.2f MB），%d 个类型实例、%d 个属性实例，建立耗时 %.2f ms\n",
+				result.Metrics["heapMB"], result.Metrics["typeHeapMB"], typeNodes, propertyNodes, result.Metrics["buildMs"])
+			fmt.Printf("  类型检查: %s，%s\n", result.Stats, relations)
+			fmt.Printf("  泛型实例化: %s\n", instantiations)
+			runtime.KeepAlive(project)
+			modes[i] = result
+			results = append(results, result)
+		}
+
+		plain, interned := modes[0], modes[1]
+		fmt.Printf("\n驻留的效果:\n")
+		fmt.Printf("  项目堆大小: %.2f MB -> %.2f MB（%+.1f%%）\n", plain.Metrics["heapMB"], interned.Metrics["heapMB"],
+			(interned.Metrics["heapMB"]/plain.Metrics["heapMB"]-1)*100)
+		fmt.Printf("  类型实例: %.0f -> %.0f，属性实例: %.0f -> %.0f\n", plain.Metrics["typeNodes"], interned.Metrics["typeNodes"],
+			plain.Metrics["propertyNodes"], interned.Metrics["propertyNodes"])
+		fmt.Printf("  类型检查提升: %s\n", speedupString(plain.Stats, interned.Stats))
+		fmt.Printf("  关系缓存命中率: %.1f%% -> %.1f%%\n\n", plain.Metrics["relationHitRate"]*100, interned.Metrics["relationHitRate"]*100)
+	}
+	return results
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 并发策略对比（-pool-bench）：对同一个项目分别用每个文件一个 goroutine、共享队列的任务池
+// 和工作窃取的任务池运行并发与高并发两种模式，比较耗时、峰值 goroutine 数和内存
+
+// poolBenchmarkFiles 默认测试的文件数
+var poolBenchmarkFiles = []int{50, 200, 500, 10000}
+
+// poolBenchmarkASTDepth 默认的 AST 深度。比大规模测试浅，每个文件的工作量更小，
+// 调度开销占比更高，10000 个文件的项目也能在合理时间内完成
+const poolBenchmarkASTDepth = 3
+
+// runtimeSampler 在后台定期读取 goroutine 数和栈内存，记录峰值。
+// 使用 runtime/metrics，不需要 stop-the-world
+type runtimeSampler struct {
+	samples        []metrics.Sample
+	peakGoroutines uint64
+	peakStackBytes uint64
+	stop           chan struct{}
+	done           chan struct{}
+}
+
+func startRuntimeSampler(interval time.Duration) *runtimeSampler {
+	s := &runtimeSampler{
+		samples: []metrics.Sample{
+			{Name: "/sched/goroutines:goroutines"},
+			{Name: "/memory/classes/heap/stacks:bytes"},
+		},
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.read()
+			select {
+			case <-ticker.C:
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	return s
+}
+
+func (s *runtimeSampler) read() {
+	metrics.Read(s.samples)
+	s.peakGoroutines = max(s.peakGoroutines, s.samples[0].Value.Uint64())
+	s.peakStackBytes = max(s.peakStackBytes, s.samples[1].Value.Uint64())
+}
+
+// finish 停止采样，返回峰值
+func (s *runtimeSampler) finish() (goroutines, stackBytes uint64) {
+	close(s.stop)
+	<-s.done
+	return s.peakGoroutines, s.peakStackBytes
+}
+
+// poolBenchmarkRow 对比表的一行
+type poolBenchmarkRow struct {
+	mode       string
+	strategy   ConcurrencyStrategy
+	result     BenchmarkResult
+	goroutines uint64
+	stackBytes uint64
+}
+
+// benchmarkWorkerPools 对每个文件数和每种并发策略测量并发与高并发两种模式
+func benchmarkWorkerPools(fileCounts []int, astDepth int, deps DependencyConfig, seed uint32, symbolTable SymbolTableKind, measure MeasureConfig) []BenchmarkResult {
+	var results []BenchmarkResult
+	for _, fileCount := range fileCounts {
+		fmt.Printf("测试项目规模: %d 个文件，AST 深度 %d\n", fileCount, astDepth)
+		fmt.Println("----------------------------------------")
+		project := generateLargeProject(fileCount, astDepth, deps, seed, symbolTable)
+
+		var rows []poolBenchmarkRow
+		fingerprints := make(map[string]string)
+		for _, mode := range []string{"concurrent", "high-concurrency"} {
+			for _, strategy := range concurrencyStrategies {
+				params := map[string]interface{}{
+					"files":    fileCount,
+					"seed":     seed,
+					"astDepth": astDepth,
+					"graph":    deps.Shape.String(),
+					"pool":     strategy.String(),
+				}
+				var diagnostics *DiagnosticCollector
+				var schedule ScheduleStats
+				sampler := startRuntimeSampler(time.Millisecond)
+				result := measureBenchmark("pool-"+mode, params, measure, func() {
+					diagnostics = NewDiagnosticCollector()
+				}, func() {
+					if mode == "concurrent" {
+						processProjectConcurrent(context.Background(), project, diagnostics, strategy)
+					} else {
+						_, schedule = processProjectHighConcurrency(context.Background(), project, diagnostics, strategy)
+					}
+				})
+				goroutines, stackBytes := sampler.finish()
+				result.Metrics = map[string]float64{
+					"peakGoroutines": float64(goroutines),
+					"peakStackBytes": float64(stackBytes),
+					"diagnostics":    float64(diagnostics.len()),
+				}
+				if mode == "high-concurrency" && strategy == StrategySteal {
+					result.Metrics["steals"] = float64(schedule.Steals)
+				}
+				results = append(results, result)
+				rows = append(rows, poolBenchmarkRow{mode, strategy, result, goroutines, stackBytes})
+
+				// 同一模式下不同策略的诊断必须一致
+				if previous, ok := fingerprints[mode]; ok && previous != diagnostics.fingerprint() {
+					fmt.Printf("  警告: %s 模式下 %s 策略的诊断与 goroutine 策略不一致\n", mode, strategy)
+				}
+				fingerprints[mode] = diagnostics.fingerprint()
+			}
+		}
+
+		fmt.Printf("\n结果（耗时为中位数，goroutine 数和栈内存为运行期间每毫秒采样的峰值，分配为每次采样的平均值）:\n")
+		for i, row := range rows {
+			fmt.Printf("  %-16s %-9s %8.2f ms，峰值 %d 个 goroutine，栈 %.1f KB，分配 %.2f MB",
+				row.mode, row.strategy, row.result.WallTimeMs, row.goroutines,
+				float64(row.stackBytes)/1024, float64(row.result.AllocBytes)/1024/1024)
+			if row.strategy != StrategyGoroutine {
+				base := rows[i-i%len(concurrencyStrategies)].result.Stats // 同一模式的 goroutine 策略
+				fmt.Printf("，相对 goroutine 策略 %s", speedupString(base, row.result.Stats))
+			}
+			fmt.Println()
+		}
+		fmt.Printf("  CPU 核心数: %d，并发模式 %d 个 worker，高并发模式 %d 个 worker\n\n",
+			runtime.NumCPU(), runtime.NumCPU(), runtime.NumCPU()*4)
+	}
+	return results
+}
+
+// parseIntList 解析逗号分隔的正整数列表，例如 "50,200,500"
+func parseIntList(list string) ([]int, error) {
+	var values []int
+	for _, field := range strings.Split(list, ",") {
+		value, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("无效的数量 %q", field)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ProgressMode 进度的显示方式
+type ProgressMode int
+
+const (
+	ProgressAuto  ProgressMode = iota // 标准输出是终端时同 ProgressTTY，否则同 ProgressPlain
+	ProgressTTY                       // 每 100ms 用 \r 原地刷新一行
+	ProgressPlain                     // 每秒最多输出一行，适合重定向到文件或 CI 日志
+	ProgressQuiet                     // 不输出
+)
+
+var progressModeNames = map[ProgressMode]string{
+	ProgressAuto:  "auto",
+	ProgressTTY:   "tty",
+	ProgressPlain: "plain",
+	ProgressQuiet: "quiet",
+}
+
+func (m ProgressMode) String() string {
+	return progressModeNames[m]
+}
+
+// parseProgressMode 解析命令行中的进度显示方式
+func parseProgressMode(name string) (ProgressMode, error) {
+	for mode, modeName := range progressModeNames {
+		if modeName == name {
+			return mode, nil
+		}
+	}
+	names := make([]string, 0, len(progressModeNames))
+	for _, modeName := range progressModeNames {
+		names = append(names, modeName)
+	}
+	sort.Strings(names)
+	return ProgressAuto, fmt.Errorf("未知的进度显示方式 %q（可选: %s）", name, strings.Join(names, ", "))
+}
+
+// progressMode 所有进度显示使用的方式，由 -progress 设置
+var progressMode = ProgressAuto
+
+// ProgressReporter 显示一项处理的进度、速率和预计剩余时间。
+// Add 可以在任意 goroutine 中调用；刷新由 reporter 自己的 goroutine 完成，只读取原子计数
+type ProgressReporter struct {
+	label string
+	total int64
+	mode  ProgressMode
+	out   io.Writer
+	start time.Time
+
+	done    atomic.Int64
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// NewProgressReporter 开始显示 label 的进度，total 为总数。调用方在处理完成后必须调用 Finish
+func NewProgressReporter(label string, total int) *ProgressReporter {
+	p := &ProgressReporter{
+		label: label,
+		total: int64(total),
+		mode:  progressMode,
+		out:   os.Stdout,
+		start: time.Now(),
+	}
+	if p.mode == ProgressAuto {
+		p.mode = ProgressPlain
+		if isTerminal(os.Stdout) {
+			p.mode = ProgressTTY
+		}
+	}
+	if p.mode == ProgressQuiet {
+		return p
+	}
+
+	interval := time.Second
+	if p.mode == ProgressTTY {
+		interval = 100 * time.Millisecond
+	}
+	p.stop = make(chan struct{})
+	p.stopped = make(chan struct{})
+	go func() {
+		defer close(p.stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.print(false)
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// isTerminal 判断 f 是否为终端（字符设备）
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Add 记录 n 项已完成
+func (p *ProgressReporter) Add(n int) {
+	p.done.Add(int64(n))
+}
+
+// Finish 停止刷新并输出最终的一行（总耗时和平均速率）
+func (p *ProgressReporter) Finish() {
+	if p.mode == ProgressQuiet {
+		return
+	}
+	close(p.stop)
+	<-p.stopped
+	p.print(true)
+}
+
+func (p *ProgressReporter) print(final bool) {
+	done := p.done.Load()
+	elapsed := time.Since(p.start)
+	line := fmt.Sprintf("  %s: %.1f%% (%d/%d)", p.label, float64(done)/float64(max(p.total, 1))*100, done, p.total)
+	rate := float64(done) / elapsed.Seconds()
+	switch {
+	case final:
+		line += fmt.Sprintf("，用时 %s，%.0f 个/秒", elapsed.Round(time.Millisecond), rate)
+	case done > 0 && done < p.total:
+		eta := time.Duration(float64(p.total-done) / rate * float64(time.Second))
+		line += fmt.Sprintf("，%.0f 个/秒，预计剩余 %s", rate, eta.Round(100*time.Millisecond))
+	}
+
+	if p.mode == ProgressTTY {
+		// \033[K 清除上一次刷新残留的较长内容
+		fmt.Fprintf(p.out, "\r%s\033[K", line)
+		if final {
+			fmt.Fprintln(p.out)
+		}
+		return
+	}
+	fmt.Fprintln(p.out, line)
+}
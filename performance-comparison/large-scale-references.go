@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// buildProjectIndex 为整个项目建立转到定义和查找引用的索引：
+//   - 解析得到的 AST（-repo 读取的真实仓库）由检查器记录文件内的声明和标识符引用；
+//   - 文件中找不到声明、但从其他文件导入的标识符，解析到被导入文件的顶层声明；
+//   - 每条 import 中的每个名称记为导入文件对被导入符号的一次引用（没有源码位置）。
+//
+// 生成的项目和语料中的 AST 只是模拟的节点树，不是可检查的源码，因此只有 import 引用。
+// 被导入文件中找不到对应顶层声明的导出（例如生成的符号）以导出符号本身作为定义，定义所在的文件记在索引中
+// （见 DefinitionFile），不修改项目中的符号
+func buildProjectIndex(project *LargeProject) *ReferenceIndex {
+	index := NewReferenceIndex()
+	checker := NewTypeChecker()
+	checker.references = index
+	for _, file := range project.Files {
+		if file.AST != nil && file.AST.Kind == SourceFileNode {
+			checker.fork().visitNode(file.AST)
+		}
+	}
+
+	definition := func(target *SourceFile, name string) *Symbol {
+		if symbol := index.TopLevelSymbol(target.Path, name); symbol != nil {
+			return symbol
+		}
+		symbol := target.exports[name]
+		if symbol != nil {
+			index.addDefinition(target.Path, symbol)
+		}
+		return symbol
+	}
+	for _, file := range project.Files {
+		imported := make(map[string]*Symbol)
+		for _, decl := range file.Imports {
+			target := project.fileByPath[decl.From]
+			if target == nil {
+				continue
+			}
+			for _, name := range decl.Names {
+				if symbol := definition(target, name); symbol != nil {
+					index.addReference(file.Path, nil, symbol)
+					imported[name] = symbol
+				}
+			}
+		}
+		if len(imported) > 0 {
+			index.resolveExternal(file.Path, func(name string) *Symbol { return imported[name] })
+		}
+	}
+	return index
+}
+
+// benchmarkQueries 测量项目引用索引的建立，以及两类查询：
+// 在每个标识符的位置转到定义，对每个有引用的符号查找引用（按文件和名称排序后依次查询）
+func benchmarkQueries(project *LargeProject, params map[string]interface{}, measure MeasureConfig) []BenchmarkResult {
+	fmt.Println("建立引用索引...")
+	var index *ReferenceIndex
+	indexResult := measureBenchmark("large-scale-reference-index", params, measure, nil, func() {
+		index = buildProjectIndex(project)
+	})
+
+	type queryPosition struct {
+		file string
+		pos  Position
+	}
+	var positions []queryPosition
+	unresolved := 0
+	for _, file := range project.Files {
+		for _, ref := range index.Identifiers(file.Path) {
+			positions = append(positions, queryPosition{file.Path, ref.Node.Pos})
+			if ref.Symbol == nil {
+				unresolved++
+			}
+		}
+	}
+	symbols := index.Symbols()
+	definedIn := make(map[*Symbol]string, len(symbols))
+	for _, symbol := range symbols {
+		definedIn[symbol] = index.DefinitionFile(symbol)
+	}
+	sort.Slice(symbols, func(i, j int) bool {
+		if definedIn[symbols[i]] != definedIn[symbols[j]] {
+			return definedIn[symbols[i]] < definedIn[symbols[j]]
+		}
+		return symbols[i].Name < symbols[j].Name
+	})
+	crossFile := 0
+	for _, symbol := range symbols {
+		for _, ref := range index.References(symbol) {
+			if ref.File != definedIn[symbol] {
+				crossFile++
+			}
+		}
+	}
+	indexResult.Metrics = map[string]float64{
+		"identifiers":         float64(len(positions)),
+		"unresolved":          float64(unresolved),
+		"symbols":             float64(len(symbols)),
+		"crossFileReferences": float64(crossFile),
+	}
+	results := []BenchmarkResult{indexResult}
+
+	fmt.Printf("\n引用索引:\n")
+	fmt.Printf("  建立耗时: %s\n", indexResult.Stats)
+	fmt.Printf("  %d 个标识符（%d 个找不到声明），%d 个符号，%d 处跨文件引用\n", len(positions), unresolved, len(symbols), crossFile)
+
+	if len(positions) > 0 {
+		resolved := 0
+		definitionResult := measureBenchmark("large-scale-query-definition", params, measure, nil, func() {
+			resolved = 0
+			for _, q := range positions {
+				if index.DefinitionAt(q.file, q.pos) != nil {
+					resolved++
+				}
+			}
+		})
+		definitionResult.Metrics = map[string]float64{
+			"queries":    float64(len(positions)),
+			"resolved":   float64(resolved),
+			"usPerQuery": definitionResult.WallTimeMs * 1000 / float64(len(positions)),
+		}
+		results = append(results, definitionResult)
+		fmt.Printf("  转到定义: %d 次查询，%d 个解析到定义，%s，每次 %.2f µs\n",
+			len(positions), resolved, definitionResult.Stats, definitionResult.Metrics["usPerQuery"])
+	} else {
+		fmt.Println("  转到定义: 项目中没有可检查的源码（生成的 AST），跳过")
+	}
+
+	if len(symbols) > 0 {
+		references := 0
+		referencesResult := measureBenchmark("large-scale-query-references", params, measure, nil, func() {
+			references = 0
+			for _, symbol := range symbols {
+				references += len(index.References(symbol))
+			}
+		})
+		referencesResult.Metrics = map[string]float64{
+			"queries":    float64(len(symbols)),
+			"references": float64(references),
+			"usPerQuery": referencesResult.WallTimeMs * 1000 / float64(len(symbols)),
+		}
+		results = append(results, referencesResult)
+		fmt.Printf("  查找引用: %d 次查询，共 %d 处，%s，每次 %.2f µs\n",
+			len(symbols), references, referencesResult.Stats, referencesResult.Metrics["usPerQuery"])
+
+		// 引用最多的符号作为示例
+		busiest := symbols[0]
+		for _, symbol := range symbols {
+			if len(index.References(symbol)) > len(index.References(busiest)) {
+				busiest = symbol
+			}
+		}
+		refs := index.References(busiest)
+		files := make(map[string]bool)
+		for _, ref := range refs {
+			files[ref.File] = true
+		}
+		fmt.Printf("  引用最多的符号: %s（定义于 %s），%d 处，分布在 %d 个文件\n", busiest.Name, definedIn[busiest], len(refs), len(files))
+	}
+	return results
+}
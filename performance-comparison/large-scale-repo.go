@@ -0,0 +1,506 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 真实仓库模式：遍历目录中的 .ts/.tsx 文件，用 compiler-parser.go 的解析器构建 AST，
+// 并从词法单元中扫描 import/export，建立与生成项目相同结构的 LargeProject。
+//
+// 解析器只支持 TypeScript 的一个子集：class/interface/enum 的成员、函数表达式和箭头函数的函数体、
+// import 和 namespace 等语句被跳过，遇到不支持的语法时跳到下一个语句边界继续解析，
+// 因此 AST 只覆盖源码的一部分，覆盖率与耗时一起报告；import/export 的扫描不依赖解析器，覆盖常见写法：
+//
+//	import x, { a, b as c } from "./m"    import * as ns from "./m"    import "./m"
+//	export { a, b as c } from "./m"       export * from "./m"          require("./m")
+//	export function|const|let|var|class|interface|type|enum|namespace name
+//	export default ...
+
+// repositorySkipDirs 遍历时跳过的目录（以及所有以 . 开头的目录）
+var repositorySkipDirs = map[string]bool{
+	"node_modules": true,
+	"dist":         true,
+	"build":        true,
+	"out":          true,
+	"coverage":     true,
+}
+
+// RepositoryStats 读取仓库时的统计
+type RepositoryStats struct {
+	Files           int
+	Bytes           int
+	ParseErrors     int // 词法、语法错误总数，主要来自解析器不支持的语法
+	SkippedBytes    int // 没有进入 AST 的源码字节数：被跳过的成员、函数体和语句，以及出错后跳过的部分
+	Imports         int // 解析到项目内文件的 import
+	ExternalImports int // 包名、路径别名等无法在仓库内解析的 import
+	MissingImports  int // 相对路径指向不存在的文件
+}
+
+// repositoryMinCoverage AST 覆盖率低于这个比例时提醒检查结果没有代表性
+const repositoryMinCoverage = 0.5
+
+// astCoverage 进入 AST 的源码占全部源码的比例
+func (s RepositoryStats) astCoverage() float64 {
+	if s.Bytes == 0 {
+		return 0
+	}
+	return float64(s.Bytes-s.SkippedBytes) / float64(s.Bytes)
+}
+
+// moduleImport 扫描到的一条 import 或 re-export
+type moduleImport struct {
+	specifier string
+	names     []string // 具名导入的原名；默认导入、命名空间导入和副作用导入没有名称
+}
+
+// moduleScan 一个文件中扫描到的模块语法
+type moduleScan struct {
+	imports    []moduleImport
+	exports    []*Symbol
+	types      []*TypeInfo // 导出的 class/interface，成员名来自花括号内的第一层
+	exportsAll bool        // 含有 export * from，导出名称无法静态确定
+}
+
+// loadRepository 读取 root 下全部 .ts/.tsx 文件（包括 .d.ts）并解析
+func loadRepository(root string, symbolTable SymbolTableKind) (*LargeProject, RepositoryStats, error) {
+	var stats RepositoryStats
+	var paths []string
+	err := filepath.WalkDir(root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if p != root && (repositorySkipDirs[entry.Name()] || strings.HasPrefix(entry.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(p, ".ts") || strings.HasSuffix(p, ".tsx") {
+			paths = append(paths, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, stats, err
+	}
+	sort.Strings(paths)
+
+	project := newLargeProject(len(paths), symbolTable)
+	scans := make([]*moduleScan, len(paths))
+	exportsAll := make(map[string]bool)
+	for i, p := range paths {
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return nil, stats, err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil, stats, err
+		}
+
+		// 与 parseSource 相同，额外保留词法单元用于扫描 import/export
+		filePath := filepath.ToSlash(rel)
+		lexer := NewLexer(string(content))
+		tokens := lexer.tokenize()
+		parser := NewParser(tokens)
+		parser.ambient = strings.HasSuffix(filePath, ".d.ts") // 声明文件中的声明都没有实现
+		ast := parser.parseSourceFile()
+		ast.Name = filePath
+		stats.ParseErrors += len(lexer.errors) + len(parser.errors)
+		stats.SkippedBytes += parser.skipped
+
+		scan := scanModuleSyntax(tokens)
+		file := &SourceFile{
+			Path:    filePath,
+			Content: content,
+			AST:     ast,
+			Symbols: scan.exports,
+			Types:   scan.types,
+			Size:    len(content),
+			exports: make(map[string]*Symbol, len(scan.exports)),
+		}
+		for _, symbol := range scan.exports {
+			file.exports[symbol.Name] = symbol
+		}
+		project.GlobalSymbols.Define(scan.exports, scan.types)
+
+		project.Files[i] = file
+		project.fileByPath[filePath] = file
+		scans[i] = scan
+		exportsAll[filePath] = scan.exportsAll
+		stats.Bytes += len(content)
+	}
+	stats.Files = len(project.Files)
+
+	// 所有文件读入后再解析 import，目标文件含 export * 时不检查导入的名称
+	for i, file := range project.Files {
+		for _, imp := range scans[i].imports {
+			target, ok := resolveRepositoryImport(project, file.Path, imp.specifier)
+			switch {
+			case !ok:
+				stats.ExternalImports++
+				continue
+			case project.fileByPath[target] == nil:
+				stats.MissingImports++
+			default:
+				stats.Imports++
+			}
+
+			decl := &ImportDeclaration{From: target}
+			if !exportsAll[target] {
+				decl.Names = imp.names
+			}
+			file.Imports = append(file.Imports, decl)
+			project.Dependencies[file.Path] = append(project.Dependencies[file.Path], target)
+		}
+	}
+
+	for _, file := range project.Files {
+		checkIncrementalChanges(file, project.GlobalSymbols)
+	}
+	return project, stats, nil
+}
+
+// benchmarkRepository 读取并解析真实仓库（只测量一次），然后与生成的项目一样测试三种处理模式
+func benchmarkRepository(root string, options projectBenchmarkOptions) []BenchmarkResult {
+	fmt.Printf("读取仓库 %s...\n", root)
+	profile := options.measure.Profile.begin("large-scale-load", nil)
+	run := startBenchmark("large-scale-load", nil)
+	project, stats, err := loadRepository(root, options.symbolTable)
+	if err == nil && stats.Files == 0 {
+		err = fmt.Errorf("%s 中没有 .ts/.tsx 文件", root)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "读取仓库失败:", err)
+		os.Exit(1)
+	}
+	load := run.stop(map[string]float64{
+		"bytes":           float64(stats.Bytes),
+		"parseErrors":     float64(stats.ParseErrors),
+		"astCoverage":     stats.astCoverage(),
+		"imports":         float64(stats.Imports),
+		"missingImports":  float64(stats.MissingImports),
+		"externalImports": float64(stats.ExternalImports),
+	})
+	profile.end()
+
+	params := map[string]interface{}{
+		"files":       stats.Files,
+		"workload":    projectFingerprint(project),
+		"graph":       "repository",
+		"source":      "repository",
+		"seed":        options.mutateSeed, // 仓库的内容是固定的，种子只决定增量检查修改哪些文件
+		"symbolTable": options.symbolTable.String(),
+		"pool":        options.strategy.String(),
+	}
+	load.Parameters = params
+
+	fmt.Printf("测试项目规模: %d 个文件\n", stats.Files)
+	fmt.Println("----------------------------------------")
+	fmt.Printf("  读取并解析耗时: %.2f ms，共 %.1f KB，AST 覆盖 %.1f KB（%.1f%%）\n", load.WallTimeMs,
+		float64(stats.Bytes)/1024, float64(stats.Bytes-stats.SkippedBytes)/1024, stats.astCoverage()*100)
+	fmt.Printf("  解析错误: %d 个（解析器只支持 TypeScript 的一个子集，不支持的语法会被跳过）\n", stats.ParseErrors)
+	if stats.astCoverage() < repositoryMinCoverage {
+		fmt.Printf("  警告: AST 只覆盖了 %.1f%% 的源码（class 和 interface 的成员、函数表达式的函数体等被跳过），检查耗时不能代表整个仓库\n",
+			stats.astCoverage()*100)
+	}
+	fmt.Printf("  import: %d 个解析到仓库内的文件，%d 个指向不存在的文件，%d 个外部模块或资源\n",
+		stats.Imports, stats.MissingImports, stats.ExternalImports)
+
+	results := append([]BenchmarkResult{load}, benchmarkProject(project, params, options)...)
+	fmt.Println()
+	return results
+}
+
+// resolveRepositoryImport 把相对路径的模块名解析为项目内的文件路径。
+// 非相对路径（包名、路径别名）以及指向 .css、.json 等非脚本资源的路径返回 ok 为 false；
+// 找不到对应文件时返回按路径拼接的结果，由 resolveImports 报告模块解析诊断
+func resolveRepositoryImport(project *LargeProject, from, specifier string) (string, bool) {
+	if !strings.HasPrefix(specifier, "./") && !strings.HasPrefix(specifier, "../") {
+		return "", false
+	}
+	base := path.Join(path.Dir(from), specifier)
+	ext := path.Ext(base)
+	switch ext {
+	case ".ts", ".tsx":
+		return base, true
+	case ".js", ".jsx", ".mjs", ".cjs":
+		// ESM 风格的 TypeScript 用 .js 后缀导入 .ts 文件
+		base = strings.TrimSuffix(base, ext)
+		ext = ""
+	}
+
+	for _, candidate := range []string{base + ".ts", base + ".tsx", base + ".d.ts", base + "/index.ts", base + "/index.tsx"} {
+		if project.fileByPath[candidate] != nil {
+			return candidate, true
+		}
+	}
+	if ext != "" {
+		// 可能是 "./styles.css"，也可能是不存在的 "./user.service"，无法区分时按资源忽略
+		return "", false
+	}
+	return base + ".ts", true
+}
+
+// scanModuleSyntax 在词法单元中扫描 import/export 语句
+func scanModuleSyntax(tokens []Token) *moduleScan {
+	scan := &moduleScan{}
+	isIdent := func(i int, text string) bool {
+		return i < len(tokens) && tokens[i].Kind == TokenIdentifier && tokens[i].Text == text
+	}
+	isPunct := func(i int, text string) bool {
+		return i < len(tokens) && tokens[i].Kind == TokenPunctuation && tokens[i].Text == text
+	}
+	addExport := func(name, symbolType string) {
+		scan.exports = append(scan.exports, &Symbol{Name: name, Type: symbolType})
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch {
+		case tok.Kind == TokenIdentifier && tok.Text == "require" && isPunct(i+1, "(") &&
+			i+2 < len(tokens) && tokens[i+2].Kind == TokenString:
+			scan.imports = append(scan.imports, moduleImport{specifier: tokens[i+2].Text})
+
+		case tok.Kind == TokenIdentifier && tok.Text == "import":
+			if isPunct(i+1, "(") || isPunct(i+1, ".") {
+				// 动态 import("./m")，或 import.meta
+				if i+2 < len(tokens) && tokens[i+2].Kind == TokenString {
+					scan.imports = append(scan.imports, moduleImport{specifier: tokens[i+2].Text})
+				}
+				continue
+			}
+			if imp, next, ok := scanImportClause(tokens, i+1); ok {
+				scan.imports = append(scan.imports, imp)
+				i = next - 1
+			}
+
+		case tok.Kind == TokenKeyword && tok.Text == "export":
+			j := i + 1
+			for isIdent(j, "declare") || isIdent(j, "abstract") || isIdent(j, "async") {
+				j++
+			}
+			if j >= len(tokens) {
+				continue
+			}
+			next := tokens[j]
+			switch {
+			case next.Kind == TokenKeyword && next.Text == "function":
+				if j+1 < len(tokens) && tokens[j+1].Kind == TokenOperator && tokens[j+1].Text == "*" {
+					j++ // function*
+				}
+				if j+1 < len(tokens) && tokens[j+1].Kind == TokenIdentifier {
+					addExport(tokens[j+1].Text, "function")
+				}
+			case next.Kind == TokenKeyword && (next.Text == "let" || next.Text == "const") || isIdent(j, "var"):
+				if isIdent(j+1, "enum") {
+					j++
+				}
+				if j+1 < len(tokens) && tokens[j+1].Kind == TokenIdentifier {
+					addExport(tokens[j+1].Text, "variable")
+				}
+			case isIdent(j, "class") || isIdent(j, "interface"):
+				if j+1 < len(tokens) && tokens[j+1].Kind == TokenIdentifier {
+					addExport(tokens[j+1].Text, next.Text)
+					scan.types = append(scan.types, scanTypeMembers(tokens, j+2, tokens[j+1].Text))
+				}
+			case isIdent(j, "type") || isIdent(j, "enum") || isIdent(j, "namespace") || isIdent(j, "module"):
+				if j+1 < len(tokens) && tokens[j+1].Kind == TokenIdentifier {
+					addExport(tokens[j+1].Text, next.Text)
+				} else if isPunct(j+1, "{") && next.Text == "type" {
+					// export type { A, B }
+					if imp, ok := scanExportClause(tokens, j+1, addExport); ok {
+						scan.imports = append(scan.imports, imp)
+					}
+				}
+			case isIdent(j, "default"):
+				addExport("default", "variable")
+			case isPunct(j, "{"):
+				if imp, ok := scanExportClause(tokens, j, addExport); ok {
+					scan.imports = append(scan.imports, imp)
+				}
+			case next.Kind == TokenOperator && next.Text == "*":
+				// export * from "./m" 或 export * as ns from "./m"
+				k := j + 1
+				if isIdent(k, "as") && k+1 < len(tokens) {
+					addExport(tokens[k+1].Text, "variable")
+					k += 2
+				} else {
+					scan.exportsAll = true
+				}
+				if isIdent(k, "from") && k+1 < len(tokens) && tokens[k+1].Kind == TokenString {
+					scan.imports = append(scan.imports, moduleImport{specifier: tokens[k+1].Text})
+				}
+			}
+		}
+	}
+	return scan
+}
+
+// scanImportClause 扫描 import 之后的部分，返回 import 和之后第一个词法单元的下标。
+// 不是 import 语句时（例如 import x = require("./m")，由 require 分支处理）ok 为 false
+func scanImportClause(tokens []Token, i int) (imp moduleImport, next int, ok bool) {
+	if i < len(tokens) && tokens[i].Kind == TokenString {
+		return moduleImport{specifier: tokens[i].Text}, i + 1, true
+	}
+	for ; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch {
+		case tok.Kind == TokenPunctuation && tok.Text == "{":
+			var bindings []namedBinding
+			i, bindings = scanNamedBindings(tokens, i)
+			for _, binding := range bindings {
+				imp.names = append(imp.names, binding.name)
+			}
+		case tok.Kind == TokenIdentifier && tok.Text == "from":
+			if i+1 < len(tokens) && tokens[i+1].Kind == TokenString {
+				imp.specifier = tokens[i+1].Text
+				return imp, i + 2, true
+			}
+			return imp, i, false
+		case tok.Kind == TokenIdentifier && tok.Text != "require",
+			tok.Kind == TokenOperator && tok.Text == "*",
+			tok.Kind == TokenPunctuation && tok.Text == ",":
+			// 默认导入、import type 以及 * as ns
+		default:
+			return imp, i, false
+		}
+	}
+	return imp, i, false
+}
+
+// scanExportClause 扫描从 i 处的 { 开始的 export { a, b as c } [from "./m"]，导出名加入 addExport；
+// 有 from 时返回一条 re-export 形式的 import
+func scanExportClause(tokens []Token, i int, addExport func(name, symbolType string)) (moduleImport, bool) {
+	end, bindings := scanNamedBindings(tokens, i)
+	var imp moduleImport
+	for _, binding := range bindings {
+		addExport(binding.exportedName(), "variable")
+		imp.names = append(imp.names, binding.name)
+	}
+	if end+2 < len(tokens) && tokens[end+1].Kind == TokenIdentifier && tokens[end+1].Text == "from" &&
+		tokens[end+2].Kind == TokenString {
+		imp.specifier = tokens[end+2].Text
+		return imp, true
+	}
+	return moduleImport{}, false
+}
+
+// namedBinding { a as b } 中的一项
+type namedBinding struct {
+	name  string
+	alias string
+}
+
+func (b namedBinding) exportedName() string {
+	if b.alias != "" {
+		return b.alias
+	}
+	return b.name
+}
+
+// scanNamedBindings 扫描从 i 处的 { 开始的 { a, type b, c as d }，返回匹配的 } 的下标和各项
+func scanNamedBindings(tokens []Token, i int) (int, []namedBinding) {
+	var bindings []namedBinding
+	var item []string
+	flush := func() {
+		if len(item) >= 2 && item[0] == "type" && item[1] != "as" {
+			item = item[1:]
+		}
+		if len(item) > 0 {
+			binding := namedBinding{name: item[0]}
+			if len(item) >= 3 && item[1] == "as" {
+				binding.alias = item[2]
+			}
+			bindings = append(bindings, binding)
+		}
+		item = item[:0]
+	}
+	for i++; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch {
+		case tok.Kind == TokenPunctuation && tok.Text == "}":
+			flush()
+			return i, bindings
+		case tok.Kind == TokenPunctuation && tok.Text == ",":
+			flush()
+		case tok.Kind == TokenIdentifier:
+			item = append(item, tok.Text)
+		case tok.Kind == TokenEOF || tok.Kind == TokenKeyword:
+			return i, bindings
+		}
+	}
+	return i, bindings
+}
+
+// scanTypeMembers 从 class/interface 名称之后找到第一个 {，收集第一层成员：
+// 后跟 : 的是属性（类型取冒号后的第一个词法单元），后跟 ( 的是方法。
+// 名称之后的 <...> 中每个参数的第一个标识符是类型参数，extends 之后的第一个词法单元是它的约束
+func scanTypeMembers(tokens []Token, i int, name string) *TypeInfo {
+	typeInfo := &TypeInfo{Name: name, Properties: make(map[string]string)}
+	if i < len(tokens) && tokens[i].Kind == TokenOperator && tokens[i].Text == "<" {
+		depth := 0 // 约束中嵌套的 <>
+	typeParams:
+		for i++; i < len(tokens) && tokens[i].Kind != TokenEOF && tokens[i].Text != "{"; i++ {
+			switch tokens[i].Text {
+			case "<":
+				depth++
+			case ">":
+				if depth == 0 {
+					break typeParams
+				}
+				depth--
+			}
+			params := typeInfo.TypeParams
+			switch prev := tokens[i-1].Text; {
+			case depth > 0:
+			case tokens[i].Kind == TokenIdentifier && (prev == "<" || prev == ","):
+				typeInfo.TypeParams = append(params, TypeParameterInfo{Name: tokens[i].Text})
+			case prev == "extends" && len(params) > 0 && params[len(params)-1].Constraint == "":
+				params[len(params)-1].Constraint = tokens[i].Text
+			}
+		}
+	}
+	for i < len(tokens) && !(tokens[i].Kind == TokenPunctuation && tokens[i].Text == "{") {
+		if tokens[i].Kind == TokenEOF || tokens[i].Kind == TokenPunctuation && tokens[i].Text == ";" {
+			return typeInfo
+		}
+		i++
+	}
+
+	depth := 0
+	for ; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok.Kind == TokenEOF {
+			break
+		}
+		if tok.Kind == TokenPunctuation {
+			switch tok.Text {
+			case "{", "(", "[":
+				depth++
+			case "}", ")", "]":
+				depth--
+			}
+			if depth == 0 {
+				break
+			}
+			continue
+		}
+		if depth != 1 || tok.Kind != TokenIdentifier || i+1 >= len(tokens) {
+			continue
+		}
+		switch next := tokens[i+1]; {
+		case next.Kind == TokenPunctuation && next.Text == ":" && i+2 < len(tokens):
+			typeInfo.Properties[tok.Text] = tokens[i+2].Text
+		case next.Kind == TokenPunctuation && next.Text == "(":
+			typeInfo.Methods = append(typeInfo.Methods, tok.Text)
+		}
+	}
+	return typeInfo
+}
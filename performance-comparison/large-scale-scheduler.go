@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"runtime/trace"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// fileGraph 以文件下标表示的依赖图，edges[i] 是文件 i 导入的文件
+type fileGraph struct {
+	files []*SourceFile
+	edges [][]int
+}
+
+func buildFileGraph(project *LargeProject) *fileGraph {
+	index := make(map[string]int, len(project.Files))
+	for i, file := range project.Files {
+		index[file.Path] = i
+	}
+	g := &fileGraph{
+		files: project.Files,
+		edges: make([][]int, len(project.Files)),
+	}
+	for i, file := range project.Files {
+		for _, dep := range project.Dependencies[file.Path] {
+			if j, ok := index[dep]; ok {
+				g.edges[i] = append(g.edges[i], j)
+			}
+		}
+	}
+	return g
+}
+
+// stronglyConnectedComponents 使用 Tarjan 算法求强连通分量。
+// 返回的分量按逆拓扑序排列：一个分量导入的分量总是排在它前面，
+// 因此这个顺序本身就是一个合法的检查顺序
+func (g *fileGraph) stronglyConnectedComponents() [][]int {
+	n := len(g.files)
+	index := make([]int, n)
+	lowlink := make([]int, n)
+	onStack := make([]bool, n)
+	for i := range index {
+		index[i] = -1
+	}
+
+	var components [][]int
+	var stack []int
+	next := 0
+
+	var connect func(v int)
+	connect = func(v int) {
+		index[v] = next
+		lowlink[v] = next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range g.edges[v] {
+			if index[w] < 0 {
+				connect(w)
+				lowlink[v] = min(lowlink[v], lowlink[w])
+			} else if onStack[w] {
+				lowlink[v] = min(lowlink[v], index[w])
+			}
+		}
+
+		if lowlink[v] == index[v] {
+			var component []int
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				component = append(component, w)
+				if w == v {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+
+	for v := 0; v < n; v++ {
+		if index[v] < 0 {
+			connect(v)
+		}
+	}
+	return components
+}
+
+// ScheduleStats 依赖调度的统计信息
+type ScheduleStats struct {
+	Components       int           // 强连通分量（调度单元）数量
+	CyclicComponents int           // 包含环的分量数量
+	LargestComponent int           // 最大分量包含的文件数
+	CriticalPathLen  int           // 关键路径上的分量数，即最少需要的波次
+	CriticalPath     time.Duration // 按实测耗时计算的关键路径长度，并行耗时的下界
+	Steals           int           // 任务池窃取模式下被其他 worker 窃取的分量数
+}
+
+// scheduleByDependencies 按依赖顺序并行处理文件：每个强连通分量作为一个整体，
+// 在它导入的所有分量处理完成后才开始；互不依赖的分量最多 workers 个并行执行。
+// strategy 为 StrategyGoroutine 时每个就绪的分量启动一个 goroutine 并等待信号量，
+// 否则提交到 workers 个 worker 的任务池，分量完成后由同一个 worker 提交新就绪的分量。
+// process 处理单个文件，ctx 为文件所在分量的 trace 任务，返回错误表示文件因取消没有完成；
+// onDone 在每个文件处理完成后调用。ctx 被取消后不再开始新的分量，未开始的分量的文件不会被处理
+func scheduleByDependencies(ctx context.Context, project *LargeProject, workers int, strategy ConcurrencyStrategy, process func(context.Context, *SourceFile) error, onDone func()) ScheduleStats {
+	g := buildFileGraph(project)
+	components := g.stronglyConnectedComponents()
+
+	componentOf := make([]int, len(g.files))
+	for c, files := range components {
+		for _, f := range files {
+			componentOf[f] = c
+		}
+	}
+
+	// 分量之间的依赖（去重，忽略分量内部的边）
+	dependsOn := make([][]int, len(components))
+	dependents := make([][]int, len(components))
+	stats := ScheduleStats{Components: len(components)}
+	for c, files := range components {
+		seen := make(map[int]bool)
+		selfLoop := false
+		for _, f := range files {
+			for _, dep := range g.edges[f] {
+				d := componentOf[dep]
+				if d == c {
+					selfLoop = true
+					continue
+				}
+				if !seen[d] {
+					seen[d] = true
+					dependsOn[c] = append(dependsOn[c], d)
+					dependents[d] = append(dependents[d], c)
+				}
+			}
+		}
+		if len(files) > 1 || selfLoop {
+			stats.CyclicComponents++
+		}
+		stats.LargestComponent = max(stats.LargestComponent, len(files))
+	}
+
+	remaining := make([]int32, len(components))
+	for c := range components {
+		remaining[c] = int32(len(dependsOn[c]))
+	}
+	durations := make([]time.Duration, len(components))
+
+	// runComponent 处理分量 c，返回因此变为就绪的分量；被取消时不再释放依赖它的分量
+	runComponent := func(ctx context.Context, c int) []int {
+		if ctx.Err() != nil {
+			return nil
+		}
+		start := time.Now()
+		// 环内的文件互相依赖，只能在同一个 goroutine 中依次处理
+		for _, f := range components[c] {
+			if process(ctx, g.files[f]) != nil {
+				return nil
+			}
+			onDone()
+		}
+		durations[c] = time.Since(start)
+
+		var ready []int
+		for _, d := range dependents[c] {
+			if atomic.AddInt32(&remaining[d], -1) == 0 {
+				ready = append(ready, d)
+			}
+		}
+		return ready
+	}
+
+	// 没有依赖的分量在启动任何任务之前确定，之后 remaining 只由任务原子地修改
+	var roots []int
+	for c := range components {
+		if remaining[c] == 0 {
+			roots = append(roots, c)
+		}
+	}
+
+	if strategy == StrategyGoroutine {
+		var wg sync.WaitGroup
+		semaphore := make(chan struct{}, workers)
+
+		var launch func(c int)
+		launch = func(c int) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ctx, task := trace.NewTask(ctx, "component")
+				if acquireSemaphore(ctx, semaphore) != nil {
+					task.End()
+					return
+				}
+				ready := runComponent(ctx, c)
+				<-semaphore
+				task.End()
+
+				for _, d := range ready {
+					launch(d)
+				}
+			}()
+		}
+
+		for _, c := range roots {
+			launch(c)
+		}
+		wg.Wait()
+	} else {
+		pool := NewWorkerPool(workers, strategy == StrategySteal)
+
+		var submit func(worker, c int)
+		submit = func(worker, c int) {
+			task := func(worker int) {
+				ctx, task := trace.NewTask(ctx, "component")
+				ready := runComponent(ctx, c)
+				task.End()
+				for _, d := range ready {
+					submit(worker, d)
+				}
+			}
+			if worker < 0 {
+				pool.Submit(task)
+			} else {
+				pool.SubmitFrom(worker, task)
+			}
+		}
+
+		for _, c := range roots {
+			submit(-1, c)
+		}
+		stats.Steals = int(pool.Wait())
+	}
+
+	// components 是逆拓扑序，依赖的分量总是先被计算
+	waves := make([]int, len(components))
+	finish := make([]time.Duration, len(components))
+	for c := range components {
+		for _, d := range dependsOn[c] {
+			waves[c] = max(waves[c], waves[d])
+			finish[c] = max(finish[c], finish[d])
+		}
+		waves[c]++
+		finish[c] += durations[c]
+		stats.CriticalPathLen = max(stats.CriticalPathLen, waves[c])
+		stats.CriticalPath = max(stats.CriticalPath, finish[c])
+	}
+	return stats
+}
@@ -0,0 +1,565 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 语言服务风格的常驻检查进程（-serve）：项目常驻内存，通过标准输入输出接收 JSON-RPC 2.0 请求，
+// 消息使用 LSP 的 Content-Length 分帧。请求按到达顺序依次处理（与 tsserver 相同），
+// 修改文件后立即增量重新检查，之后的诊断请求直接返回缓存的结果。
+// 每个请求在服务端的处理耗时写到标准错误，server/stats 返回各方法的耗时统计。
+//
+// 方法（参数 → 结果）：
+//
+//	initialize                 {}                                  → {files, diagnostics, checkMs}
+//	textDocument/didOpen       {path}                              → {path, diagnostics}
+//	textDocument/didClose      {path}                              → null
+//	textDocument/didChange     {path, text?, signatureChange?}     → {rechecked, waves, signatureChanged, checkMs, affected}
+//	textDocument/diagnostics   {path}                              → {path, diagnostics}
+//	workspace/resolveSymbol    {name}                              → {name, type, path}，找不到时为 null
+//	server/stats               {}                                  → {<方法>: {count, medianMs, p95Ms, maxMs}}
+//	shutdown                   {}                                  → null，回复后服务结束
+//
+// 没有 id 的消息是通知，照常处理但不回复
+
+// JSON-RPC 2.0 错误码
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"` // 结果为空时是 "null"，不会被省略
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("JSON-RPC 错误 %d: %s", e.Code, e.Message)
+}
+
+// readRPCMessage 读取一条 Content-Length 分帧的消息，返回消息体
+func readRPCMessage(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line == "" && length < 0 {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("读取消息头: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("无效的 Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, errors.New("消息头中没有 Content-Length")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("读取消息体: %w", err)
+	}
+	return body, nil
+}
+
+// writeRPCMessage 把 message 编码为 JSON 并以 Content-Length 分帧写出
+func writeRPCMessage(w io.Writer, message interface{}) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
+
+// rpcDiagnostic 诊断在协议中的表示，行列从 1 开始
+type rpcDiagnostic struct {
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine"`
+	EndColumn int    `json:"endColumn"`
+	Severity  string `json:"severity"`
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+}
+
+type rpcFileDiagnostics struct {
+	Path        string          `json:"path"`
+	Diagnostics []rpcDiagnostic `json:"diagnostics"`
+}
+
+type rpcChangeResult struct {
+	Rechecked        int      `json:"rechecked"`
+	Waves            int      `json:"waves"`
+	SignatureChanged int      `json:"signatureChanged"`
+	CheckMs          float64  `json:"checkMs"`
+	Affected         []string `json:"affected"` // 被重新检查的已打开文件，编辑器应当重新获取它们的诊断
+}
+
+type rpcSymbol struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Path string `json:"path"`
+}
+
+type rpcMethodStats struct {
+	Count    int     `json:"count"`
+	MedianMs float64 `json:"medianMs"`
+	P95Ms    float64 `json:"p95Ms"`
+	MaxMs    float64 `json:"maxMs"`
+}
+
+// CheckServer 常驻内存的检查服务。不是并发安全的，Serve 依次处理请求
+type CheckServer struct {
+	project     *LargeProject
+	strategy    ConcurrencyStrategy
+	open        map[string]bool
+	diagnostics map[string][]*Diagnostic // 每个文件最近一次检查的诊断
+	definedIn   map[string]*SourceFile   // 导出符号名称 -> 导出它的第一个文件
+	latencies   map[string][]time.Duration
+	initialized bool
+	checkTime   time.Duration // 启动时全量检查的耗时
+
+	Log io.Writer // 非 nil 时每个请求输出一行方法名和耗时
+}
+
+// NewCheckServer 创建检查服务，按依赖顺序全量检查一次项目，缓存每个文件的诊断
+func NewCheckServer(project *LargeProject, strategy ConcurrencyStrategy) *CheckServer {
+	s := &CheckServer{
+		project:     project,
+		strategy:    strategy,
+		open:        make(map[string]bool),
+		diagnostics: make(map[string][]*Diagnostic, len(project.Files)),
+		definedIn:   make(map[string]*SourceFile),
+		latencies:   make(map[string][]time.Duration),
+	}
+	for _, file := range project.Files {
+		for _, symbol := range file.Symbols {
+			if _, ok := s.definedIn[symbol.Name]; !ok {
+				s.definedIn[symbol.Name] = file
+			}
+		}
+	}
+
+	collector := NewDiagnosticCollector()
+	result, _ := processProjectHighConcurrency(context.Background(), project, collector, strategy)
+	s.checkTime = result.Duration
+	s.storeDiagnostics(project.Files, collector)
+	return s
+}
+
+// cachedDiagnostics 缓存中全部文件的诊断
+func (s *CheckServer) cachedDiagnostics() *DiagnosticCollector {
+	collector := NewDiagnosticCollector()
+	for _, diagnostics := range s.diagnostics {
+		collector.add(diagnostics...)
+	}
+	return collector
+}
+
+// storeDiagnostics 用 collector 中的诊断替换 files 的缓存，没有诊断的文件清空
+func (s *CheckServer) storeDiagnostics(files []*SourceFile, collector *DiagnosticCollector) {
+	for _, file := range files {
+		s.diagnostics[file.Path] = nil
+	}
+	for _, d := range collector.sorted() {
+		s.diagnostics[d.File] = append(s.diagnostics[d.File], d)
+	}
+}
+
+// Serve 从 in 读取请求并把回复写到 out，直到输入结束或收到 shutdown
+func (s *CheckServer) Serve(in io.Reader, out io.Writer) error {
+	reader := bufio.NewReader(in)
+	for {
+		body, err := readRPCMessage(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var request rpcRequest
+		response := rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null")}
+		if err := json.Unmarshal(body, &request); err != nil {
+			response.Error = &rpcError{Code: rpcParseError, Message: err.Error()}
+		} else if request.Method == "" {
+			response.Error = &rpcError{Code: rpcInvalidRequest, Message: "缺少 method"}
+		} else {
+			start := time.Now()
+			result, err := s.handle(request.Method, request.Params)
+			elapsed := time.Since(start)
+			var rpcErr *rpcError
+			if !errors.As(err, &rpcErr) || rpcErr.Code != rpcMethodNotFound {
+				s.latencies[request.Method] = append(s.latencies[request.Method], elapsed)
+			}
+			if s.Log != nil {
+				fmt.Fprintf(s.Log, "%s %.3f ms\n", request.Method, float64(elapsed.Nanoseconds())/1000000)
+			}
+
+			if request.ID == nil {
+				continue // 通知不回复
+			}
+			response.ID = request.ID
+			if err != nil {
+				if !errors.As(err, &rpcErr) {
+					rpcErr = &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+				}
+				response.Error = rpcErr
+			} else if response.Result, err = json.Marshal(result); err != nil {
+				return err
+			}
+		}
+
+		if err := writeRPCMessage(out, response); err != nil {
+			return err
+		}
+		if request.Method == "shutdown" {
+			return nil
+		}
+	}
+}
+
+func (s *CheckServer) handle(method string, params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Path            string  `json:"path"`
+		Text            *string `json:"text"`
+		SignatureChange bool    `json:"signatureChange"`
+		Name            string  `json:"name"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+	}
+
+	switch method {
+	case "initialize":
+		s.initialized = true
+		total := 0
+		for _, diagnostics := range s.diagnostics {
+			total += len(diagnostics)
+		}
+		return map[string]interface{}{
+			"files":       len(s.project.Files),
+			"diagnostics": total,
+			"checkMs":     float64(s.checkTime.Nanoseconds()) / 1000000,
+		}, nil
+	case "shutdown":
+		return nil, nil
+	case "server/stats":
+		return s.stats(), nil
+	}
+
+	if !s.initialized {
+		return nil, &rpcError{Code: rpcInvalidRequest, Message: "请先发送 initialize"}
+	}
+	switch method {
+	case "textDocument/didOpen", "textDocument/diagnostics":
+		file, err := s.file(p.Path)
+		if err != nil {
+			return nil, err
+		}
+		if method == "textDocument/didOpen" {
+			s.open[file.Path] = true
+		}
+		return s.fileDiagnostics(file), nil
+	case "textDocument/didClose":
+		delete(s.open, p.Path)
+		return nil, nil
+	case "textDocument/didChange":
+		file, err := s.file(p.Path)
+		if err != nil {
+			return nil, err
+		}
+		return s.change(file, p.Text, p.SignatureChange), nil
+	case "workspace/resolveSymbol":
+		symbol := resolveSymbolWithGlobal(p.Name, s.project.GlobalSymbols)
+		file := s.definedIn[p.Name]
+		if symbol == nil || file == nil {
+			return nil, nil
+		}
+		return rpcSymbol{Name: symbol.Name, Type: symbol.Type, Path: file.Path}, nil
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: "未知的方法 " + method}
+}
+
+func (s *CheckServer) file(path string) (*SourceFile, error) {
+	file, ok := s.project.fileByPath[path]
+	if !ok {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "项目中没有文件 " + path}
+	}
+	return file, nil
+}
+
+func (s *CheckServer) fileDiagnostics(file *SourceFile) rpcFileDiagnostics {
+	result := rpcFileDiagnostics{Path: file.Path, Diagnostics: []rpcDiagnostic{}}
+	for _, d := range s.diagnostics[file.Path] {
+		result.Diagnostics = append(result.Diagnostics, rpcDiagnostic{
+			Line:      d.Pos.Line,
+			Column:    d.Pos.Column,
+			EndLine:   d.End.Line,
+			EndColumn: d.End.Column,
+			Severity:  d.Severity.String(),
+			Code:      d.Code,
+			Message:   d.Message,
+		})
+	}
+	return result
+}
+
+// change 修改文件内容：有 text 时替换全部内容，否则模拟一次按键（改动一个字节）；
+// signatureChange 同时改变一个导出符号的类型。然后增量重新检查受影响的文件
+func (s *CheckServer) change(file *SourceFile, text *string, signatureChange bool) rpcChangeResult {
+	switch {
+	case text != nil:
+		file.Content = []byte(*text)
+	case len(file.Content) > 0:
+		file.Content[int(file.ContentHash%uint64(len(file.Content)))]++
+	default:
+		file.Content = append(file.Content, '\n')
+	}
+	if signatureChange && len(file.Symbols) > 0 {
+		symbol := file.Symbols[int(file.ContentHash%uint64(len(file.Symbols)))]
+		if symbol.Type == "function" {
+			s.project.setSymbolType(symbol, "variable")
+		} else {
+			s.project.setSymbolType(symbol, "function")
+		}
+	}
+
+	collector := NewDiagnosticCollector()
+	incremental := recheckIncremental(s.project, collector)
+	s.storeDiagnostics(incremental.Files, collector)
+	result := rpcChangeResult{
+		Rechecked:        incremental.Rechecked,
+		Waves:            incremental.Waves,
+		SignatureChanged: incremental.SignatureChanged,
+		CheckMs:          float64(incremental.Duration.Nanoseconds()) / 1000000,
+		Affected:         []string{},
+	}
+	for _, rechecked := range incremental.Files {
+		if s.open[rechecked.Path] {
+			result.Affected = append(result.Affected, rechecked.Path)
+		}
+	}
+	return result
+}
+
+func (s *CheckServer) stats() map[string]rpcMethodStats {
+	stats := make(map[string]rpcMethodStats, len(s.latencies))
+	for method, durations := range s.latencies {
+		timing := newTimingStats(durations, 0)
+		stats[method] = rpcMethodStats{Count: timing.Samples, MedianMs: timing.MedianMs, P95Ms: timing.P95Ms, MaxMs: timing.MaxMs}
+	}
+	return stats
+}
+
+// rpcClient 模拟编辑器的客户端，测量每个请求从发送到收到回复的耗时
+type rpcClient struct {
+	w      io.Writer
+	r      *bufio.Reader
+	nextID int
+}
+
+// call 发送请求并等待回复，result 为 nil 时忽略结果
+func (c *rpcClient) call(method string, params, result interface{}) (time.Duration, error) {
+	c.nextID++
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return 0, err
+	}
+	request := rpcRequest{JSONRPC: "2.0", ID: json.RawMessage(strconv.Itoa(c.nextID)), Method: method, Params: rawParams}
+
+	start := time.Now()
+	if err := writeRPCMessage(c.w, request); err != nil {
+		return 0, err
+	}
+	body, err := readRPCMessage(c.r)
+	elapsed := time.Since(start)
+	if err != nil {
+		return elapsed, err
+	}
+
+	var response rpcResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return elapsed, err
+	}
+	if response.Error != nil {
+		return elapsed, response.Error
+	}
+	if result != nil {
+		return elapsed, json.Unmarshal(response.Result, result)
+	}
+	return elapsed, nil
+}
+
+// serverBenchmarkMethods 编辑器会话中测量的方法及其结果名称
+var serverBenchmarkMethods = []struct{ method, name string }{
+	{"textDocument/didOpen", "server-open"},
+	{"textDocument/didChange", "server-change"},
+	{"textDocument/diagnostics", "server-diagnostics"},
+	{"workspace/resolveSymbol", "server-resolve-symbol"},
+}
+
+// serverBenchmarkOpenFiles 编辑器会话中打开的文件数
+const serverBenchmarkOpenFiles = 10
+
+// benchmarkServer 在进程内启动检查服务，通过管道模拟一次编辑器会话：打开若干文件，
+// 然后重复 rounds 轮"修改一个打开的文件、获取它的诊断、解析一个符号"。
+// 报告客户端看到的每种请求的延迟（含 JSON 编解码），并与全量检查的耗时对比
+func benchmarkServer(project *LargeProject, params map[string]interface{}, rounds int, signatureRate float64, strategy ConcurrencyStrategy, seed int64) []BenchmarkResult {
+	rng := rand.New(rand.NewSource(seed))
+	var openPaths []string
+	for _, i := range rng.Perm(len(project.Files))[:min(serverBenchmarkOpenFiles, len(project.Files))] {
+		openPaths = append(openPaths, project.Files[i].Path)
+	}
+	// 符号名在服务启动前取出，之后项目只由服务访问
+	var symbolNames []string
+	for _, file := range project.Files {
+		for _, symbol := range file.Symbols {
+			symbolNames = append(symbolNames, symbol.Name)
+		}
+	}
+
+	fmt.Println("启动检查服务（全量检查一次）...")
+	server := NewCheckServer(project, strategy)
+	requestReader, requestWriter := io.Pipe()
+	responseReader, responseWriter := io.Pipe()
+	served := make(chan error, 1)
+	go func() {
+		err := server.Serve(requestReader, responseWriter)
+		responseWriter.CloseWithError(err)
+		served <- err
+	}()
+	client := &rpcClient{w: requestWriter, r: bufio.NewReader(responseReader)}
+
+	// 会话中任何请求失败都说明服务有问题，直接退出
+	latencies := make(map[string][]time.Duration)
+	call := func(method string, params, result interface{}) {
+		elapsed, err := client.call(method, params, result)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s 失败: %v\n", method, err)
+			os.Exit(1)
+		}
+		latencies[method] = append(latencies[method], elapsed)
+	}
+
+	var initialized struct {
+		Files       int     `json:"files"`
+		Diagnostics int     `json:"diagnostics"`
+		CheckMs     float64 `json:"checkMs"`
+	}
+	call("initialize", struct{}{}, &initialized)
+	fmt.Printf("  %d 个文件，%d 条诊断，全量检查 %.2f ms\n", initialized.Files, initialized.Diagnostics, initialized.CheckMs)
+
+	for _, path := range openPaths {
+		call("textDocument/didOpen", map[string]string{"path": path}, nil)
+	}
+	rechecked := 0
+	for i := 0; i < rounds; i++ {
+		path := openPaths[rng.Intn(len(openPaths))]
+		var change rpcChangeResult
+		call("textDocument/didChange", map[string]interface{}{
+			"path":            path,
+			"signatureChange": rng.Float64() < signatureRate,
+		}, &change)
+		rechecked += change.Rechecked
+		call("textDocument/diagnostics", map[string]string{"path": path}, nil)
+		call("workspace/resolveSymbol", map[string]string{"name": symbolNames[rng.Intn(len(symbolNames))]}, nil)
+	}
+
+	var serverStats map[string]rpcMethodStats
+	call("server/stats", struct{}{}, &serverStats)
+	call("shutdown", struct{}{}, nil)
+	requestWriter.Close()
+	if err := <-served; err != nil {
+		fmt.Fprintln(os.Stderr, "检查服务出错:", err)
+		os.Exit(1)
+	}
+
+	// 增量检查后缓存的诊断应当与对修改后的项目做一次全量检查的结果一致
+	full := NewDiagnosticCollector()
+	processProjectHighConcurrency(context.Background(), project, full, strategy)
+	cached := server.cachedDiagnostics()
+
+	serverParams := map[string]interface{}{"rounds": rounds, "openFiles": len(openPaths), "signatureRate": signatureRate}
+	for key, value := range params {
+		serverParams[key] = value
+	}
+
+	var results []BenchmarkResult
+	fmt.Printf("\n编辑器会话（%d 轮，客户端延迟 / 服务端处理耗时的中位数）:\n", rounds)
+	for _, m := range serverBenchmarkMethods {
+		durations := latencies[m.method]
+		if len(durations) == 0 {
+			continue
+		}
+		result := sampledResult(m.name, serverParams, durations, 0)
+		result.Metrics = map[string]float64{"serverMedianMs": serverStats[m.method].MedianMs}
+		if m.method == "textDocument/didChange" {
+			result.Metrics["recheckedPerChange"] = float64(rechecked) / float64(len(durations))
+			result.Metrics["fullCheckMs"] = initialized.CheckMs
+		}
+		results = append(results, result)
+		fmt.Printf("  %s: %s / %.3f ms\n", m.method, result.Stats, serverStats[m.method].MedianMs)
+	}
+	fmt.Printf("  每次修改平均重新检查 %.1f 个文件（全量检查 %.2f ms）\n",
+		float64(rechecked)/float64(max(rounds, 1)), initialized.CheckMs)
+	fmt.Printf("  会话结束时缓存的诊断: %s（摘要 %s）\n", cached.summary(), cached.fingerprint())
+	if cached.fingerprint() != full.fingerprint() {
+		fmt.Printf("  警告: 与全量重新检查的结果（摘要 %s）不一致\n", full.fingerprint())
+	}
+	return results
+}
+
+// writeStats 按方法名输出服务端的耗时统计，用于 -serve 结束时的总结
+func (s *CheckServer) writeStats(w io.Writer) {
+	stats := s.stats()
+	methods := make([]string, 0, len(stats))
+	for method := range stats {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		m := stats[method]
+		fmt.Fprintf(w, "  %s: %d 次，中位数 %.3f ms，p95 %.3f ms，最大 %.3f ms\n", method, m.Count, m.MedianMs, m.P95Ms, m.MaxMs)
+	}
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// GlobalSymbolTable 全局符号表。检查阶段只有读操作，由多个 goroutine 并发调用
+// LookupSymbol、LookupType 和 RangeTypes；Define 在构建项目时批量写入。
+// 不同的实现用于比较共享检查器状态的同步方式（-symtab）
+type GlobalSymbolTable interface {
+	LookupSymbol(name string) *Symbol
+	LookupType(name string) *TypeInfo
+	// RangeTypes 依次访问所有类型，fn 返回 false 时停止。顺序不确定
+	RangeTypes(fn func(*TypeInfo) bool)
+	// Define 写入一批符号和类型，同名的覆盖
+	Define(symbols []*Symbol, types []*TypeInfo)
+}
+
+// SymbolTableKind 全局符号表的实现方式
+type SymbolTableKind int
+
+const (
+	SymbolTableRWMutex  SymbolTableKind = iota // 一把 sync.RWMutex 保护两个 map
+	SymbolTableSyncMap                         // sync.Map
+	SymbolTableSharded                         // 按名称哈希分片，每个分片一把 RWMutex
+	SymbolTableSnapshot                        // 不可变快照，写入时复制并用 atomic.Pointer 发布，读取无锁
+)
+
+var symbolTableKindNames = map[SymbolTableKind]string{
+	SymbolTableRWMutex:  "rwmutex",
+	SymbolTableSyncMap:  "syncmap",
+	SymbolTableSharded:  "sharded",
+	SymbolTableSnapshot: "snapshot",
+}
+
+// symbolTableKinds 全部实现，按定义顺序
+var symbolTableKinds = []SymbolTableKind{SymbolTableRWMutex, SymbolTableSyncMap, SymbolTableSharded, SymbolTableSnapshot}
+
+func (k SymbolTableKind) String() string {
+	return symbolTableKindNames[k]
+}
+
+// parseSymbolTableKind 解析命令行中的符号表实现名称
+func parseSymbolTableKind(name string) (SymbolTableKind, error) {
+	for kind, kindName := range symbolTableKindNames {
+		if kindName == name {
+			return kind, nil
+		}
+	}
+	names := make([]string, 0, len(symbolTableKindNames))
+	for _, kindName := range symbolTableKindNames {
+		names = append(names, kindName)
+	}
+	sort.Strings(names)
+	return SymbolTableRWMutex, fmt.Errorf("未知的符号表实现 %q（可选: %s）", name, strings.Join(names, ", "))
+}
+
+// newGlobalSymbolTable 创建 kind 对应的空符号表
+func newGlobalSymbolTable(kind SymbolTableKind) GlobalSymbolTable {
+	switch kind {
+	case SymbolTableSyncMap:
+		return &syncMapSymbolTable{}
+	case SymbolTableSharded:
+		table := &shardedSymbolTable{}
+		for i := range table.shards {
+			table.shards[i].symbols = make(map[string]*Symbol)
+			table.shards[i].types = make(map[string]*TypeInfo)
+		}
+		return table
+	case SymbolTableSnapshot:
+		table := &snapshotSymbolTable{}
+		table.current.Store(&symbolSnapshot{
+			symbols: make(map[string]*Symbol),
+			types:   make(map[string]*TypeInfo),
+		})
+		return table
+	default:
+		return &rwMutexSymbolTable{
+			symbols: make(map[string]*Symbol),
+			types:   make(map[string]*TypeInfo),
+		}
+	}
+}
+
+// rwMutexSymbolTable 最初的实现：所有读操作共享一把读写锁
+type rwMutexSymbolTable struct {
+	mu      sync.RWMutex
+	symbols map[string]*Symbol
+	types   map[string]*TypeInfo
+}
+
+func (t *rwMutexSymbolTable) LookupSymbol(name string) *Symbol {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.symbols[name]
+}
+
+func (t *rwMutexSymbolTable) LookupType(name string) *TypeInfo {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.types[name]
+}
+
+func (t *rwMutexSymbolTable) RangeTypes(fn func(*TypeInfo) bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, typeInfo := range t.types {
+		if !fn(typeInfo) {
+			return
+		}
+	}
+}
+
+func (t *rwMutexSymbolTable) Define(symbols []*Symbol, types []*TypeInfo) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, symbol := range symbols {
+		t.symbols[symbol.Name] = symbol
+	}
+	for _, typeInfo := range types {
+		t.types[typeInfo.Name] = typeInfo
+	}
+}
+
+// syncMapSymbolTable 使用 sync.Map，适合写入一次、多次读取的场景
+type syncMapSymbolTable struct {
+	symbols sync.Map // string -> *Symbol
+	types   sync.Map // string -> *TypeInfo
+}
+
+func (t *syncMapSymbolTable) LookupSymbol(name string) *Symbol {
+	if value, ok := t.symbols.Load(name); ok {
+		return value.(*Symbol)
+	}
+	return nil
+}
+
+func (t *syncMapSymbolTable) LookupType(name string) *TypeInfo {
+	if value, ok := t.types.Load(name); ok {
+		return value.(*TypeInfo)
+	}
+	return nil
+}
+
+func (t *syncMapSymbolTable) RangeTypes(fn func(*TypeInfo) bool) {
+	t.types.Range(func(_, value any) bool {
+		return fn(value.(*TypeInfo))
+	})
+}
+
+func (t *syncMapSymbolTable) Define(symbols []*Symbol, types []*TypeInfo) {
+	for _, symbol := range symbols {
+		t.symbols.Store(symbol.Name, symbol)
+	}
+	for _, typeInfo := range types {
+		t.types.Store(typeInfo.Name, typeInfo)
+	}
+}
+
+// symbolTableShards 分片数，取 2 的幂以便用位运算取模
+const symbolTableShards = 64
+
+// symbolTableShardFields 分片的字段，不含填充
+type symbolTableShardFields struct {
+	mu      sync.RWMutex
+	symbols map[string]*Symbol
+	types   map[string]*TypeInfo
+}
+
+// symbolTableShard 一个分片，填充到 128 字节的整数倍：分片数组只保证按指针大小对齐，锁可能跨越缓存行边界，
+// 间隔 64 字节时相邻分片的锁仍可能落在同一个 64 字节缓存行上，间隔 128 字节时不会。
+// 填充按字段的大小计算，RWMutex 和 map 的大小随 GOARCH 和 Go 版本变化
+type symbolTableShard struct {
+	symbolTableShardFields
+	_ [128 - unsafe.Sizeof(symbolTableShardFields{})%128]byte
+}
+
+// 分片大小不是 128 字节的整数倍时编译失败
+var _ = [1]struct{}{}[unsafe.Sizeof(symbolTableShard{})%128]
+
+// shardedSymbolTable 按名称的 FNV-1a 哈希分片，不同名称的读操作大多落在不同的锁上
+type shardedSymbolTable struct {
+	shards [symbolTableShards]symbolTableShard
+}
+
+func (t *shardedSymbolTable) shard(name string) *symbolTableShard {
+	h := uint32(2166136261)
+	for i := 0; i < len(name); i++ {
+		h ^= uint32(name[i])
+		h *= 16777619
+	}
+	return &t.shards[h&(symbolTableShards-1)]
+}
+
+func (t *shardedSymbolTable) LookupSymbol(name string) *Symbol {
+	shard := t.shard(name)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	return shard.symbols[name]
+}
+
+func (t *shardedSymbolTable) LookupType(name string) *TypeInfo {
+	shard := t.shard(name)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	return shard.types[name]
+}
+
+// RangeTypes 依次锁定每个分片，不是整个表的一致快照
+func (t *shardedSymbolTable) RangeTypes(fn func(*TypeInfo) bool) {
+	for i := range t.shards {
+		shard := &t.shards[i]
+		shard.mu.RLock()
+		for _, typeInfo := range shard.types {
+			if !fn(typeInfo) {
+				shard.mu.RUnlock()
+				return
+			}
+		}
+		shard.mu.RUnlock()
+	}
+}
+
+func (t *shardedSymbolTable) Define(symbols []*Symbol, types []*TypeInfo) {
+	for _, symbol := range symbols {
+		shard := t.shard(symbol.Name)
+		shard.mu.Lock()
+		shard.symbols[symbol.Name] = symbol
+		shard.mu.Unlock()
+	}
+	for _, typeInfo := range types {
+		shard := t.shard(typeInfo.Name)
+		shard.mu.Lock()
+		shard.types[typeInfo.Name] = typeInfo
+		shard.mu.Unlock()
+	}
+}
+
+// symbolSnapshot 发布后不再修改的符号表内容
+type symbolSnapshot struct {
+	symbols map[string]*Symbol
+	types   map[string]*TypeInfo
+}
+
+// snapshotSymbolTable 读取时只做一次原子加载，没有任何锁；
+// 每次 Define 复制整个表再发布，写入的代价与表的大小成正比，因此按批写入
+type snapshotSymbolTable struct {
+	mu      sync.Mutex // 只在写入者之间互斥
+	current atomic.Pointer[symbolSnapshot]
+}
+
+func (t *snapshotSymbolTable) LookupSymbol(name string) *Symbol {
+	return t.current.Load().symbols[name]
+}
+
+func (t *snapshotSymbolTable) LookupType(name string) *TypeInfo {
+	return t.current.Load().types[name]
+}
+
+func (t *snapshotSymbolTable) RangeTypes(fn func(*TypeInfo) bool) {
+	for _, typeInfo := range t.current.Load().types {
+		if !fn(typeInfo) {
+			return
+		}
+	}
+}
+
+func (t *snapshotSymbolTable) Define(symbols []*Symbol, types []*TypeInfo) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	old := t.current.Load()
+	next := &symbolSnapshot{symbols: old.symbols, types: old.types}
+	if len(symbols) > 0 {
+		next.symbols = maps.Clone(old.symbols)
+		for _, symbol := range symbols {
+			next.symbols[symbol.Name] = symbol
+		}
+	}
+	if len(types) > 0 {
+		next.types = maps.Clone(old.types)
+		for _, typeInfo := range types {
+			next.types[typeInfo.Name] = typeInfo
+		}
+	}
+	t.current.Store(next)
+}
+
+// 符号表竞争测试（-symtab-bench）：与大规模测试相同规模的符号表，
+// 固定的总操作数平均分给不同数量的 goroutine，比较各实现的吞吐量
+
+// symbolTableGoroutines 测试的 goroutine 数量
+var symbolTableGoroutines = []int{1, 2, 4, 8, 16, 32, 64}
+
+// symbolTableOps 每次采样的总操作数
+const symbolTableOps = 200000
+
+// fillSymbolTable 按 createLargeProject 的规模填充 fileCount 个文件的符号和类型，返回全部符号
+func fillSymbolTable(table GlobalSymbolTable, fileCount int) []*Symbol {
+	var all []*Symbol
+	for i := 0; i < fileCount; i++ {
+		symbols := make([]*Symbol, 50)
+		for j := range symbols {
+			symbols[j] = &Symbol{Name: fmt.Sprintf("symbol_%d_%d", i, j), Type: "function", Scope: j / 10}
+		}
+		table.Define(symbols, []*TypeInfo{syntheticTypeInfo(i)})
+		all = append(all, symbols...)
+	}
+	return all
+}
+
+// symbolTableWorker 执行 ops 次操作：按 writeRate 的比例重新写入一个符号，
+// 其余操作中每 10 次有 1 次类型比较（按名称查找两个类型并判断可赋值性），其他为 resolveSymbolWithGlobal。
+// 每个 worker 使用自己的关系缓存，竞争只来自符号表
+func symbolTableWorker(table GlobalSymbolTable, symbols []*Symbol, ops int, writeRate float64, rng *workloadRand) {
+	relation := newSymbolTableRelation(table, nil)
+	types := len(symbols) / 50
+	for i := 0; i < ops; i++ {
+		symbol := symbols[rng.intn(len(symbols))]
+		switch {
+		case writeRate > 0 && rng.float64() < writeRate:
+			table.Define([]*Symbol{symbol}, nil)
+		case i%10 == 0:
+			source := table.LookupType(fmt.Sprintf("Type_%d", rng.intn(types)))
+			target := table.LookupType(fmt.Sprintf("Type_%d", rng.intn(types)))
+			relation.isAssignableTo(source.Structure(), target.Structure())
+		default:
+			resolveSymbolWithGlobal(symbol.Name, table)
+		}
+	}
+}
+
+// benchmarkSymbolTables 对每种实现和每个 goroutine 数量测量吞吐量，最后输出对比表
+func benchmarkSymbolTables(fileCount int, seed uint32, writeRate float64, measure MeasureConfig) []BenchmarkResult {
+	fmt.Printf("符号表竞争测试: %d 个文件的符号（%d 个符号，%d 个类型），每次采样 %d 次操作，写入比例 %g\n",
+		fileCount, fileCount*50, fileCount, symbolTableOps, writeRate)
+
+	var results []BenchmarkResult
+	throughput := make(map[SymbolTableKind][]*TimingStats)
+	for _, kind := range symbolTableKinds {
+		table := newGlobalSymbolTable(kind)
+		symbols := fillSymbolTable(table, fileCount)
+		for _, goroutines := range symbolTableGoroutines {
+			ops := symbolTableOps / goroutines
+			params := map[string]interface{}{
+				"backend":    kind.String(),
+				"goroutines": goroutines,
+				"symbols":    len(symbols),
+				"ops":        ops * goroutines,
+				"writeRate":  writeRate,
+				"seed":       seed,
+			}
+			result := measureBenchmark("symbol-table-contention", params, measure, nil, func() {
+				var wg sync.WaitGroup
+				for g := 0; g < goroutines; g++ {
+					wg.Add(1)
+					go func(rng *workloadRand) {
+						defer wg.Done()
+						symbolTableWorker(table, symbols, ops, writeRate, rng)
+					}(newWorkloadRand(deriveSeed(seed, g)))
+				}
+				wg.Wait()
+			})
+			result.Metrics = map[string]float64{
+				"opsPerSec": float64(ops*goroutines) / (result.WallTimeMs / 1000),
+			}
+			results = append(results, result)
+			throughput[kind] = append(throughput[kind], result.Stats)
+			fmt.Printf("  %-8s %2d 个 goroutine: %s，%.2f 百万次操作/秒\n",
+				kind, goroutines, result.Stats, result.Metrics["opsPerSec"]/1e6)
+		}
+	}
+
+	// 对比表：每行一个 goroutine 数量，括号中为相对 rwmutex 的加速比
+	fmt.Printf("\n吞吐量（百万次操作/秒，括号中为相对 rwmutex 的加速比，* 表示差异不显著）:\n")
+	fmt.Printf("  %10s", "goroutine")
+	for _, kind := range symbolTableKinds {
+		fmt.Printf("  %-16s", kind)
+	}
+	fmt.Println()
+	for i, goroutines := range symbolTableGoroutines {
+		fmt.Printf("  %10d", goroutines)
+		base := throughput[SymbolTableRWMutex][i]
+		for _, kind := range symbolTableKinds {
+			stats := throughput[kind][i]
+			cell := fmt.Sprintf("%.2f", float64(symbolTableOps/goroutines*goroutines)/stats.MedianMs/1000)
+			if kind != SymbolTableRWMutex {
+				cell += fmt.Sprintf(" (%.2fx)", base.MedianMs/stats.MedianMs)
+				if different, ok := significantlyDifferent(base, stats); ok && !different {
+					cell += "*"
+				}
+			}
+			fmt.Printf("  %-16s", cell)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("  CPU 核心数: %d\n", runtime.NumCPU())
+	return results
+}
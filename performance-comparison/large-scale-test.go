@@ -1,70 +1,29 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"math"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"runtime"
+	"runtime/trace"
+	"strconv"
 	"sync"
-	"sync/atomic"
 	"time"
 )
 
-// 基础类型定义
-type NodeKind int
-
-const (
-	FunctionDeclaration NodeKind = iota + 1
-	VariableDeclaration
-	CallExpression
-	BinaryExpression
-	Identifier
-)
-
-type ASTNode struct {
-	Kind     NodeKind
-	Name     string
-	Children []*ASTNode
-	Parent   *ASTNode
-}
-
-type Symbol struct {
-	Name  string
-	Type  string
-	Scope int
-}
-
-// 生成 AST 的函数
-func generateAST(depth, breadth int) *ASTNode {
-	kinds := []NodeKind{
-		FunctionDeclaration,
-		VariableDeclaration,
-		CallExpression,
-		BinaryExpression,
-		Identifier,
-	}
-
-	node := &ASTNode{
-		Kind:     kinds[rand.Intn(len(kinds))],
-		Name:     fmt.Sprintf("node_%d", rand.Int()),
-		Children: make([]*ASTNode, 0),
-	}
-
-	if depth > 0 {
-		for i := 0; i < breadth; i++ {
-			child := generateAST(depth-1, breadth)
-			child.Parent = node
-			node.Children = append(node.Children, child)
-		}
-	}
-
-	return node
-}
-
 // 模拟大型项目的数据结构
 type LargeProject struct {
 	Files         []*SourceFile
-	GlobalSymbols *GlobalSymbolTable
-	Dependencies  map[string][]string
+	GlobalSymbols GlobalSymbolTable
+	Dependencies  map[string][]string // 文件路径 -> 它导入的文件路径
+	Relations     *TypeRelation       // 类型检查的可赋值关系缓存，每次完整检查前由 resetTypeRelation 清空
+	Interner      *TypeInterner       // internTypes 之后不为 nil
+
+	fileByPath map[string]*SourceFile
 }
 
 type SourceFile struct {
@@ -72,49 +31,81 @@ type SourceFile struct {
 	Content []byte
 	AST     *ASTNode
 	Symbols []*Symbol
+	Imports []*ImportDeclaration
+	Types   []*TypeInfo // 文件中声明的类型
 	Size    int
-}
 
-type GlobalSymbolTable struct {
-	mu      sync.RWMutex
-	symbols map[string]*Symbol
-	types   map[string]*TypeInfo
+	// 上一次检查时记录的哈希，见 checkIncrementalChanges
+	ContentHash   uint64
+	SignatureHash uint64
+
+	exports map[string]*Symbol // 按名称索引的 Symbols，供其他文件的 import 查找
 }
 
+// TypeInfo 文件中声明的类型。Properties 的键以 ? 结尾表示可选属性，值为类型文本（见 parseTypeText），
+// 可以使用 TypeParams 中的类型参数；方法的签名都是 (): void。
+// 驻留（见 internTypes）之后 Properties 和 Methods 置为 nil，成员只能通过 Structure() 访问
 type TypeInfo struct {
 	Name       string
+	TypeParams []TypeParameterInfo // 不为空时是泛型类型
 	Properties map[string]string
 	Methods    []string
+	ID         TypeID // 驻留后的类型编号，未驻留时为 0
+
+	structureOnce sync.Once
+	structure     *Type
+}
+
+// TypeParameterInfo 泛型类型的一个类型参数，Constraint 为 extends 之后的类型文本，没有约束时为空
+type TypeParameterInfo struct {
+	Name       string
+	Constraint string
 }
 
-// 创建大型项目模拟
-func createLargeProject(fileCount int) *LargeProject {
-	fmt.Printf("正在创建项目结构...")
+// newLargeProject 创建包含 fileCount 个空位的项目，由调用方填充 Files，
+// 全局符号表使用 symbolTable 对应的实现
+func newLargeProject(fileCount int, symbolTable SymbolTableKind) *LargeProject {
 	project := &LargeProject{
 		Files:         make([]*SourceFile, fileCount),
-		GlobalSymbols: &GlobalSymbolTable{
-			symbols: make(map[string]*Symbol),
-			types:   make(map[string]*TypeInfo),
-		},
-		Dependencies: make(map[string][]string),
+		GlobalSymbols: newGlobalSymbolTable(symbolTable),
+		Dependencies:  make(map[string][]string),
+		fileByPath:    make(map[string]*SourceFile, fileCount),
 	}
+	project.resetTypeRelation()
+	return project
+}
+
+// 创建大型项目模拟。第 i 个文件的 AST 只取决于 seed 和 i，与 JS/TS 版本逐字节一致；
+// 依赖图等 Go 独有的部分使用由 seed 初始化的 math/rand
+func createLargeProject(fileCount int, deps DependencyConfig, seed uint32, symbolTable SymbolTableKind) *LargeProject {
+	return generateLargeProject(fileCount, largeProjectASTDepth, deps, seed, symbolTable)
+}
+
+// largeProjectASTDepth 每个文件 AST 的深度（每个节点 3 个子节点），与 JS/TS 版本一致
+const largeProjectASTDepth = 6 // 减少 AST 深度以提高速度
+
+// generateLargeProject 与 createLargeProject 相同，但可以指定 AST 深度。
+// 深度不是 largeProjectASTDepth 时工作负载与 JS/TS 版本不同
+func generateLargeProject(fileCount, astDepth int, deps DependencyConfig, seed uint32, symbolTable SymbolTableKind) *LargeProject {
+	project := newLargeProject(fileCount, symbolTable)
+	progress := NewProgressReporter("正在创建项目结构", fileCount)
 
 	// 创建大量文件
 	for i := 0; i < fileCount; i++ {
-		// 显示进度
-		if i%50 == 0 || i == fileCount-1 {
-			progress := float64(i+1) / float64(fileCount) * 100
-			fmt.Printf("\r正在创建项目结构... %.1f%% (%d/%d)", progress, i+1, fileCount)
-		}
-		
+		// 每个文件使用独立派生的种子，文件的 AST 与项目规模和生成顺序无关
+		ast := generateWorkloadAST(newWorkloadRand(deriveSeed(seed, i)), astDepth, 3)
+
 		file := &SourceFile{
 			Path:    fmt.Sprintf("src/file_%d.ts", i),
 			Content: make([]byte, 10000), // 10KB 每个文件
-			AST:     generateAST(6, 3),   // 减少 AST 深度以提高速度
+			AST:     ast,
 			Symbols: make([]*Symbol, 50), // 减少符号数量
 			Size:    10000,
+			exports: make(map[string]*Symbol, 50),
 		}
 
+		assignSyntheticPositions(file.AST)
+
 		// 填充符号
 		for j := 0; j < 50; j++ {
 			symbol := &Symbol{
@@ -123,232 +114,235 @@ func createLargeProject(fileCount int) *LargeProject {
 				Scope: j / 10,
 			}
 			file.Symbols[j] = symbol
-			
-			// 添加到全局符号表
-			project.GlobalSymbols.symbols[symbol.Name] = symbol
+			file.exports[symbol.Name] = symbol
 		}
 
 		// 添加类型信息
-		typeInfo := &TypeInfo{
-			Name:       fmt.Sprintf("Type_%d", i),
-			Properties: make(map[string]string),
-			Methods:    make([]string, 10), // 减少方法数量
-		}
-		
-		for k := 0; k < 10; k++ {
-			typeInfo.Properties[fmt.Sprintf("prop_%d", k)] = "string"
-			typeInfo.Methods[k] = fmt.Sprintf("method_%d", k)
-		}
-		
-		project.GlobalSymbols.types[typeInfo.Name] = typeInfo
+		file.Types = []*TypeInfo{syntheticTypeInfo(i)}
+
+		// 添加到全局符号表
+		project.GlobalSymbols.Define(file.Symbols, file.Types)
 		project.Files[i] = file
+		project.fileByPath[file.Path] = file
+		progress.Add(1)
+	}
+	progress.Finish()
+
+	buildDependencyGraph(project, deps, rand.New(rand.NewSource(int64(seed))))
+	for _, file := range project.Files {
+		checkIncrementalChanges(file, project.GlobalSymbols)
 	}
-	
-	fmt.Printf("\n项目创建完成！\n")
+
+	fmt.Println("项目创建完成！")
 	return project
 }
 
-// 单线程处理
-func processProjectSingleThread(project *LargeProject) time.Duration {
-	start := time.Now()
-	total := len(project.Files)
-	
+// projectFingerprint 项目全部文件 AST 的工作负载指纹
+func projectFingerprint(project *LargeProject) string {
+	asts := make([]*ASTNode, len(project.Files))
 	for i, file := range project.Files {
-		if i%20 == 0 || i == total-1 {
-			progress := float64(i+1) / float64(total) * 100
-			fmt.Printf("\r  单线程处理进度: %.1f%% (%d/%d)", progress, i+1, total)
+		asts[i] = file.AST
+	}
+	return fingerprintASTs(asts...)
+}
+
+// 单线程处理。ctx 被取消后不再开始新的文件，返回已完成的部分
+func processProjectSingleThread(ctx context.Context, project *LargeProject, diagnostics *DiagnosticCollector) CheckResult {
+	completion := newCompletionTracker(len(project.Files))
+	progress := NewProgressReporter("单线程处理进度", len(project.Files))
+
+	for _, file := range project.Files {
+		if ctx.Err() != nil {
+			break
+		}
+		fileCtx, task := startFileTask(ctx, file.Path)
+		fileDiagnostics, err := processFile(fileCtx, file, project)
+		task.End()
+		if err != nil {
+			break
 		}
-		processFile(file, project.GlobalSymbols)
+		diagnostics.add(fileDiagnostics...)
+		completion.done(file.Path)
+		progress.Add(1)
 	}
-	fmt.Printf("\n")
-	
-	return time.Since(start)
+	progress.Finish()
+
+	return completion.result(ctx)
 }
 
-// 并发处理
-func processProjectConcurrent(project *LargeProject) time.Duration {
-	start := time.Now()
-	total := len(project.Files)
-	processed := int64(0)
-	
+// 并发处理：strategy 为 StrategyGoroutine 时每个文件一个 goroutine，否则使用 NumCPU 个 worker 的任务池。
+// ctx 被取消后等待信号量或排队中的文件直接跳过
+func processProjectConcurrent(ctx context.Context, project *LargeProject, diagnostics *DiagnosticCollector, strategy ConcurrencyStrategy) CheckResult {
+	completion := newCompletionTracker(len(project.Files))
+	progress := NewProgressReporter("并发处理进度", len(project.Files))
+
+	check := func(ctx context.Context, f *SourceFile) {
+		fileDiagnostics, err := processFile(ctx, f, project)
+		if err != nil {
+			return
+		}
+		diagnostics.add(fileDiagnostics...)
+		completion.done(f.Path)
+		progress.Add(1)
+	}
+
 	var wg sync.WaitGroup
 	semaphore := make(chan struct{}, runtime.NumCPU())
-	
-	// 启动进度监控
-	done := make(chan bool)
-	go func() {
-		ticker := time.NewTicker(100 * time.Millisecond)
-		defer ticker.Stop()
-		
-		for {
-			select {
-			case <-ticker.C:
-				current := processed
-				progress := float64(current) / float64(total) * 100
-				fmt.Printf("\r  并发处理进度: %.1f%% (%d/%d)", progress, current, total)
-			case <-done:
-				return
+
+	if strategy == StrategyGoroutine {
+		for _, file := range project.Files {
+			if ctx.Err() != nil {
+				break
 			}
+			wg.Add(1)
+			go func(f *SourceFile) {
+				defer wg.Done()
+				fileCtx, task := startFileTask(ctx, f.Path)
+				defer task.End()
+				if acquireSemaphore(fileCtx, semaphore) != nil {
+					return
+				}
+				defer func() { <-semaphore }()
+
+				check(fileCtx, f)
+			}(file)
 		}
-	}()
-	
-	for _, file := range project.Files {
-		wg.Add(1)
-		go func(f *SourceFile) {
-			defer wg.Done()
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
-			
-			processFile(f, project.GlobalSymbols)
-			atomic.AddInt64(&processed, 1)
-		}(file)
-	}
-	
-	wg.Wait()
-	done <- true
-	fmt.Printf("\n")
-	
-	return time.Since(start)
+		wg.Wait()
+	} else {
+		pool := NewWorkerPool(runtime.NumCPU(), strategy == StrategySteal)
+		for _, file := range project.Files {
+			pool.Submit(func(int) {
+				if ctx.Err() != nil {
+					return
+				}
+				fileCtx, task := startFileTask(ctx, file.Path)
+				defer task.End()
+
+				check(fileCtx, file)
+			})
+		}
+		pool.Wait()
+	}
+	progress.Finish()
+
+	return completion.result(ctx)
 }
 
-// 高并发处理（模拟真实编译器）
-func processProjectHighConcurrency(project *LargeProject) time.Duration {
-	start := time.Now()
-	total := len(project.Files)
-	processed := int64(0)
-	
-	var wg sync.WaitGroup
+// 高并发处理（模拟真实编译器）：按 import 依赖顺序调度，
+// 一个文件在它导入的文件检查完成后才开始检查。ctx 被取消后不再调度新的分量
+func processProjectHighConcurrency(ctx context.Context, project *LargeProject, diagnostics *DiagnosticCollector, strategy ConcurrencyStrategy) (CheckResult, ScheduleStats) {
+	completion := newCompletionTracker(len(project.Files))
+	progress := NewProgressReporter("高并发处理进度", len(project.Files))
+
 	// 使用更多的 goroutine
-	semaphore := make(chan struct{}, runtime.NumCPU()*4)
-	
-	// 启动进度监控
-	done := make(chan bool)
-	go func() {
-		ticker := time.NewTicker(100 * time.Millisecond)
-		defer ticker.Stop()
-		
-		for {
-			select {
-			case <-ticker.C:
-				current := processed
-				progress := float64(current) / float64(total) * 100
-				fmt.Printf("\r  高并发处理进度: %.1f%% (%d/%d)", progress, current, total)
-			case <-done:
-				return
-			}
-		}
-	}()
-	
-	for _, file := range project.Files {
-		wg.Add(1)
-		go func(f *SourceFile) {
-			defer wg.Done()
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
-			
+	stats := scheduleByDependencies(ctx, project, runtime.NumCPU()*4, strategy,
+		func(ctx context.Context, f *SourceFile) error {
+			ctx, task := startFileTask(ctx, f.Path)
+			defer task.End()
 			// 更复杂的处理
-			processFileWithDependencies(f, project.GlobalSymbols, project.Dependencies)
-			atomic.AddInt64(&processed, 1)
-		}(file)
-	}
-	
-	wg.Wait()
-	done <- true
-	fmt.Printf("\n")
-	
-	return time.Since(start)
+			fileDiagnostics, err := processFileWithDependencies(ctx, f, project)
+			if err != nil {
+				return err
+			}
+			diagnostics.add(fileDiagnostics...)
+			completion.done(f.Path)
+			return nil
+		},
+		func() { progress.Add(1) })
+	progress.Finish()
+
+	return completion.result(ctx), stats
 }
 
-// 模拟文件处理
-func processFile(file *SourceFile, globalSymbols *GlobalSymbolTable) {
+// 模拟文件处理，返回该文件的诊断。ctx 为文件的 trace 任务，每个步骤是一个区域；
+// ctx 被取消时在下一个 AST 节点或步骤之间退出，返回 ctx.Err()，已产生的诊断丢弃
+func processFile(ctx context.Context, file *SourceFile, project *LargeProject) ([]*Diagnostic, error) {
+	var diagnostics []*Diagnostic
+
 	// 模拟 AST 遍历
-	nodeCount := visitNodeComplex(file.AST)
-	
+	region := trace.StartRegion(ctx, regionASTVisit)
+	nodeCount, err := visitNodeComplex(ctx, file.AST, file, &diagnostics)
+	region.End()
+	if err != nil {
+		return nil, err
+	}
+
 	// 模拟符号解析
+	region = trace.StartRegion(ctx, regionSymbolResolution)
 	for _, symbol := range file.Symbols {
-		resolveSymbolWithGlobal(symbol.Name, globalSymbols)
+		resolveSymbolWithGlobal(symbol.Name, project.GlobalSymbols)
 	}
-	
-	// 模拟类型检查
-	for i := 0; i < nodeCount/10; i++ {
-		performTypeCheck(globalSymbols)
+	region.End()
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
+
+	// 模拟类型检查
+	region = trace.StartRegion(ctx, regionTypeCheck)
+	performTypeCheck(project, file, nodeCount/10)
+	region.End()
+
+	return diagnostics, nil
 }
 
-// 带依赖关系的文件处理
-func processFileWithDependencies(file *SourceFile, globalSymbols *GlobalSymbolTable, deps map[string][]string) {
+// 带依赖关系的文件处理。被取消时不解析 import，也不更新增量检查的哈希
+func processFileWithDependencies(ctx context.Context, file *SourceFile, project *LargeProject) ([]*Diagnostic, error) {
 	// 基本处理
-	processFile(file, globalSymbols)
-	
-	// 处理依赖关系
-	if dependencies, exists := deps[file.Path]; exists {
-		for _, dep := range dependencies {
-			// 模拟依赖解析
-			resolveSymbolWithGlobal(dep, globalSymbols)
-		}
+	diagnostics, err := processFile(ctx, file, project)
+	if err != nil {
+		return nil, err
 	}
-	
+
+	// 处理依赖关系：在被导入文件的符号中查找每个导入的名称
+	region := trace.StartRegion(ctx, regionImportResolution)
+	diagnostics = append(diagnostics, resolveImports(file, project)...)
+	region.End()
+
 	// 模拟增量编译检查
-	checkIncrementalChanges(file, globalSymbols)
+	region = trace.StartRegion(ctx, regionIncrementalCheck)
+	checkIncrementalChanges(file, project.GlobalSymbols)
+	region.End()
+
+	return diagnostics, nil
 }
 
-// 复杂的 AST 遍历
-func visitNodeComplex(node *ASTNode) int {
+// 复杂的 AST 遍历，发现的问题追加到 diagnostics。
+// 每个节点开始前检查 ctx，被取消时返回 ctx.Err()
+func visitNodeComplex(ctx context.Context, node *ASTNode, file *SourceFile, diagnostics *[]*Diagnostic) (int, error) {
 	if node == nil {
-		return 0
+		return 0, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
 	}
-	
+
 	count := 1
-	
+
+	// 生成的 AST 中没有子节点的调用表达式缺少被调用表达式
+	if node.Kind == CallExpression && len(node.Children) == 0 {
+		*diagnostics = append(*diagnostics, newDiagnostic(file.Path, node, DiagExpressionExpected, "Expression expected."))
+	}
+
 	// 减少模拟操作以提高速度
 	for i := 0; i < 50; i++ {
 		_ = fmt.Sprintf("op_%s_%d", node.Name, i)
 	}
-	
+
 	// 递归处理子节点
 	for _, child := range node.Children {
-		count += visitNodeComplex(child)
-	}
-	
-	return count
-}
-
-// 全局符号解析
-func resolveSymbolWithGlobal(name string, globalSymbols *GlobalSymbolTable) *Symbol {
-	globalSymbols.mu.RLock()
-	defer globalSymbols.mu.RUnlock()
-	
-	if symbol, exists := globalSymbols.symbols[name]; exists {
-		return symbol
-	}
-	
-	return nil
-}
-
-// 类型检查
-func performTypeCheck(globalSymbols *GlobalSymbolTable) {
-	globalSymbols.mu.RLock()
-	defer globalSymbols.mu.RUnlock()
-	
-	// 简化类型推导
-	count := 0
-	for _, typeInfo := range globalSymbols.types {
-		for range typeInfo.Properties {
-			count++
-			if count > 5 { // 限制处理数量
-				return
-			}
+		childCount, err := visitNodeComplex(ctx, child, file, diagnostics)
+		if err != nil {
+			return count, err
 		}
+		count += childCount
 	}
+
+	return count, nil
 }
 
-// 增量编译检查
-func checkIncrementalChanges(file *SourceFile, globalSymbols *GlobalSymbolTable) {
-	// 简化增量编译逻辑
-	for i := 0; i < 10; i++ {
-		symbolName := fmt.Sprintf("inc_%d", i)
-		resolveSymbolWithGlobal(symbolName, globalSymbols)
-	}
+// 全局符号解析
+func resolveSymbolWithGlobal(name string, globalSymbols GlobalSymbolTable) *Symbol {
+	return globalSymbols.LookupSymbol(name)
 }
 
 // 内存使用统计
@@ -358,44 +352,470 @@ func getMemStats() (float64, float64) {
 	return float64(m.Alloc) / 1024 / 1024, float64(m.Sys) / 1024 / 1024
 }
 
+// projectBenchmarkOptions 每个项目共用的测试选项
+type projectBenchmarkOptions struct {
+	measure       MeasureConfig
+	mutate        int     // 增量检查前修改的文件数，0 表示跳过增量检查
+	signatureRate float64 // 被修改的文件中导出签名发生变化的比例
+	mutateSeed    int64
+	symbolTable   SymbolTableKind     // 全局符号表的实现
+	strategy      ConcurrencyStrategy // 并发和高并发模式的并发策略
+	deadline      time.Duration       // 大于 0 时另外以此为时限运行每种模式一次，报告部分结果
+	queries       bool                // 只测试转到定义和查找引用，不运行检查流程
+	typeRelations bool                // 只测试类型之间的可赋值性判断，不运行检查流程
+	intern        bool                // 检查前驻留项目中的类型
+}
+
+// benchmarkProject 依次测量单线程、并发和高并发三种处理模式，输出诊断，
+// 然后修改部分文件做一次增量检查。params 会记录在每一项结果中
+func benchmarkProject(project *LargeProject, params map[string]interface{}, options projectBenchmarkOptions) []BenchmarkResult {
+	if options.queries {
+		return benchmarkQueries(project, params, options.measure)
+	}
+	if options.typeRelations {
+		return benchmarkTypeRelations(project, params, options.measure)
+	}
+	if options.intern {
+		project.internTypes()
+		fmt.Printf("类型驻留: %s\n", project.Interner.Stats())
+	}
+
+	edges, names := countDependencyEdges(project)
+	fmt.Printf("依赖图: %v，%d 条 import，导入 %d 个符号\n", params["graph"], edges, names)
+	graphMetrics := func(diagnostics *DiagnosticCollector) map[string]float64 {
+		return map[string]float64{
+			"importEdges":   float64(edges),
+			"importedNames": float64(names),
+			"diagnostics":   float64(diagnostics.len()),
+		}
+	}
+
+	allocBefore, _ := getMemStats()
+
+	// 每种模式按 measure 预热后多次采样，每次采样使用新的诊断收集器和关系缓存
+	var singleDiagnostics, concurrentDiagnostics, highConcurrentDiagnostics *DiagnosticCollector
+
+	// 单线程测试
+	fmt.Println("单线程处理...")
+	singleResult := measureBenchmark("large-scale-single-thread", params, options.measure, func() {
+		singleDiagnostics = NewDiagnosticCollector()
+		project.resetTypeRelation()
+	}, func() {
+		processProjectSingleThread(context.Background(), project, singleDiagnostics)
+	})
+	singleResult.Metrics = graphMetrics(singleDiagnostics)
+	relations := project.Relations.Stats()
+	instantiations := project.Relations.InstantiationStats()
+	singleResult.Metrics["typeChecks"] = float64(relations.Checks)
+	singleResult.Metrics["relationHitRate"] = relations.HitRate()
+	singleResult.Metrics["instantiations"] = float64(instantiations.Instances)
+	singleResult.Metrics["instantiationRequests"] = float64(instantiations.Requests)
+	singleResult.Metrics["instantiationHitRate"] = instantiations.HitRate()
+	singleResult.Metrics["constraintViolations"] = float64(instantiations.ConstraintViolations)
+
+	// 并发测试
+	fmt.Println("并发处理...")
+	concurrentResult := measureBenchmark("large-scale-concurrent", params, options.measure, func() {
+		concurrentDiagnostics = NewDiagnosticCollector()
+		project.resetTypeRelation()
+	}, func() {
+		processProjectConcurrent(context.Background(), project, concurrentDiagnostics, options.strategy)
+	})
+	concurrentResult.Metrics = graphMetrics(concurrentDiagnostics)
+	concurrentResult.Metrics["speedup"] = singleResult.WallTimeMs / concurrentResult.WallTimeMs
+
+	// 高并发测试
+	fmt.Println("高并发处理...")
+	var schedule ScheduleStats
+	highConcurrentResult := measureBenchmark("large-scale-high-concurrency", params, options.measure, func() {
+		highConcurrentDiagnostics = NewDiagnosticCollector()
+		project.resetTypeRelation()
+	}, func() {
+		_, schedule = processProjectHighConcurrency(context.Background(), project, highConcurrentDiagnostics, options.strategy)
+	})
+	highConcurrentResult.Metrics = graphMetrics(highConcurrentDiagnostics)
+	highConcurrentResult.Metrics["speedup"] = singleResult.WallTimeMs / highConcurrentResult.WallTimeMs
+	highConcurrentResult.Metrics["components"] = float64(schedule.Components)
+	highConcurrentResult.Metrics["cyclicComponents"] = float64(schedule.CyclicComponents)
+	highConcurrentResult.Metrics["criticalPathWaves"] = float64(schedule.CriticalPathLen)
+	highConcurrentResult.Metrics["criticalPathMs"] = float64(schedule.CriticalPath.Nanoseconds()) / 1000000
+	if options.strategy == StrategySteal {
+		highConcurrentResult.Metrics["steals"] = float64(schedule.Steals)
+	}
+
+	allocAfter, _ := getMemStats()
+	results := []BenchmarkResult{singleResult, concurrentResult, highConcurrentResult}
+
+	// 结果
+	fmt.Printf("\n结果:\n")
+	fmt.Printf("  单线程耗时: %s\n", singleResult.Stats)
+	fmt.Printf("  并发耗时: %s\n", concurrentResult.Stats)
+	fmt.Printf("  高并发耗时: %s\n", highConcurrentResult.Stats)
+	fmt.Printf("  关键路径: %.2f ms，%d 波（最后一次采样）\n",
+		float64(schedule.CriticalPath.Nanoseconds())/1000000, schedule.CriticalPathLen)
+	fmt.Printf("  并发提升: %s\n", speedupString(singleResult.Stats, concurrentResult.Stats))
+	fmt.Printf("  高并发提升: %s\n", speedupString(singleResult.Stats, highConcurrentResult.Stats))
+	fmt.Printf("  类型关系（单线程最后一次采样）: %s\n", relations)
+	fmt.Printf("  泛型实例化（单线程最后一次采样）: %s\n", instantiations)
+	fmt.Printf("  内存使用: %.2f MB\n", allocAfter-allocBefore)
+	fmt.Printf("  CPU 核心数: %d\n", runtime.NumCPU())
+	fmt.Printf("  调度单元: %d 个强连通分量（%d 个含环，最大 %d 个文件）\n",
+		schedule.Components, schedule.CyclicComponents, schedule.LargestComponent)
+	if options.strategy == StrategySteal {
+		fmt.Printf("  任务窃取: %d 个分量（最后一次采样）\n", schedule.Steals)
+	}
+
+	// 诊断：排序后与处理顺序无关，单线程与并发的摘要应当一致；
+	// 高并发模式还会解析 import，因此额外包含模块解析诊断
+	fmt.Printf("\n诊断:\n")
+	fmt.Printf("  单线程: %s（摘要 %s）\n", singleDiagnostics.summary(), singleDiagnostics.fingerprint())
+	fmt.Printf("  并发: %s（摘要 %s）\n", concurrentDiagnostics.summary(), concurrentDiagnostics.fingerprint())
+	fmt.Printf("  高并发（含模块解析）: %s（摘要 %s）\n", highConcurrentDiagnostics.summary(), highConcurrentDiagnostics.fingerprint())
+	if singleDiagnostics.fingerprint() != concurrentDiagnostics.fingerprint() {
+		fmt.Println("  警告: 并发模式的诊断结果与单线程不一致")
+	}
+	writeDiagnostics(os.Stdout, singleDiagnostics.sorted(), "    ", 5)
+
+	// 限时检查在增量检查修改文件之前进行，部分结果可以和上面的完整结果对比
+	if options.deadline > 0 {
+		results = append(results, benchmarkDeadline(params, options.deadline, []deadlineMode{
+			{"single-thread", "单线程", func(ctx context.Context, d *DiagnosticCollector) CheckResult {
+				return processProjectSingleThread(ctx, project, d)
+			}, singleDiagnostics},
+			{"concurrent", "并发", func(ctx context.Context, d *DiagnosticCollector) CheckResult {
+				return processProjectConcurrent(ctx, project, d, options.strategy)
+			}, concurrentDiagnostics},
+			{"high-concurrency", "高并发", func(ctx context.Context, d *DiagnosticCollector) CheckResult {
+				result, _ := processProjectHighConcurrency(ctx, project, d, options.strategy)
+				return result
+			}, highConcurrentDiagnostics},
+		})...)
+	}
+
+	// 增量检查：修改部分文件后只重新检查受影响的文件
+	if options.mutate > 0 {
+		signatureChanges := mutateFiles(project, options.mutate, options.signatureRate, rand.New(rand.NewSource(options.mutateSeed)))
+		incrementalParams := map[string]interface{}{
+			"mutate":        options.mutate,
+			"signatureRate": options.signatureRate,
+		}
+		for _, key := range []string{"files", "seed", "graph", "source"} {
+			if value, ok := params[key]; ok {
+				incrementalParams[key] = value
+			}
+		}
+		// 修改只能应用一次，增量检查只测量一次
+		profile := options.measure.Profile.begin("large-scale-incremental", incrementalParams)
+		run := startBenchmark("large-scale-incremental", incrementalParams)
+		incremental := recheckIncremental(project, NewDiagnosticCollector())
+		results = append(results, run.stop(map[string]float64{
+			"changed":          float64(incremental.Changed),
+			"signatureChanged": float64(incremental.SignatureChanged),
+			"rechecked":        float64(incremental.Rechecked),
+			"waves":            float64(incremental.Waves),
+		}))
+		profile.end()
+		fmt.Printf("\n增量检查:\n")
+		fmt.Printf("  修改文件: %d（其中 %d 个修改了导出签名）\n", incremental.Changed, signatureChanges)
+		fmt.Printf("  重新检查: %d 个文件，%d 波，%d 个文件的导出签名发生变化\n",
+			incremental.Rechecked, incremental.Waves, incremental.SignatureChanged)
+		fmt.Printf("  增量耗时: %.2f ms（全量高并发 %.2f ms，%.2fx）\n",
+			float64(incremental.Duration.Nanoseconds())/1000000, highConcurrentResult.WallTimeMs,
+			highConcurrentResult.WallTimeMs/(float64(incremental.Duration.Nanoseconds())/1000000))
+	}
+	return results
+}
+
 func main() {
-	fmt.Println("=== 大规模 Go 并发测试 ===\n")
-	
-	// 调整测试规模，使其更合理
-	fileCounts := []int{50, 200, 500}
-	
-	for _, fileCount := range fileCounts {
+	graph := flag.String("graph", "dag", "依赖图形状: none, chain, hub, dag, cycle")
+	imports := flag.Int("imports", 3, "dag/cycle 模式下每个文件最多导入的文件数")
+	missing := flag.Float64("missing", 0, "导入不存在符号的概率，用于产生模块解析诊断")
+	mutate := flag.Int("mutate", 10, "增量检查前修改的文件数（0 表示跳过增量检查）")
+	signatureRate := flag.Float64("sigchange", 0.3, "被修改的文件中导出签名发生变化的比例")
+	jsonOutput := flag.Bool("json", false, "在标准输出输出 JSON 格式的结果，文字报告改写到标准错误")
+	warmup := flag.Int("warmup", 1, "每种处理模式的预热次数")
+	samples := flag.Int("samples", 3, "每种处理模式的采样次数")
+	seed := flag.Uint("seed", 1, "工作负载的随机种子，相同种子在 Go/JS/TS 中生成相同的 AST")
+	writeCorpusDir := flag.String("write-corpus", "", "把每种规模的项目写成语料目录（<目录>/<文件数>/）后退出")
+	corpusRoot := flag.String("corpus", "", "从语料目录读取项目，而不是在内存中生成")
+	repoRoot := flag.String("repo", "", "读取并检查一个真实的 TypeScript 仓库（.ts/.tsx 文件），而不是生成项目")
+	profilePhases := flag.String("profile", "", "逗号分隔的阶段名（例如 concurrent,high-concurrency），或 all；只剖析这些阶段")
+	profileDir := flag.String("profile-dir", "profiles", "剖析文件的输出目录")
+	profileKindList := flag.String("profile-kinds", "cpu,heap,allocs,mutex,block", "采集的剖析类型")
+	symbolTableName := flag.String("symtab", "rwmutex", "全局符号表的实现: rwmutex, syncmap, sharded, snapshot")
+	symbolTableBench := flag.Bool("symtab-bench", false, "只运行符号表竞争测试：比较各实现的吞吐量随 goroutine 数量的变化")
+	symbolTableWrites := flag.Float64("symtab-writes", 0, "符号表竞争测试中写操作的比例")
+	strategyName := flag.String("pool", "goroutine", "并发和高并发模式的并发策略: goroutine（每个文件一个 goroutine）, queue（共享队列任务池）, steal（工作窃取任务池）")
+	poolBench := flag.Bool("pool-bench", false, "只运行并发策略对比：三种策略在不同文件数下的耗时、goroutine 数和内存")
+	poolFiles := flag.String("pool-files", "50,200,500,10000", "并发策略对比的文件数，逗号分隔")
+	poolDepth := flag.Int("pool-depth", poolBenchmarkASTDepth, "并发策略对比中每个文件 AST 的深度")
+	files := flag.String("files", "50,200,500", "测试的项目规模（文件数），逗号分隔")
+	deadline := flag.Duration("deadline", 0, "大于 0 时，完整测试后再以此为时限（例如 20ms）运行每种模式一次，报告部分结果和取消延迟")
+	serve := flag.Bool("serve", false, "作为常驻检查服务运行：通过标准输入输出接收 JSON-RPC 请求（Content-Length 分帧），项目取 -files 的第一个规模或 -repo")
+	serveBench := flag.Int("serve-bench", 0, "大于 0 时只运行编辑器会话测试：在进程内启动检查服务，进行这么多轮修改、诊断和符号解析，报告每种请求的延迟")
+	queryBench := flag.Bool("query-bench", false, "只测试转到定义和查找引用：建立项目的引用索引并测量两类查询（可与 -repo、-corpus 一起使用）")
+	typesBench := flag.Bool("types-bench", false, "只测试类型关系：对项目中的类型两两判断可赋值性，比较冷缓存和热缓存（可与 -repo、-corpus 一起使用）")
+	intern := flag.Bool("intern", false, "检查前驻留项目中的类型：结构相同的类型只保留一个实例，符号通过 TypeID 引用类型")
+	astBench := flag.Bool("ast-bench", false, "只运行 AST 布局对比：指针 AST 与扁平 AST 的分配次数、GC 耗时和遍历速度（项目规模取 -files）")
+	internBench := flag.Bool("intern-bench", false, "只运行类型驻留对比：不驻留和驻留类型时的堆大小和类型检查耗时（项目规模取 -files）")
+	progressName := flag.String("progress", "auto", "进度显示: auto, tty（原地刷新）, plain（每秒一行）, quiet（不显示）")
+	flag.Parse()
+
+	// JSON 模式下标准输出只保留 JSON，其余输出全部转到标准错误；检查服务模式下标准输出只用于协议消息
+	stdout := os.Stdout
+	if *jsonOutput || *serve {
+		os.Stdout = os.Stderr
+	}
+
+	deps := defaultDependencyConfig()
+	shape, err := parseGraphShape(*graph)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	deps.Shape = shape
+	if *imports < 0 {
+		fmt.Fprintf(os.Stderr, "-imports: 无效的数量 %d\n", *imports)
+		os.Exit(2)
+	}
+	if !(*missing >= 0 && *missing <= 1) { // 同时拒绝 NaN
+		fmt.Fprintf(os.Stderr, "-missing: 概率 %g 不在 [0, 1] 内\n", *missing)
+		os.Exit(2)
+	}
+	deps.ImportsPerFile = *imports
+	deps.MissingRate = *missing
+	if *seed > math.MaxUint32 { // JS/TS 以 >>> 0 取 32 位种子，更大的值在各语言中不再对应同一工作负载
+		fmt.Fprintf(os.Stderr, "-seed: 种子 %d 超出 32 位无符号整数的范围\n", *seed)
+		os.Exit(2)
+	}
+
+	symbolTable, err := parseSymbolTableKind(*symbolTableName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	strategy, err := parseConcurrencyStrategy(*strategyName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	poolFileCounts, err := parseIntList(*poolFiles)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "-pool-files:", err)
+		os.Exit(2)
+	}
+	fileCounts, err := parseIntList(*files)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "-files:", err)
+		os.Exit(2)
+	}
+	if progressMode, err = parseProgressMode(*progressName); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	profiler, err := NewProfiler(*profileDir, *profilePhases, *profileKindList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	measure := MeasureConfig{Warmup: *warmup, Samples: *samples, Profile: profiler}
+	options := projectBenchmarkOptions{measure: measure, mutate: *mutate, signatureRate: *signatureRate, symbolTable: symbolTable, strategy: strategy, deadline: *deadline, queries: *queryBench, typeRelations: *typesBench, intern: *intern}
+
+	if *writeCorpusDir != "" {
+		for _, fileCount := range fileCounts {
+			project := createLargeProject(fileCount, deps, uint32(*seed), symbolTable)
+			dir := filepath.Join(*writeCorpusDir, strconv.Itoa(fileCount))
+			manifest := CorpusManifest{
+				Seed:           uint32(*seed),
+				Workload:       projectFingerprint(project),
+				Graph:          deps.Shape.String(),
+				ImportsPerFile: deps.ImportsPerFile,
+				MissingRate:    deps.MissingRate,
+			}
+			if err := writeCorpus(project, dir, manifest); err != nil {
+				fmt.Fprintln(os.Stderr, "写入语料失败:", err)
+				os.Exit(1)
+			}
+			fmt.Printf("已写入 %s（工作负载指纹 %s）\n", dir, manifest.Workload)
+		}
+		return
+	}
+
+	// 每种规模的项目来源：默认在内存中生成，-corpus 时读取语料目录
+	var corpusDirs []string
+	if *corpusRoot != "" {
+		if corpusDirs, err = findCorpusDirs(*corpusRoot); err != nil {
+			fmt.Fprintln(os.Stderr, "读取语料失败:", err)
+			os.Exit(1)
+		}
+		fileCounts = make([]int, len(corpusDirs)) // 文件数以各语料的 manifest 为准
+	}
+
+	fmt.Println("=== 大规模 Go 并发测试 ===")
+	if *repoRoot != "" {
+		fmt.Printf("每种处理模式预热 %d 次，采样 %d 次，读取仓库 %s\n", measure.Warmup, measure.Samples, *repoRoot)
+	} else if corpusDirs != nil {
+		fmt.Printf("每种处理模式预热 %d 次，采样 %d 次，读取语料 %s\n", measure.Warmup, measure.Samples, *corpusRoot)
+	} else {
+		fmt.Printf("每种处理模式预热 %d 次，采样 %d 次，随机种子 %d\n", measure.Warmup, measure.Samples, *seed)
+	}
+	if symbolTable != SymbolTableRWMutex {
+		fmt.Printf("全局符号表: %s\n", symbolTable)
+	}
+	if strategy != StrategyGoroutine {
+		fmt.Printf("并发策略: %s\n", strategy)
+	}
+	if profiler != nil {
+		fmt.Printf("剖析阶段 %s，输出到 %s（被剖析阶段的耗时包含剖析开销）\n", *profilePhases, profiler.Dir)
+	}
+	fmt.Println()
+
+	if *serve || *serveBench > 0 {
+		// 检查服务的项目：真实仓库，或者 -files 中第一个规模的生成项目
+		var project *LargeProject
+		var params map[string]interface{}
+		if *repoRoot != "" {
+			var stats RepositoryStats
+			if project, stats, err = loadRepository(*repoRoot, symbolTable); err != nil {
+				fmt.Fprintln(os.Stderr, "读取仓库失败:", err)
+				os.Exit(1)
+			}
+			params = map[string]interface{}{"files": stats.Files, "source": "repository"}
+		} else {
+			project = createLargeProject(fileCounts[0], deps, uint32(*seed), symbolTable)
+			params = map[string]interface{}{"files": fileCounts[0], "seed": *seed, "graph": deps.Shape.String()}
+		}
+		params["symbolTable"] = symbolTable.String()
+		params["pool"] = strategy.String()
+
+		if *serve {
+			// 标准输出只用于协议消息
+			server := NewCheckServer(project, strategy)
+			server.Log = os.Stderr
+			fmt.Fprintln(os.Stderr, "检查服务已启动，等待请求...")
+			err := server.Serve(os.Stdin, stdout)
+			fmt.Fprintln(os.Stderr, "检查服务结束，各方法的处理耗时:")
+			server.writeStats(os.Stderr)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "检查服务出错:", err)
+				os.Exit(1)
+			}
+			return
+		}
+		results := benchmarkServer(project, params, *serveBench, *signatureRate, strategy, int64(*seed))
+		if *jsonOutput {
+			if err := writeResultsJSON(stdout, results); err != nil {
+				fmt.Fprintln(os.Stderr, "输出 JSON 失败:", err)
+				os.Exit(1)
+			}
+		}
+		return
+	}
+
+	var results []BenchmarkResult
+	switch {
+	case *poolBench:
+		results = benchmarkWorkerPools(poolFileCounts, *poolDepth, deps, uint32(*seed), symbolTable, measure)
+		fileCounts = nil
+	case *astBench:
+		results = benchmarkASTLayouts(fileCounts, largeProjectASTDepth, uint32(*seed), measure)
+		fileCounts = nil
+	case *internBench:
+		results = benchmarkInterning(fileCounts, deps, uint32(*seed), symbolTable, measure)
+		fileCounts = nil
+	case *symbolTableBench:
+		results = benchmarkSymbolTables(fileCounts[len(fileCounts)-1], uint32(*seed), *symbolTableWrites, measure)
+		fileCounts = nil
+	case *repoRoot != "":
+		options.mutateSeed = int64(*seed)
+		results = benchmarkRepository(*repoRoot, options)
+		fileCounts = nil
+	}
+
+	for run, fileCount := range fileCounts {
+		projectSeed := uint32(*seed)
+		projectDeps := deps
+		var manifest *CorpusManifest
+		if corpusDirs != nil {
+			if manifest, err = readCorpusManifest(corpusDirs[run]); err == nil {
+				projectDeps.Shape, err = parseGraphShape(manifest.Graph)
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "读取语料失败:", err)
+				os.Exit(1)
+			}
+			fileCount = len(manifest.Files)
+			projectSeed = manifest.Seed
+			projectDeps.ImportsPerFile = manifest.ImportsPerFile
+			projectDeps.MissingRate = manifest.MissingRate
+		}
+
 		fmt.Printf("测试项目规模: %d 个文件\n", fileCount)
 		fmt.Println("----------------------------------------")
-		
-		// 创建项目
-		project := createLargeProject(fileCount)
-		
-		allocBefore, _ := getMemStats()
-		
-		// 单线程测试
-		fmt.Println("单线程处理...")
-		singleTime := processProjectSingleThread(project)
-		
-		// 并发测试
-		fmt.Println("并发处理...")
-		concurrentTime := processProjectConcurrent(project)
-		
-		// 高并发测试
-		fmt.Println("高并发处理...")
-		highConcurrentTime := processProjectHighConcurrency(project)
-		
-		allocAfter, _ := getMemStats()
-		
-		// 结果
-		fmt.Printf("\n结果:\n")
-		fmt.Printf("  单线程耗时: %.2f ms\n", float64(singleTime.Nanoseconds())/1000000)
-		fmt.Printf("  并发耗时: %.2f ms\n", float64(concurrentTime.Nanoseconds())/1000000)
-		fmt.Printf("  高并发耗时: %.2f ms\n", float64(highConcurrentTime.Nanoseconds())/1000000)
-		fmt.Printf("  并发提升: %.2fx\n", float64(singleTime.Nanoseconds())/float64(concurrentTime.Nanoseconds()))
-		fmt.Printf("  高并发提升: %.2fx\n", float64(singleTime.Nanoseconds())/float64(highConcurrentTime.Nanoseconds()))
-		fmt.Printf("  内存使用: %.2f MB\n", allocAfter-allocBefore)
-		fmt.Printf("  CPU 核心数: %d\n\n", runtime.NumCPU())
-	}
-
-} 
\ No newline at end of file
+
+		// 创建项目，或者读取语料（读取计入测量）
+		var project *LargeProject
+		var workload string
+		if manifest == nil {
+			project = createLargeProject(fileCount, projectDeps, projectSeed, symbolTable)
+			workload = projectFingerprint(project)
+		} else {
+			workload = manifest.Workload
+		}
+
+		params := map[string]interface{}{
+			"files":          fileCount,
+			"seed":           projectSeed,
+			"workload":       workload,
+			"graph":          projectDeps.Shape.String(),
+			"importsPerFile": projectDeps.ImportsPerFile,
+			"missingRate":    projectDeps.MissingRate,
+			"symbolTable":    symbolTable.String(),
+			"pool":           strategy.String(),
+		}
+
+		if manifest != nil {
+			params["source"] = "corpus"
+			fmt.Printf("读取语料 %s...\n", corpusDirs[run])
+			var loadErr error
+			loadResult := measureBenchmark("large-scale-load", params, measure, nil, func() {
+				project, loadErr = loadCorpus(corpusDirs[run], manifest, symbolTable)
+			})
+			if loadErr != nil {
+				fmt.Fprintln(os.Stderr, "读取语料失败:", loadErr)
+				os.Exit(1)
+			}
+			if loaded := projectFingerprint(project); loaded != workload {
+				fmt.Fprintf(os.Stderr, "语料 %s 的工作负载指纹 %s 与 manifest 记录的 %s 不一致\n", corpusDirs[run], loaded, workload)
+				os.Exit(1)
+			}
+			totalBytes := 0
+			for _, file := range project.Files {
+				totalBytes += file.Size
+			}
+			loadResult.Metrics = map[string]float64{"bytes": float64(totalBytes)}
+			results = append(results, loadResult)
+			fmt.Printf("  读取耗时: %s，共 %.1f KB\n", loadResult.Stats, float64(totalBytes)/1024)
+		}
+
+		fmt.Printf("工作负载指纹: %s（与相同种子的 JS/TS 测试一致）\n", workload)
+		options.mutateSeed = int64(projectSeed)
+		results = append(results, benchmarkProject(project, params, options)...)
+		fmt.Println()
+	}
+
+	printInstantiationScaling(results)
+
+	if profiler != nil && profiler.Written() == 0 {
+		fmt.Fprintf(os.Stderr, "警告: -profile %s 没有匹配任何阶段\n", *profilePhases)
+	}
+
+	if *jsonOutput {
+		if err := writeResultsJSON(stdout, results); err != nil {
+			fmt.Fprintln(os.Stderr, "输出 JSON 失败:", err)
+			os.Exit(1)
+		}
+	}
+}
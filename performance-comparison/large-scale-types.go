@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// methodSignature TypeInfo 中方法的签名 (): void
+var methodSignature = NewFunctionType(nil, primitiveTypes[TypeVoid])
+
+// Structure 返回类型的结构化表示（第一次调用时由 TypeParams、Properties 和 Methods 构建），
+// 属性类型中除类型参数以外的类型名是引用类型，比较时通过全局符号表解析
+func (t *TypeInfo) Structure() *Type {
+	t.structureOnce.Do(func() {
+		var typeParams []*Type
+		scope := make(map[string]*Type, len(t.TypeParams))
+		for _, param := range t.TypeParams {
+			var constraint *Type
+			if param.Constraint != "" {
+				constraint = parseTypeText(param.Constraint, scope)
+			}
+			typeParam := NewTypeParameter(param.Name, constraint)
+			typeParams = append(typeParams, typeParam)
+			scope[param.Name] = typeParam
+		}
+		properties := make([]*Property, 0, len(t.Properties)+len(t.Methods))
+		for name, text := range t.Properties {
+			name, optional := strings.CutSuffix(name, "?")
+			properties = append(properties, &Property{Name: name, Type: parseTypeText(text, scope), Optional: optional})
+		}
+		for _, name := range t.Methods {
+			properties = append(properties, &Property{Name: name, Type: methodSignature})
+		}
+		if len(typeParams) > 0 {
+			t.structure = NewGenericType(t.Name, typeParams, properties)
+		} else {
+			t.structure = NewObjectType(t.Name, properties)
+		}
+	})
+	return t.structure
+}
+
+// typeParamsText 返回类型参数的声明形式 <T extends C, U>，不是泛型类型时返回空字符串
+func (t *TypeInfo) typeParamsText() string {
+	if len(t.TypeParams) == 0 {
+		return ""
+	}
+	params := make([]string, len(t.TypeParams))
+	for i, param := range t.TypeParams {
+		params[i] = param.Name
+		if param.Constraint != "" {
+			params[i] += " extends " + param.Constraint
+		}
+	}
+	return "<" + strings.Join(params, ", ") + ">"
+}
+
+// parseTypeParamsText 解析 typeParamsText 的结果（不含两侧的尖括号）
+func parseTypeParamsText(text string) []TypeParameterInfo {
+	var params []TypeParameterInfo
+	for _, part := range splitTopLevel(text, ',') {
+		name, constraint, _ := strings.Cut(strings.TrimSpace(part), " extends ")
+		params = append(params, TypeParameterInfo{Name: name, Constraint: constraint})
+	}
+	return params
+}
+
+// syntheticPropertyTypes 生成的类型中属性可以使用的类型
+var syntheticPropertyTypes = []string{TypeString, TypeNumber, "string | undefined", TypeBoolean}
+
+// syntheticTypeArguments 生成的类型引用泛型类型时使用的类型实参，boolean 不满足泛型类型的约束
+var syntheticTypeArguments = []string{TypeString, TypeNumber, TypeBoolean}
+
+// syntheticGenericInterval 每隔多少个文件声明一个泛型类型
+const syntheticGenericInterval = 4
+
+// syntheticTypeInfo 生成项目中第 i 个文件声明的类型 Type_i：6 到 10 个属性，prop_8 之后的属性是可选的，
+// 属性类型按 i%3 分为三族，另有 10 个方法。同一族中属性不少于目标必需属性的类型可以赋给目标，
+// 不同族之间的属性类型不兼容，因此类型之间的可赋值关系有成立的也有不成立的。
+// 每 4 个文件中第一个的类型是泛型类型 Type_i<T extends string | number>，多一个类型为 T 的属性 value；
+// 其余类型多一个属性 box，引用前面最近的泛型类型，类型实参按 i%3 轮换，
+// 因此泛型实例的数量随项目规模增长（泛型类型本身不引用泛型类型，比较不会沿引用链展开）
+func syntheticTypeInfo(i int) *TypeInfo {
+	typeInfo := &TypeInfo{
+		Name:       fmt.Sprintf("Type_%d", i),
+		Properties: make(map[string]string),
+		Methods:    make([]string, 10), // 减少方法数量
+	}
+	family := i % 3
+	for k := 0; k < 6+i%5; k++ {
+		name := fmt.Sprintf("prop_%d", k)
+		if k >= 8 {
+			name += "?"
+		}
+		typeInfo.Properties[name] = syntheticPropertyTypes[(k+family)%len(syntheticPropertyTypes)]
+	}
+	for k := range typeInfo.Methods {
+		typeInfo.Methods[k] = fmt.Sprintf("method_%d", k)
+	}
+	if i%syntheticGenericInterval == 0 {
+		typeInfo.TypeParams = []TypeParameterInfo{{Name: "T", Constraint: "string | number"}}
+		typeInfo.Properties["value"] = "T"
+	} else {
+		generic := i / syntheticGenericInterval * syntheticGenericInterval
+		typeInfo.Properties["box"] = fmt.Sprintf("Type_%d<%s>", generic, syntheticTypeArguments[i%len(syntheticTypeArguments)])
+	}
+	return typeInfo
+}
+
+// typeStructure 返回类型的结构：已驻留（interner 不为 nil 且 ID 不为 0）时是编号对应的驻留实例，否则为 Structure()
+func typeStructure(typeInfo *TypeInfo, interner *TypeInterner) *Type {
+	if interner != nil && typeInfo.ID != 0 {
+		return interner.Type(typeInfo.ID)
+	}
+	return typeInfo.Structure()
+}
+
+// newSymbolTableRelation 创建通过 globalSymbols 解析引用类型的关系缓存，interner 不为 nil 时引用解析为驻留的类型
+func newSymbolTableRelation(globalSymbols GlobalSymbolTable, interner *TypeInterner) *TypeRelation {
+	return NewTypeRelation(func(name string) *Type {
+		if typeInfo := globalSymbols.LookupType(name); typeInfo != nil {
+			return typeStructure(typeInfo, interner)
+		}
+		return nil
+	})
+}
+
+// declaredSymbolType 导出符号的类型：函数为 () => void，变量为 number（与语料中的声明一致），
+// class/interface 为同名类型的引用，其他（type、enum 等）为 any
+func declaredSymbolType(symbol *Symbol) *Type {
+	switch symbol.Type {
+	case "function":
+		return methodSignature
+	case "variable":
+		return primitiveTypes[TypeNumber]
+	case "class", "interface":
+		return NewReferenceType(symbol.Name)
+	}
+	return primitiveTypes[TypeAny]
+}
+
+// setSymbolType 修改导出符号的种类（function 或 variable），项目的类型已驻留时同时更新 TypeID
+func (p *LargeProject) setSymbolType(symbol *Symbol, symbolType string) {
+	symbol.Type = symbolType
+	if p.Interner != nil {
+		symbol.TypeID = p.Interner.Intern(declaredSymbolType(symbol))
+	}
+}
+
+// internTypes 驻留项目中全部类型的结构和导出符号的类型：类型检查通过 TypeInfo.ID 取驻留的实例，
+// Structure() 此后也返回驻留的实例，文本形式的成员不再保留；Symbol.TypeID 指向驻留的类型，导出签名的哈希以它为准。需要在检查开始前、没有其他 goroutine 访问项目时调用
+func (p *LargeProject) internTypes() {
+	p.Interner = NewTypeInterner()
+	for _, file := range p.Files {
+		for _, typeInfo := range file.Types {
+			typeInfo.ID = p.Interner.Intern(typeInfo.Structure())
+			typeInfo.structure = p.Interner.Type(typeInfo.ID)
+			typeInfo.Properties, typeInfo.Methods = nil, nil
+		}
+		for _, symbol := range file.Symbols {
+			symbol.TypeID = p.Interner.Intern(declaredSymbolType(symbol))
+		}
+	}
+	p.resetTypeRelation()
+}
+
+// resetTypeRelation 清空项目的关系缓存，相当于为新的一次检查创建检查器
+func (p *LargeProject) resetTypeRelation() {
+	p.Relations = newSymbolTableRelation(p.GlobalSymbols, p.Interner)
+}
+
+// 类型检查：把文件中声明的类型与它导入的文件中声明的类型互相比较（模拟两个方向的赋值和参数传递），
+// 共 checks 轮，依次轮换类型对，重复的类型对由关系缓存回答。文件没有导入时与自身的类型比较
+func performTypeCheck(project *LargeProject, file *SourceFile, checks int) {
+	if len(file.Types) == 0 {
+		return
+	}
+	structures := func(types []*TypeInfo) []*Type {
+		result := make([]*Type, len(types))
+		for i, typeInfo := range types {
+			result[i] = typeStructure(typeInfo, project.Interner)
+		}
+		return result
+	}
+	var imported []*TypeInfo
+	for _, decl := range file.Imports {
+		if dependency := project.fileByPath[decl.From]; dependency != nil {
+			imported = append(imported, dependency.Types...)
+		}
+	}
+	sources := structures(file.Types)
+	targets := sources
+	if len(imported) > 0 {
+		targets = structures(imported)
+	}
+	for i := 0; i < checks; i++ {
+		source := sources[i%len(sources)]
+		target := targets[(i/len(sources))%len(targets)]
+		project.Relations.isAssignableTo(source, target)
+		project.Relations.isAssignableTo(target, source)
+	}
+}
+
+// typeRelationSampleTypes 类型关系测试最多比较的类型数（两两比较，按名称排序后取前面的类型）
+const typeRelationSampleTypes = 300
+
+// benchmarkTypeRelations 对项目中的类型两两判断可赋值性：冷缓存时每次采样使用新的关系缓存，
+// 每个类型对都要做结构比较；热缓存时重复使用同一个缓存，测量缓存命中的开销
+func benchmarkTypeRelations(project *LargeProject, params map[string]interface{}, measure MeasureConfig) []BenchmarkResult {
+	var types []*Type
+	project.GlobalSymbols.RangeTypes(func(typeInfo *TypeInfo) bool {
+		types = append(types, typeInfo.Structure())
+		return true
+	})
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+	if len(types) > typeRelationSampleTypes {
+		types = types[:typeRelationSampleTypes]
+	}
+	pairs := len(types) * len(types)
+	if pairs == 0 {
+		fmt.Println("项目中没有类型，跳过类型关系测试")
+		return nil
+	}
+
+	var relation *TypeRelation
+	assignable := 0
+	compareAll := func() {
+		assignable = 0
+		for _, source := range types {
+			for _, target := range types {
+				if relation.isAssignableTo(source, target) {
+					assignable++
+				}
+			}
+		}
+	}
+
+	fmt.Printf("类型关系: %d 个类型，%d 个类型对\n", len(types), pairs)
+	coldResult := measureBenchmark("large-scale-type-relation-cold", params, measure, func() {
+		relation = newSymbolTableRelation(project.GlobalSymbols, project.Interner)
+	}, compareAll)
+	cold := relation.Stats()
+	instantiations := relation.InstantiationStats()
+	coldResult.Metrics = map[string]float64{
+		"pairs":                float64(pairs),
+		"assignable":           float64(assignable),
+		"computed":             float64(cold.Computed),
+		"cached":               float64(cold.Cached),
+		"nsPerPair":            coldResult.WallTimeMs * 1000000 / float64(pairs),
+		"instantiations":       float64(instantiations.Instances),
+		"instantiationHitRate": instantiations.HitRate(),
+	}
+
+	warmResult := measureBenchmark("large-scale-type-relation-warm", params, measure, nil, compareAll)
+	warm := relation.Stats()
+	warm.Lookups -= cold.Lookups
+	warm.Hits -= cold.Hits
+	warmResult.Metrics = map[string]float64{
+		"pairs":      float64(pairs),
+		"assignable": float64(assignable),
+		"hitRate":    warm.HitRate(),
+		"nsPerPair":  warmResult.WallTimeMs * 1000000 / float64(pairs),
+	}
+
+	fmt.Printf("\n类型关系:\n")
+	fmt.Printf("  冷缓存: %s，每对 %.0f ns\n", coldResult.Stats, coldResult.Metrics["nsPerPair"])
+	fmt.Printf("  热缓存: %s，每对 %.0f ns\n", warmResult.Stats, warmResult.Metrics["nsPerPair"])
+	fmt.Printf("  可赋值: %d / %d 个类型对\n", assignable, pairs)
+	fmt.Printf("  关系缓存（冷缓存的最后一次采样）: %s\n", cold)
+	fmt.Printf("  泛型实例化（冷缓存的最后一次采样）: %s\n", instantiations)
+	return []BenchmarkResult{coldResult, warmResult}
+}
+
+// printInstantiationScaling 测试了多个项目规模时，按单线程结果输出泛型实例化数和缓存命中率随规模的变化
+func printInstantiationScaling(results []BenchmarkResult) {
+	var rows []BenchmarkResult
+	for _, result := range results {
+		if result.Benchmark == "large-scale-single-thread" {
+			rows = append(rows, result)
+		}
+	}
+	if len(rows) < 2 {
+		return
+	}
+	fmt.Println("泛型实例化随项目规模的变化（单线程，每次采样使用新的缓存）:")
+	for _, result := range rows {
+		fmt.Printf("  %v 个文件: %.0f 次实例化请求，%.0f 个实例（命中率 %.1f%%），其中 %.0f 个违反约束\n", result.Parameters["files"],
+			result.Metrics["instantiationRequests"], result.Metrics["instantiations"],
+			result.Metrics["instantiationHitRate"]*100, result.Metrics["constraintViolations"])
+	}
+	fmt.Println()
+}
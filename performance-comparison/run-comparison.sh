@@ -51,7 +51,7 @@ echo ""
 # 运行 Go 测试
 echo "运行 Go 测试..."
 echo "================================"
-go run go-test.go
+go run go-test.go compiler-*.go
 
 echo ""
 echo "=== 对比完成 ==="
@@ -61,4 +61,5 @@ echo "1. JavaScript: 动态类型，JIT 编译优化"
 echo "2. TypeScript: 静态类型检查，编译为 JavaScript"
 echo "3. Go: 静态类型，AOT 编译，原生机器码"
 echo ""
-echo "请查看上面的输出结果进行详细性能对比"
\ No newline at end of file
+echo "请查看上面的输出结果进行详细性能对比"
+echo "生成对比表格: go run compare-results.go compiler-report.go -run small"
\ No newline at end of file
@@ -38,7 +38,7 @@ echo ""
 # 运行 Go 版本
 echo "🟩 运行 Go 大规模测试..."
 echo "----------------------------------------"
-go run large-scale-test.go
+go run large-scale-*.go compiler-*.go
 echo ""
 
 echo "=== 测试完成 ==="
@@ -52,4 +52,6 @@ echo "🔍 关键观察点:"
 echo "• 内存使用效率"
 echo "• 并发处理能力"
 echo "• 大规模数据处理性能"
-echo "• 垃圾回收效率" 
\ No newline at end of file
+echo "• 垃圾回收效率"
+echo ""
+echo "生成对比表格: go run compare-results.go compiler-report.go -run large" 
\ No newline at end of file
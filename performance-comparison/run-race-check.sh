@@ -0,0 +1,67 @@
+#!/bin/bash
+
+# 用 Go 的竞态检测器（-race）运行所有 Go 测试程序的各种处理模式。
+# 检测到数据竞争时程序以非零状态退出，脚本最后汇总并以非零状态退出。
+# 为了在竞态检测的开销下尽快完成，使用很小的项目规模和一次采样
+
+echo "=== Go 数据竞争检查 ==="
+echo ""
+
+cd "$(dirname "$0")"
+
+if ! command -v go &> /dev/null; then
+    echo "错误: 需要安装 Go"
+    exit 1
+fi
+
+if [ "$(go env CGO_ENABLED)" != "1" ]; then
+    echo "错误: -race 需要启用 cgo（CGO_ENABLED=1）以及 C 编译器"
+    exit 1
+fi
+
+failed=0
+log=$(mktemp)
+corpus=$(mktemp -d)
+trap 'rm -rf "$log" "$corpus"' EXIT
+
+# check 描述 命令...：运行命令，输出只在失败时显示
+check() {
+    local name="$1"
+    shift
+    printf "%s ... " "$name"
+    if "$@" > "$log" 2>&1; then
+        echo "通过"
+    else
+        echo "失败"
+        grep -A 30 "WARNING: DATA RACE" "$log" | head -60
+        tail -5 "$log"
+        failed=1
+    fi
+}
+
+quick="-warmup 0 -samples 2"
+# 强制 tty 进度：进度 goroutine 与处理 goroutine 并发读写计数
+large="$quick -files 20,60 -mutate 5 -progress tty"
+
+check "基础测试" go run -race go-test.go compiler-*.go $quick
+
+for pool in goroutine queue steal; do
+    check "大规模测试（-pool $pool）" go run -race large-scale-*.go compiler-*.go $large -pool $pool
+    check "大规模测试（-pool $pool，有环）" go run -race large-scale-*.go compiler-*.go $large -pool $pool -graph cycle -missing 0.01
+done
+
+for symtab in syncmap sharded snapshot; do
+    check "大规模测试（-symtab $symtab）" go run -race large-scale-*.go compiler-*.go $large -symtab $symtab
+done
+
+check "写入语料" go run -race large-scale-*.go compiler-*.go -files 20 -write-corpus "$corpus"
+check "读取语料" go run -race large-scale-*.go compiler-*.go $large -corpus "$corpus"
+check "检查服务会话" go run -race large-scale-*.go compiler-*.go -files 20 -serve-bench 20 -sigchange 0.5 -progress tty
+check "并发策略对比" go run -race large-scale-*.go compiler-*.go $quick -pool-bench -pool-files 40 -pool-depth 3 -progress tty
+
+echo ""
+if [ $failed -ne 0 ]; then
+    echo "发现数据竞争或运行失败"
+    exit 1
+fi
+echo "没有发现数据竞争"
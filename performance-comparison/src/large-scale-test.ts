@@ -1,5 +1,35 @@
 // TypeScript 大规模性能测试
 
+const largeTestFs: typeof import('fs') = require('fs');
+const largeTestPath: typeof import('path') = require('path');
+
+// --json：标准输出只输出 JSON 格式的结果（字段与 Go 程序的 -json 输出一致），文字报告和进度改写到标准错误
+const largeTestJsonOutput = process.argv.includes('--json');
+const largeTestWriteStdout = process.stdout.write.bind(process.stdout);
+if (largeTestJsonOutput) {
+  console.log = console.error;
+  process.stdout.write = process.stderr.write.bind(process.stderr) as typeof process.stdout.write;
+}
+
+// --seed=N 或 --seed N：工作负载的随机种子（默认 1）。相同种子生成的 AST 与 Go 测试逐字节一致
+const largeTestSeed = Number(largeTestOptionValue(process.argv, 'seed') ?? 1) >>> 0;
+
+// --corpus=DIR 或 --corpus DIR：读取 Go 程序 -write-corpus 写出的语料目录，而不是在内存中生成项目
+const largeTestCorpusRoot = largeTestOptionValue(process.argv, 'corpus');
+
+// 读取 --name=value 或 --name value 形式的参数，不存在时返回 undefined
+function largeTestOptionValue(argv: string[], name: string): string | undefined {
+  for (let i = 0; i < argv.length; i++) {
+    if (argv[i].startsWith(`--${name}=`)) {
+      return argv[i].slice(name.length + 3);
+    }
+    if (argv[i] === `--${name}` && i + 1 < argv.length) {
+      return argv[i + 1];
+    }
+  }
+  return undefined;
+}
+
 // 基础类型定义
 enum LargeTestNodeKind {
   FunctionDeclaration = 1,
@@ -35,8 +65,59 @@ class TSSymbol {
   }
 }
 
+// 可复现的工作负载生成：LargeTestRandom 与 Go 测试中的 workloadRand 使用同一算法（mulberry32），
+// 相同种子产生完全相同的随机数序列。修改算法时必须同步修改 Go 版本（compiler-workload.go）
+class LargeTestRandom {
+  private state: number;
+
+  constructor(seed: number) {
+    this.state = seed >>> 0;
+  }
+
+  // next 返回 32 位无符号整数，只使用 Math.imul 等 32 位运算，与 Go 的 uint32 运算结果一致
+  next(): number {
+    this.state = (this.state + 0x6D2B79F5) >>> 0;
+    let t = this.state;
+    t = Math.imul(t ^ (t >>> 15), t | 1);
+    t ^= t + Math.imul(t ^ (t >>> 7), t | 61);
+    return (t ^ (t >>> 14)) >>> 0;
+  }
+
+  // intn 返回 [0, n) 内的整数
+  intn(n: number): number {
+    return this.next() % n;
+  }
+}
+
+// 为第 index 个文件派生独立的种子，使每个文件的 AST 只取决于 seed 和文件编号
+function largeTestDeriveSeed(seed: number, index: number): number {
+  return (seed + Math.imul(index + 1, 0x9E3779B9)) >>> 0;
+}
+
+// 一组 AST 规范形式（前序遍历，每个节点一行 "类型编号 名称 子节点数"）的 FNV-1a 32 位哈希，
+// 与 Go 测试输出的工作负载指纹可以直接比较
+function largeTestFingerprint(roots: LargeTestASTNode[]): string {
+  let hash = 0x811c9dc5;
+  const write = (text: string): void => {
+    for (let i = 0; i < text.length; i++) {
+      hash ^= text.charCodeAt(i);
+      hash = Math.imul(hash, 0x01000193) >>> 0;
+    }
+  };
+  const visit = (node: LargeTestASTNode): void => {
+    write(`${node.kind} ${node.name} ${node.children.length}\n`);
+    for (const child of node.children) {
+      visit(child);
+    }
+  };
+  for (const root of roots) {
+    visit(root);
+  }
+  return hash.toString(16).padStart(8, '0');
+}
+
 // 生成 AST 的函数
-function generateAST(depth: number, breadth: number): LargeTestASTNode {
+function generateAST(rng: LargeTestRandom, depth: number, breadth: number): LargeTestASTNode {
   const kinds: LargeTestNodeKind[] = [
     LargeTestNodeKind.FunctionDeclaration,
     LargeTestNodeKind.VariableDeclaration,
@@ -46,13 +127,13 @@ function generateAST(depth: number, breadth: number): LargeTestASTNode {
   ];
 
   const node = new LargeTestASTNode(
-    kinds[Math.floor(Math.random() * kinds.length)],
-    `node_${Math.floor(Math.random() * 1000000)}`
+    kinds[rng.intn(kinds.length)],
+    `node_${rng.next().toString(36)}`
   );
 
   if (depth > 0) {
     for (let i = 0; i < breadth; i++) {
-      const child = generateAST(depth - 1, breadth);
+      const child = generateAST(rng, depth - 1, breadth);
       child.parent = node;
       node.children.push(child);
     }
@@ -76,17 +157,17 @@ class LargeProject {
 
 class SourceFile {
   path: string;
-  content: number[];
+  content: number[] | Buffer;
   ast: LargeTestASTNode;
   symbols: TSSymbol[];
   size: number;
 
-  constructor(path: string, size: number) {
+  constructor(path: string, content: number[] | Buffer, ast: LargeTestASTNode) {
     this.path = path;
-    this.content = new Array(size).fill(0); // 模拟文件内容
-    this.ast = generateAST(6, 3); // 减少 AST 深度以提高速度
+    this.content = content;
+    this.ast = ast;
     this.symbols = [];
-    this.size = size;
+    this.size = content.length;
   }
 }
 
@@ -132,8 +213,8 @@ class TypeInfo {
   }
 }
 
-// 创建大型项目模拟
-function createLargeProject(fileCount: number): LargeProject {
+// 创建大型项目模拟。第 i 个文件的 AST 只取决于 seed 和 i，与 Go 版本逐字节一致
+function createLargeProject(fileCount: number, seed: number): LargeProject {
   process.stdout.write('正在创建项目结构...');
   const project = new LargeProject();
 
@@ -145,7 +226,8 @@ function createLargeProject(fileCount: number): LargeProject {
       process.stdout.write(`\r正在创建项目结构... ${progress}% (${i + 1}/${fileCount})`);
     }
 
-    const file = new SourceFile(`src/file_${i}.ts`, 10000); // 10KB 每个文件
+    const ast = generateAST(new LargeTestRandom(largeTestDeriveSeed(seed, i)), 6, 3); // 减少 AST 深度以提高速度
+    const file = new SourceFile(`src/file_${i}.ts`, new Array(10000).fill(0), ast); // 模拟文件内容，10KB 每个文件
 
     // 填充符号
     for (let j = 0; j < 50; j++) {
@@ -176,6 +258,158 @@ function createLargeProject(fileCount: number): LargeProject {
   return project;
 }
 
+// 语料目录：由 Go 程序 large-scale-test.go -write-corpus 写出，包含 manifest.json 和 src/*.ts。
+// 文件格式与按行解析的方式与 large-scale-corpus.go 一致，修改时需要同步
+interface LargeTestCorpusManifest {
+  version: number;
+  seed: number;
+  workload: string;
+  graph: string;
+  importsPerFile: number;
+  missingRate: number;
+  files: { path: string; size: number }[];
+}
+
+// 返回 root 下的语料目录：root 本身有 manifest.json 时只返回 root，否则返回包含 manifest.json 的子目录，按文件数排序
+function findCorpusDirs(root: string): string[] {
+  if (largeTestFs.existsSync(largeTestPath.join(root, 'manifest.json'))) {
+    return [root];
+  }
+  const found = largeTestFs.readdirSync(root, { withFileTypes: true })
+    .filter((entry) => entry.isDirectory() && largeTestFs.existsSync(largeTestPath.join(root, entry.name, 'manifest.json')))
+    .map((entry) => {
+      const dir = largeTestPath.join(root, entry.name);
+      return { dir, files: readCorpusManifest(dir).files.length };
+    });
+  if (found.length === 0) {
+    throw new Error(`${root} 中没有找到语料（manifest.json）`);
+  }
+  found.sort((a, b) => a.files - b.files);
+  return found.map((corpus) => corpus.dir);
+}
+
+function readCorpusManifest(dir: string): LargeTestCorpusManifest {
+  const manifestPath = largeTestPath.join(dir, 'manifest.json');
+  const manifest: LargeTestCorpusManifest = JSON.parse(largeTestFs.readFileSync(manifestPath, 'utf8'));
+  if (manifest.version !== 1) {
+    throw new Error(`${manifestPath}: 不支持的语料版本 ${manifest.version}（当前为 1）`);
+  }
+  return manifest;
+}
+
+// 读取并解析 manifest 列出的全部文件，重建与 createLargeProject 相同结构的项目
+function loadCorpus(dir: string, manifest: LargeTestCorpusManifest): LargeProject {
+  const project = new LargeProject();
+  for (const entry of manifest.files) {
+    const content = largeTestFs.readFileSync(largeTestPath.join(dir, entry.path));
+    const { file, typeInfo, imports } = parseCorpusFile(entry.path, content);
+    for (const symbol of file.symbols) {
+      project.globalSymbols.addSymbol(symbol);
+    }
+    if (typeInfo) {
+      project.globalSymbols.addType(typeInfo);
+    }
+    if (imports.length > 0) {
+      project.dependencies.set(file.path, imports);
+    }
+    project.files.push(file);
+  }
+  return project;
+}
+
+// 逐行解析一个语料文件，返回文件、其中声明的类型以及导入的文件路径
+function parseCorpusFile(
+  filePath: string,
+  content: Buffer
+): { file: SourceFile; typeInfo: TypeInfo | undefined; imports: string[] } {
+  const symbols: TSSymbol[] = [];
+  const imports: string[] = [];
+  const rows: [number, string, number][] = [];
+  let typeInfo: TypeInfo | undefined;
+  let state = 'top';
+
+  const lines = content.toString('utf8').split('\n');
+  for (let i = 0; i < lines.length; i++) {
+    const line = lines[i].trim();
+    const fail = (message: string) => new Error(`${filePath}:${i + 1}: ${message} ${JSON.stringify(line)}`);
+
+    if (state === 'interface') {
+      if (line === '}') {
+        state = 'top';
+      } else if (line.endsWith('(): void;')) {
+        typeInfo!.methods.push(line.slice(0, -'(): void;'.length));
+      } else {
+        const member = line.replace(/;$/, '');
+        const separator = member.indexOf(': ');
+        if (separator < 0) {
+          throw fail('无法解析的类型成员');
+        }
+        typeInfo!.properties.set(member.slice(0, separator), member.slice(separator + 2));
+      }
+      continue;
+    }
+    if (state === 'ast') {
+      if (line === '];') {
+        state = 'top';
+      } else {
+        // 每个元素 [类型编号, 名称, 子节点数] 同时也是合法的 JSON
+        rows.push(JSON.parse(line.replace(/,$/, '')));
+      }
+      continue;
+    }
+
+    if (line === '' || line.startsWith('//')) {
+      continue;
+    } else if (line.startsWith('import {')) {
+      const match = /^import \{(.*)\} from "(.*)";$/.exec(line);
+      if (!match) {
+        throw fail('无法解析的 import');
+      }
+      imports.push(largeTestPath.posix.join(largeTestPath.posix.dirname(filePath), match[2]) + '.ts');
+    } else if (line.startsWith('export interface ')) {
+      typeInfo = new TypeInfo(line.slice('export interface '.length, -' {'.length));
+      state = 'interface';
+    } else if (line.startsWith('export function ')) {
+      const name = line.slice('export function '.length, line.indexOf('('));
+      symbols.push(new TSSymbol(name, 'function', Math.floor(symbols.length / 10)));
+    } else if (line.startsWith('export let ')) {
+      const name = line.slice('export let '.length, line.indexOf(':'));
+      symbols.push(new TSSymbol(name, 'variable', Math.floor(symbols.length / 10)));
+    } else if (line.startsWith('export const ast')) {
+      state = 'ast';
+    } else {
+      throw fail('无法解析的语句');
+    }
+  }
+  if (state !== 'top') {
+    throw new Error(`${filePath}: 文件意外结束`);
+  }
+
+  // 按前序展开的元素重建 AST
+  let next = 0;
+  const build = (): LargeTestASTNode => {
+    if (next >= rows.length) {
+      throw new Error(`${filePath}: AST 节点不足`);
+    }
+    const [kind, name, childCount] = rows[next++];
+    const node = new LargeTestASTNode(kind, name);
+    for (let c = 0; c < childCount; c++) {
+      const child = build();
+      child.parent = node;
+      node.children.push(child);
+    }
+    return node;
+  };
+  const ast = build();
+  if (next < rows.length) {
+    throw new Error(`${filePath}: AST 之后还有 ${rows.length - next} 个多余的节点`);
+  }
+
+  const file = new SourceFile(filePath, content, ast);
+  file.symbols = symbols;
+  return { file, typeInfo, imports };
+}
+
 // 单线程处理
 function processProjectSingleThread(project: LargeProject): number {
   const start = process.hrtime.bigint();
@@ -383,19 +617,86 @@ function getMemStats(): MemStats {
   };
 }
 
+// 大规模测试的机器可读结果，与 typescript-test.ts 中的 BenchmarkResult 字段一致
+interface LargeTestBenchmarkResult {
+  benchmark: string;
+  language: string;
+  parameters: { [name: string]: number | string };
+  wallTimeMs: number;
+  cpuCount: number;
+  nodeVersion: string;
+  platform: string;
+  arch: string;
+  metrics: { [name: string]: number };
+}
+
+function largeTestResult(
+  benchmark: string,
+  parameters: { [name: string]: number | string },
+  wallTimeMs: number,
+  metrics: { [name: string]: number }
+): LargeTestBenchmarkResult {
+  return {
+    benchmark,
+    language: 'typescript',
+    parameters,
+    wallTimeMs,
+    cpuCount: require('os').cpus().length,
+    nodeVersion: process.version,
+    platform: process.platform,
+    arch: process.arch,
+    metrics
+  };
+}
+
 // 主函数
 async function main(): Promise<void> {
-  console.log('=== 大规模 TypeScript 测试 ===\n');
+  // 每种规模的项目来源：默认在内存中生成，--corpus 时读取语料目录，文件数以各语料的 manifest 为准
+  const corpusDirs = largeTestCorpusRoot === undefined ? null : findCorpusDirs(largeTestCorpusRoot);
+
+  console.log('=== 大规模 TypeScript 测试 ===');
+  if (corpusDirs) {
+    console.log(`读取语料: ${largeTestCorpusRoot}\n`);
+  } else {
+    console.log(`随机种子: ${largeTestSeed}\n`);
+  }
 
   // 调整测试规模，使其更合理
-  const fileCounts: number[] = [50, 200, 500];
-
-  for (const fileCount of fileCounts) {
+  const fileCounts: number[] = corpusDirs
+    ? corpusDirs.map((dir) => readCorpusManifest(dir).files.length)
+    : [50, 200, 500];
+  const results: LargeTestBenchmarkResult[] = [];
+
+  for (let run = 0; run < fileCounts.length; run++) {
+    const fileCount = fileCounts[run];
+    const manifest = corpusDirs ? readCorpusManifest(corpusDirs[run]) : null;
     console.log(`测试项目规模: ${fileCount} 个文件`);
     console.log('----------------------------------------');
 
-    // 创建项目
-    const project = createLargeProject(fileCount);
+    // 创建项目，或者读取语料（读取计入测量）
+    let project: LargeProject;
+    let loadTime = 0;
+    if (manifest) {
+      console.log(`读取语料 ${corpusDirs![run]}...`);
+      const loadStart = process.hrtime.bigint();
+      project = loadCorpus(corpusDirs![run], manifest);
+      loadTime = Number(process.hrtime.bigint() - loadStart) / 1000000;
+    } else {
+      project = createLargeProject(fileCount, largeTestSeed);
+    }
+
+    const workload = largeTestFingerprint(project.files.map((file) => file.ast));
+    if (manifest && workload !== manifest.workload) {
+      throw new Error(`语料 ${corpusDirs![run]} 的工作负载指纹 ${workload} 与 manifest 记录的 ${manifest.workload} 不一致`);
+    }
+    const params: { [name: string]: number | string } = { files: fileCount, seed: manifest ? manifest.seed : largeTestSeed, workload };
+    if (manifest) {
+      const totalBytes = project.files.reduce((sum, file) => sum + file.size, 0);
+      console.log(`  读取耗时: ${loadTime.toFixed(2)} ms，共 ${(totalBytes / 1024).toFixed(1)} KB`);
+      params.source = 'corpus';
+      results.push(largeTestResult('large-scale-load', params, loadTime, { bytes: totalBytes }));
+    }
+    console.log(`工作负载指纹: ${workload}（与相同种子的 Go 测试一致）`);
 
     const memBefore = getMemStats();
 
@@ -422,6 +723,19 @@ async function main(): Promise<void> {
     console.log(`  高并发模拟提升: ${(singleTime / highConcurrentTime).toFixed(2)}x`);
     console.log(`  内存使用: ${(memAfter.heapUsed - memBefore.heapUsed).toFixed(2)} MB`);
     console.log(`  CPU 核心数: ${require('os').cpus().length}\n`);
+
+    results.push(
+      largeTestResult('large-scale-single-thread', params, singleTime, {}),
+      largeTestResult('large-scale-concurrent', params, concurrentTime, { speedup: singleTime / concurrentTime }),
+      largeTestResult('large-scale-high-concurrency', params, highConcurrentTime, {
+        speedup: singleTime / highConcurrentTime,
+        heapGrowthMB: memAfter.heapUsed - memBefore.heapUsed
+      })
+    );
+  }
+
+  if (largeTestJsonOutput) {
+    largeTestWriteStdout(JSON.stringify(results, null, 2) + '\n');
   }
 }
 
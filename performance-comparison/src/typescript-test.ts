@@ -1,5 +1,26 @@
 // TypeScript 性能测试
 
+// --json：标准输出只输出 JSON 格式的结果（字段与 Go 程序的 -json 输出一致），文字报告改写到标准错误
+const jsonOutput = process.argv.includes('--json');
+if (jsonOutput) {
+  console.log = console.error;
+}
+
+// --seed=N 或 --seed N：工作负载的随机种子（默认 1）。相同种子每次生成完全相同的 AST
+const seed = parseSeed(process.argv);
+
+function parseSeed(argv: string[]): number {
+  for (let i = 0; i < argv.length; i++) {
+      if (argv[i].startsWith('--seed=')) {
+          return Number(argv[i].slice('--seed='.length)) >>> 0;
+      }
+      if (argv[i] === '--seed' && i + 1 < argv.length) {
+          return Number(argv[i + 1]) >>> 0;
+      }
+  }
+  return 1;
+}
+
 enum NodeKind {
   FunctionDeclaration = 1,
   VariableDeclaration = 2,
@@ -102,8 +123,59 @@ class TypeChecker {
   }
 }
 
+// 可复现的工作负载生成：WorkloadRandom 与 Go 测试中的 workloadRand 使用同一算法（mulberry32），
+// 相同种子产生完全相同的随机数序列。修改算法时必须同步修改 Go 版本（compiler-workload.go）
+class WorkloadRandom {
+  private state: number;
+
+  constructor(seed: number) {
+      this.state = seed >>> 0;
+  }
+
+  // next 返回 32 位无符号整数，只使用 Math.imul 等 32 位运算，与 Go 的 uint32 运算结果一致
+  next(): number {
+      this.state = (this.state + 0x6D2B79F5) >>> 0;
+      let t = this.state;
+      t = Math.imul(t ^ (t >>> 15), t | 1);
+      t ^= t + Math.imul(t ^ (t >>> 7), t | 61);
+      return (t ^ (t >>> 14)) >>> 0;
+  }
+
+  // intn 返回 [0, n) 内的整数
+  intn(n: number): number {
+      return this.next() % n;
+  }
+}
+
+// deriveSeed 为第 index 个文件派生独立的种子
+function deriveSeed(seed: number, index: number): number {
+  return (seed + Math.imul(index + 1, 0x9E3779B9)) >>> 0;
+}
+
+// fingerprintASTs 返回一组 AST 规范形式（前序遍历，每个节点一行 "类型编号 名称 子节点数"）
+// 的 FNV-1a 32 位哈希，用于核对不同语言生成的工作负载是否一致
+function fingerprintASTs(roots: ASTNode[]): string {
+  let hash = 0x811c9dc5;
+  const write = (text: string): void => {
+      for (let i = 0; i < text.length; i++) {
+          hash ^= text.charCodeAt(i);
+          hash = Math.imul(hash, 0x01000193) >>> 0;
+      }
+  };
+  const visit = (node: ASTNode): void => {
+      write(`${node.kind} ${node.name} ${node.children.length}\n`);
+      for (const child of node.children) {
+          visit(child);
+      }
+  };
+  for (const root of roots) {
+      visit(root);
+  }
+  return hash.toString(16).padStart(8, '0');
+}
+
 // 生成测试数据
-function generateAST(depth: number, breadth: number): ASTNode {
+function generateAST(rng: WorkloadRandom, depth: number, breadth: number): ASTNode {
   const kinds = [
       NodeKind.FunctionDeclaration,
       NodeKind.VariableDeclaration,
@@ -113,14 +185,14 @@ function generateAST(depth: number, breadth: number): ASTNode {
   ];
   
   const node: ASTNode = {
-      kind: kinds[Math.floor(Math.random() * kinds.length)],
-      name: `node_${Math.random().toString(36).substr(2, 9)}`,
+      kind: kinds[rng.intn(kinds.length)],
+      name: `node_${rng.next().toString(36)}`,
       children: []
   };
   
   if (depth > 0) {
       for (let i = 0; i < breadth; i++) {
-          const child = generateAST(depth - 1, breadth);
+          const child = generateAST(rng, depth - 1, breadth);
           child.parent = node;
           node.children.push(child);
       }
@@ -141,15 +213,49 @@ function generateSymbols(count: number): CustomSymbol[] {
   return symbols;
 }
 
-// 性能测试
-function runPerformanceTest() {
-  console.log('=== TypeScript 性能测试 ===\n');
+interface BenchmarkResult {
+  benchmark: string;
+  language: string;
+  parameters: { [name: string]: number | string };
+  wallTimeMs: number;
+  cpuCount: number;
+  nodeVersion: string;
+  platform: string;
+  arch: string;
+  metrics: { [name: string]: number };
+}
+
+// 生成一项测试的机器可读结果
+function benchmarkResult(
+  benchmark: string,
+  parameters: { [name: string]: number | string },
+  wallTimeMs: number,
+  metrics: { [name: string]: number }
+): BenchmarkResult {
+  return {
+      benchmark,
+      language: 'typescript',
+      parameters,
+      wallTimeMs,
+      cpuCount: require('os').cpus().length,
+      nodeVersion: process.version,
+      platform: process.platform,
+      arch: process.arch,
+      metrics
+  };
+}
+
+// 性能测试，返回每一项的机器可读结果
+function runPerformanceTest(): BenchmarkResult[] {
+  console.log('=== TypeScript 性能测试 ===');
+  console.log(`随机种子: ${seed}\n`);
   
   const checker = new TypeChecker();
+  const results: BenchmarkResult[] = [];
   
   // 1. AST 遍历测试
   console.log('1. AST 节点遍历测试');
-  const ast = generateAST(6, 4); // 深度6，每层4个子节点
+  const ast = generateAST(new WorkloadRandom(seed), 6, 4); // 深度6，每层4个子节点
   
   const astStart = process.hrtime.bigint();
   const nodeCount = checker.visitNode(ast);
@@ -158,6 +264,7 @@ function runPerformanceTest() {
   
   console.log(`   处理节点数: ${nodeCount}`);
   console.log(`   耗时: ${astTime.toFixed(2)} ms\n`);
+  results.push(benchmarkResult('workload-traversal', { depth: 6, breadth: 4, seed, workload: fingerprintASTs([ast]) }, astTime, { nodes: nodeCount }));
   
   // 2. 符号表测试
   console.log('2. 符号表查找测试');
@@ -182,12 +289,13 @@ function runPerformanceTest() {
   console.log(`   查找次数: 50000`);
   console.log(`   找到符号: ${foundCount}`);
   console.log(`   耗时: ${symbolTime.toFixed(2)} ms\n`);
+  results.push(benchmarkResult('symbol-lookup', { symbols: 10000, lookups: 50000 }, symbolTime, { found: foundCount }));
   
   // 3. 批量文件处理测试
   console.log('3. 批量文件处理测试（单线程）');
   const files: ASTNode[] = [];
   for (let i = 0; i < 10; i++) {
-      files.push(generateAST(5, 3));
+      files.push(generateAST(new WorkloadRandom(deriveSeed(seed, i)), 5, 3));
   }
   
   const batchStart = process.hrtime.bigint();
@@ -198,10 +306,12 @@ function runPerformanceTest() {
   console.log(`   处理文件数: ${files.length}`);
   console.log(`   总节点数: ${totalNodes}`);
   console.log(`   耗时: ${batchTime.toFixed(2)} ms\n`);
+  results.push(benchmarkResult('workload-batch-single-thread', { files: files.length, seed, workload: fingerprintASTs(files) }, batchTime, { nodes: totalNodes }));
   
   // 4. 内存使用测试
   console.log('4. 内存使用测试');
   const memStart = process.memoryUsage();
+  const memTimeStart = process.hrtime.bigint();
   
   // 创建大量对象
   const objects: ASTNode[] = [];
@@ -213,6 +323,7 @@ function runPerformanceTest() {
       });
   }
   
+  const memTime = Number(process.hrtime.bigint() - memTimeStart) / 1000000;
   const memEnd = process.memoryUsage();
   console.log(`   创建对象数: 100000`);
   console.log(`   堆内存增长: ${((memEnd.heapUsed - memStart.heapUsed) / 1024 / 1024).toFixed(2)} MB\n`);
@@ -223,7 +334,15 @@ function runPerformanceTest() {
   console.log(`符号查找: ${symbolTime.toFixed(2)} ms`);
   console.log(`批量处理: ${batchTime.toFixed(2)} ms`);
   console.log(`内存使用: ${((memEnd.heapUsed - memStart.heapUsed) / 1024 / 1024).toFixed(2)} MB`);
+
+  results.push(benchmarkResult('memory', { objects: 100000 }, memTime, {
+      heapGrowthMB: (memEnd.heapUsed - memStart.heapUsed) / 1024 / 1024
+  }));
+  return results;
 }
 
 // 运行测试
-runPerformanceTest();
\ No newline at end of file
+const results = runPerformanceTest();
+if (jsonOutput) {
+  process.stdout.write(JSON.stringify(results, null, 2) + '\n');
+}
\ No newline at end of file